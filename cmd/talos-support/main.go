@@ -0,0 +1,331 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package main implements the talos-support CLI which collects the support bundle from a Talos cluster.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support"
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// profiles maps the profile names accepted by the --profile flag.
+var profiles = map[string]bundle.Profile{
+	"standard": bundle.ProfileStandard,
+	"minimal":  bundle.ProfileMinimal,
+	"full":     bundle.ProfileFull,
+}
+
+// uploadTokenEnv is the environment variable with the support portal token used if the flag is not set.
+const uploadTokenEnv = "TALOS_SUPPORT_UPLOAD_TOKEN"
+
+type cliOptions struct {
+	metadata         map[string]string
+	talosconfig      string
+	contextName      string
+	kubeconfig       string
+	nodes            string
+	nodeLabels       string
+	profile          string
+	output           string
+	namespaces       string
+	redactionRules   string
+	uploadURL        string
+	uploadToken      string
+	webhook          string
+	requester        string
+	since            time.Duration
+	workers          int
+	controlPlaneOnly bool
+	workersOnly      bool
+	skipKubernetes   bool
+	skipTalos        bool
+	noDiscovery      bool
+	inCluster        bool
+	redactSecrets    bool
+	redactPII        bool
+	verbose          bool
+	noProgress       bool
+	progressJSON     bool
+	auditLog         bool
+	machineConfig    bool
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	opts, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	bundleOptions, err := opts.bundleOptions()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(opts.output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	options := bundle.NewOptions(append(bundleOptions, bundle.WithArchiveOutput(f))...)
+
+	if err = collect(ctx, options, opts); err != nil {
+		f.Close()           //nolint:errcheck
+		os.Remove(f.Name()) //nolint:errcheck
+
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "support bundle written to %s\n", opts.output)
+
+	if result := options.UploadResult(); result != nil {
+		fmt.Fprintf(os.Stderr, "support bundle uploaded, reference %s\n", result.Reference)
+	}
+
+	return nil
+}
+
+func collect(ctx context.Context, options *bundle.Options, opts *cliOptions) error {
+	cols, err := collectors.GetForOptions(ctx, options)
+	if err != nil {
+		options.Close() //nolint:errcheck
+
+		return fmt.Errorf("failed to set up collectors: %w", err)
+	}
+
+	switch {
+	case opts.progressJSON:
+		options.ProgressWriter = os.Stdout
+	case !opts.noProgress && !opts.verbose:
+		bar := newProgressBar(os.Stderr, len(cols))
+		defer bar.Finish()
+
+		options.ProgressFunc = bar.Update
+	}
+
+	return support.CreateSupportBundle(ctx, options, cols...)
+}
+
+func parseFlags(args []string) (*cliOptions, error) {
+	opts := &cliOptions{}
+
+	fs := flag.NewFlagSet("talos-support", flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: talos-support [flags]\n\nCollects the support bundle from the Talos cluster.\n\nFlags:\n") //nolint:errcheck
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&opts.talosconfig, "talosconfig", "", "path to the talosconfig, the default talosconfig is used if empty")
+	fs.StringVar(&opts.contextName, "context", "", "talosconfig context to use, the current context is used if empty")
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig, the admin kubeconfig is fetched via Talos API if empty")
+	fs.StringVar(&opts.nodes, "nodes", "", "comma separated list of the nodes to collect the data from, the talosconfig context nodes are used if empty")
+	fs.BoolVar(&opts.noDiscovery, "no-node-discovery", false, "don't discover the cluster nodes if no nodes are set")
+	fs.StringVar(&opts.nodeLabels, "node-labels", "", "collect the data from the Kubernetes nodes matching the label selector")
+	fs.BoolVar(&opts.controlPlaneOnly, "control-plane-only", false, "collect the data only from the control plane nodes")
+	fs.BoolVar(&opts.workersOnly, "workers-only", false, "collect the data only from the worker nodes")
+	fs.StringVar(&opts.profile, "profile", "standard", "collection profile: standard, minimal or full")
+	fs.StringVar(&opts.output, "output", "support.zip", "path to the output archive")
+	fs.IntVar(&opts.workers, "workers", 1, "number of the parallel collectors")
+	fs.DurationVar(&opts.since, "since", 0, "collect only the logs newer than the duration, e.g. 2h")
+	fs.StringVar(&opts.namespaces, "namespaces", "", "comma separated list of the Kubernetes namespaces to collect the resources from")
+	fs.BoolVar(&opts.redactSecrets, "redact-secrets", false, "redact the secrets from the collected data")
+	fs.BoolVar(&opts.redactPII, "redact-pii", false, "redact the personally identifiable information from the collected data")
+	fs.BoolVar(&opts.machineConfig, "machine-config", false, "collect the machine config of the nodes with the secrets removed")
+	fs.StringVar(&opts.redactionRules, "redaction-rules", "", "path to the file with the custom redaction rules")
+	fs.StringVar(&opts.uploadURL, "upload-url", "", "HTTPS URL of the support portal to upload the bundle to")
+	fs.StringVar(&opts.uploadToken, "upload-token", "", "support portal token, defaults to $"+uploadTokenEnv)
+	fs.StringVar(&opts.webhook, "completion-webhook", "", "URL to post the collection summary to when it finishes")
+	fs.BoolVar(&opts.auditLog, "audit-log", false, "write the audit log of the API calls made during the collection to the bundle")
+	fs.StringVar(&opts.requester, "requester", "", "identity of the bundle requester recorded in the audit log")
+	fs.BoolVar(&opts.skipKubernetes, "skip-kubernetes", false, "don't collect the Kubernetes resources and logs")
+	fs.BoolVar(&opts.skipTalos, "skip-talos", false, "don't collect the data from the Talos nodes")
+	fs.BoolVar(&opts.inCluster, "in-cluster", false, "run inside the cluster using the pod ServiceAccount and the Talos ServiceAccount credentials")
+	fs.BoolVar(&opts.verbose, "verbose", false, "print the collector logs instead of the progress bar")
+	fs.BoolVar(&opts.noProgress, "no-progress", false, "don't print the progress bar")
+	fs.BoolVar(&opts.progressJSON, "progress-json", false, "print the progress events as NDJSON to stdout instead of the progress bar")
+	fs.Func("metadata", "custom metadata `key=value` to add to the bundle, can be repeated", func(s string) error {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid metadata %q, expected key=value", s)
+		}
+
+		if opts.metadata == nil {
+			opts.metadata = map[string]string{}
+		}
+
+		opts.metadata[key] = value
+
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	// the token is not used as the flag default, so it's not printed in the usage
+	if opts.uploadToken == "" {
+		opts.uploadToken = os.Getenv(uploadTokenEnv)
+	}
+
+	return opts, nil
+}
+
+func (opts *cliOptions) bundleOptions() ([]bundle.Option, error) {
+	profile, ok := profiles[opts.profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", opts.profile)
+	}
+
+	if opts.controlPlaneOnly && opts.workersOnly {
+		return nil, errors.New("--control-plane-only and --workers-only are mutually exclusive")
+	}
+
+	bundleOptions := []bundle.Option{
+		bundle.WithProfile(profile),
+		bundle.WithNumWorkers(max(opts.workers, 1)),
+	}
+
+	if !opts.verbose {
+		bundleOptions = append(bundleOptions, bundle.WithLogOutput(io.Discard))
+	}
+
+	if opts.inCluster {
+		bundleOptions = append(bundleOptions, bundle.WithInCluster())
+	}
+
+	switch {
+	case opts.skipTalos:
+		bundleOptions = append(bundleOptions, bundle.WithoutTalos())
+	case !opts.inCluster || opts.talosconfig != "":
+		bundleOptions = append(bundleOptions, bundle.WithTalosconfig(opts.talosconfig, opts.contextName))
+	}
+
+	switch {
+	case opts.kubeconfig != "":
+		bundleOptions = append(bundleOptions, bundle.WithKubeconfig(opts.kubeconfig))
+	case !opts.skipTalos && !opts.inCluster:
+		bundleOptions = append(bundleOptions, bundle.WithKubernetesClientFromTalos())
+	}
+
+	// the Kubernetes client is still used to select the nodes if the Kubernetes data is not collected
+	if opts.skipKubernetes {
+		bundleOptions = append(bundleOptions, bundle.WithoutKubernetes())
+	}
+
+	if nodes := splitList(opts.nodes); len(nodes) > 0 {
+		bundleOptions = append(bundleOptions, bundle.WithNodes(nodes...))
+	}
+
+	if opts.noDiscovery {
+		bundleOptions = append(bundleOptions, bundle.WithoutNodeDiscovery())
+	}
+
+	if opts.nodeLabels != "" {
+		bundleOptions = append(bundleOptions, bundle.WithNodeLabels(opts.nodeLabels))
+	}
+
+	switch {
+	case opts.controlPlaneOnly:
+		bundleOptions = append(bundleOptions, bundle.WithControlPlaneOnly())
+	case opts.workersOnly:
+		bundleOptions = append(bundleOptions, bundle.WithWorkersOnly())
+	}
+
+	if opts.since > 0 {
+		bundleOptions = append(bundleOptions, bundle.WithSince(opts.since))
+	}
+
+	if namespaces := splitList(opts.namespaces); len(namespaces) > 0 {
+		bundleOptions = append(bundleOptions, bundle.WithNamespaces(namespaces...))
+	}
+
+	if opts.redactSecrets {
+		bundleOptions = append(bundleOptions, bundle.WithSecretRedaction())
+	}
+
+	if opts.redactPII {
+		bundleOptions = append(bundleOptions, bundle.WithPIIRedaction())
+	}
+
+	if opts.machineConfig {
+		bundleOptions = append(bundleOptions, bundle.WithMachineConfig())
+	}
+
+	if opts.redactionRules != "" {
+		bundleOptions = append(bundleOptions, bundle.WithRedactionRulesFile(opts.redactionRules))
+	}
+
+	if opts.metadata != nil {
+		bundleOptions = append(bundleOptions, bundle.WithMetadata(opts.metadata))
+	}
+
+	if opts.webhook != "" {
+		bundleOptions = append(bundleOptions, bundle.WithCompletionWebhook(opts.webhook))
+	}
+
+	if opts.uploadURL != "" {
+		bundleOptions = append(bundleOptions, bundle.WithUpload(opts.uploadURL, opts.uploadToken))
+	}
+
+	if opts.auditLog {
+		bundleOptions = append(bundleOptions, bundle.WithAuditLog())
+	}
+
+	if opts.requester != "" {
+		bundleOptions = append(bundleOptions, bundle.WithRequester(opts.requester))
+	}
+
+	return bundleOptions, nil
+}
+
+// splitList splits the comma separated list skipping the empty items.
+func splitList(s string) []string {
+	var items []string
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
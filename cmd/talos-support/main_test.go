@@ -0,0 +1,228 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+func TestUploadToken(t *testing.T) {
+	t.Setenv(uploadTokenEnv, "env-token")
+
+	opts, err := parseFlags(nil)
+	require.NoError(t, err)
+	require.Equal(t, "env-token", opts.uploadToken)
+
+	opts, err = parseFlags([]string{"--upload-token", "flag-token"})
+	require.NoError(t, err)
+	require.Equal(t, "flag-token", opts.uploadToken)
+}
+
+func TestUploadTokenUsage(t *testing.T) {
+	t.Setenv(uploadTokenEnv, "env-token")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+
+	_, err = parseFlags([]string{"-h"})
+
+	os.Stderr = stderr
+
+	require.ErrorIs(t, err, flag.ErrHelp)
+	require.NoError(t, w.Close())
+
+	usage, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	require.Contains(t, string(usage), "-upload-token")
+	require.NotContains(t, string(usage), "env-token")
+}
+
+func TestParseFlags(t *testing.T) {
+	require := require.New(t)
+
+	opts, err := parseFlags([]string{
+		"--nodes", "10.5.0.2, 10.5.0.3,",
+		"--profile", "full",
+		"--since", "2h",
+		"--workers", "4",
+		"--metadata", "ticket=1234",
+		"--metadata", "cluster=prod",
+	})
+	require.NoError(err)
+
+	require.Equal("10.5.0.2, 10.5.0.3,", opts.nodes)
+	require.Equal([]string{"10.5.0.2", "10.5.0.3"}, splitList(opts.nodes))
+	require.Equal("full", opts.profile)
+	require.Equal(2*time.Hour, opts.since)
+	require.Equal(4, opts.workers)
+	require.Equal(map[string]string{"ticket": "1234", "cluster": "prod"}, opts.metadata)
+	require.Equal("support.zip", opts.output)
+
+	_, err = parseFlags([]string{"--metadata", "=value"})
+	require.ErrorContains(err, `invalid metadata "=value"`)
+
+	_, err = parseFlags([]string{"--nodes", "10.5.0.2", "extra"})
+	require.EqualError(err, "unexpected arguments: extra")
+}
+
+func TestBundleOptions(t *testing.T) {
+	for _, test := range []struct {
+		check func(*require.Assertions, *bundle.Options)
+		name  string
+		err   string
+		args  []string
+	}{
+		{
+			name: "defaults",
+			check: func(require *require.Assertions, options *bundle.Options) {
+				require.Equal(bundle.ProfileStandard, options.Profile)
+				require.Equal(1, options.NumWorkers)
+				require.Equal(bundle.NodeRoleAny, options.NodeRole)
+				require.NotNil(options.TalosConfigProvider)
+				require.True(options.KubernetesFromTalos)
+				require.Equal(io.Discard, options.LogOutput)
+			},
+		},
+		{
+			name: "selection",
+			args: []string{"--profile", "minimal", "--workers", "0", "--nodes", "10.5.0.2,10.5.0.3", "--control-plane-only", "--namespaces", "default,monitoring", "--since", "30m"},
+			check: func(require *require.Assertions, options *bundle.Options) {
+				require.Equal(bundle.ProfileMinimal, options.Profile)
+				require.Equal(1, options.NumWorkers)
+				require.Equal(bundle.NodeRoleControlPlane, options.NodeRole)
+				require.Equal([]string{"10.5.0.2", "10.5.0.3"}, options.Nodes)
+				require.Equal([]string{"kube-system", "default", "monitoring"}, options.KubernetesNamespaces())
+				require.Equal(30*time.Minute, options.Since)
+			},
+		},
+		{
+			name: "kubernetes only",
+			args: []string{"--skip-talos", "--kubeconfig", "/tmp/kubeconfig", "--workers-only"},
+			check: func(require *require.Assertions, options *bundle.Options) {
+				require.True(options.SkipTalos)
+				require.Nil(options.TalosConfigProvider)
+				require.False(options.KubernetesFromTalos)
+				require.Equal("/tmp/kubeconfig", options.Kubeconfig)
+				require.Equal(bundle.NodeRoleWorker, options.NodeRole)
+			},
+		},
+		{
+			name: "in cluster",
+			args: []string{"--in-cluster", "--skip-kubernetes", "--node-labels", "node-role.kubernetes.io/control-plane", "--verbose"},
+			check: func(require *require.Assertions, options *bundle.Options) {
+				require.True(options.InCluster)
+				require.True(options.SkipKubernetes)
+				require.Nil(options.TalosConfigProvider)
+				require.False(options.KubernetesFromTalos)
+				require.True(options.SelectsNodes())
+				require.Nil(options.LogOutput)
+			},
+		},
+		{
+			name: "redaction and reporting",
+			args: []string{"--redact-secrets", "--redact-pii", "--machine-config", "--audit-log", "--requester", "alice", "--no-node-discovery", "--metadata", "ticket=1234"},
+			check: func(require *require.Assertions, options *bundle.Options) {
+				require.True(options.RedactSecrets)
+				require.True(options.RedactPII)
+				require.True(options.MachineConfig)
+				require.True(options.AuditLog)
+				require.Equal("alice", options.Requester)
+				require.True(options.SkipNodeDiscovery)
+				require.Equal(map[string]string{"ticket": "1234"}, options.Metadata)
+			},
+		},
+		{
+			name: "unknown profile",
+			args: []string{"--profile", "huge"},
+			err:  `unknown profile "huge"`,
+		},
+		{
+			name: "conflicting roles",
+			args: []string{"--control-plane-only", "--workers-only"},
+			err:  "--control-plane-only and --workers-only are mutually exclusive",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			opts, err := parseFlags(test.args)
+			require.NoError(err)
+
+			bundleOptions, err := opts.bundleOptions()
+			if test.err != "" {
+				require.EqualError(err, test.err)
+
+				return
+			}
+
+			require.NoError(err)
+
+			test.check(require, bundle.NewOptions(bundleOptions...))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+
+	output := filepath.Join(t.TempDir(), "support.zip")
+
+	require.NoError(run([]string{"--skip-talos", "--skip-kubernetes", "--no-progress", "--output", output, "--metadata", "ticket=1234"}))
+
+	b, err := bundlereader.Open(output)
+	require.NoError(err)
+
+	defer b.Close() //nolint:errcheck
+
+	require.Equal("1234", b.Metadata().Custom["ticket"])
+
+	// the invalid options are rejected before the output is created
+	missing := filepath.Join(t.TempDir(), "missing.zip")
+
+	require.Error(run([]string{"--profile", "huge", "--output", missing}))
+	require.NoFileExists(missing)
+}
+
+func TestProgressBar(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+
+	bar := newProgressBar(&buf, 4)
+
+	bar.Update(bundle.Progress{Source: "10.5.0.2", State: "dmesg.log"})
+	bar.Update(bundle.Progress{Source: "10.5.0.2", State: "etcd/members.txt", Error: errors.New("etcd is not running")})
+	bar.Update(bundle.Progress{Source: "cluster", State: "kubernetesResources/nodes.yaml", Warnings: []string{"the list is truncated"}})
+
+	out := buf.String()
+
+	require.Contains(out, "[===============               ] 2/4 10.5.0.2: etcd/members.txt")
+	require.Contains(out, "10.5.0.2: etcd/members.txt: etcd is not running\n")
+	require.Contains(out, "cluster: kubernetesResources/nodes.yaml: warning: the list is truncated\n")
+
+	bar.Finish()
+
+	out = buf.String()
+
+	require.Contains(out, "] 3/4 done\n")
+	require.True(strings.HasSuffix(out, "1 collectors failed, see the manifest in the bundle for details\n"))
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders the bundle collection progress as a single updating line.
+//
+// The collector errors and warnings are printed above the bar as they come.
+type progressBar struct {
+	w      io.Writer
+	errors int
+	done   int
+	total  int
+}
+
+func newProgressBar(w io.Writer, total int) *progressBar {
+	bar := &progressBar{
+		w:     w,
+		total: total,
+	}
+
+	bar.render("")
+
+	return bar
+}
+
+// Update implements the bundle progress callback, it is never called concurrently.
+func (bar *progressBar) Update(progress bundle.Progress) {
+	bar.done++
+
+	if progress.Error != nil {
+		bar.errors++
+
+		bar.clear()
+		fmt.Fprintf(bar.w, "%s: %s: %s\n", progress.Source, progress.State, progress.Error) //nolint:errcheck
+	}
+
+	for _, warning := range progress.Warnings {
+		bar.clear()
+		fmt.Fprintf(bar.w, "%s: %s: warning: %s\n", progress.Source, progress.State, warning) //nolint:errcheck
+	}
+
+	bar.render(progress.Source + ": " + progress.State)
+}
+
+// Finish moves the cursor past the bar.
+func (bar *progressBar) Finish() {
+	bar.render("done")
+	fmt.Fprintln(bar.w) //nolint:errcheck
+
+	if bar.errors > 0 {
+		fmt.Fprintf(bar.w, "%d collectors failed, see the manifest in the bundle for details\n", bar.errors) //nolint:errcheck
+	}
+}
+
+func (bar *progressBar) clear() {
+	fmt.Fprint(bar.w, "\r\033[K") //nolint:errcheck
+}
+
+func (bar *progressBar) render(state string) {
+	filled := progressBarWidth
+
+	if bar.total > 0 {
+		filled = min(bar.done*progressBarWidth/bar.total, progressBarWidth)
+	}
+
+	bar.clear()
+	fmt.Fprintf(bar.w, "[%s%s] %d/%d %s", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), bar.done, bar.total, state) //nolint:errcheck
+}
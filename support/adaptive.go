@@ -0,0 +1,170 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/gen/channel"
+)
+
+// dispatch sends the tasks to the worker pool, it's run for each pool.
+//
+// In the adaptive mode the tasks of the sources running as many collectors as their limit allows are held back,
+// the tasks of the other sources are sent first, one per source in turn.
+func dispatch(ctx context.Context, limiter *sourceLimiter, tasks []task) {
+	if limiter == nil {
+		for _, t := range tasks {
+			if !channel.SendWithContext(ctx, t.pool, t) {
+				return
+			}
+		}
+
+		return
+	}
+
+	var sources []string
+
+	pending := map[string][]task{}
+
+	for _, t := range tasks {
+		source := t.source()
+
+		if _, ok := pending[source]; !ok {
+			sources = append(sources, source)
+		}
+
+		pending[source] = append(pending[source], t)
+	}
+
+	for len(sources) > 0 {
+		// the channel is taken before the sources are checked, so the release in between is not missed
+		changed := limiter.changes()
+		sent := false
+
+		for i := 0; i < len(sources); {
+			source := sources[i]
+
+			if !limiter.tryAcquire(source) {
+				i++
+
+				continue
+			}
+
+			t := pending[source][0]
+
+			if !channel.SendWithContext(ctx, t.pool, t) {
+				return
+			}
+
+			sent = true
+
+			if pending[source] = pending[source][1:]; len(pending[source]) == 0 {
+				sources = slices.Delete(sources, i, i+1)
+			} else {
+				i++
+			}
+		}
+
+		if sent {
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sourceLimiter limits the number of the collectors run at once against each source (Talos node or cluster).
+//
+// The limit of the source is halved when its collectors time out or their average latency goes over the threshold,
+// and it grows back by one with each collector done in time, up to the number of the workers.
+type sourceLimiter struct {
+	sources map[string]*sourceLimit
+	// changed is closed and replaced when a collector is done
+	changed  chan struct{}
+	mu       sync.Mutex
+	latency  time.Duration
+	maxLimit int
+}
+
+// sourceLimit is the state of a single source.
+type sourceLimit struct {
+	// average is the moving average of the collector latency
+	average time.Duration
+	limit   int
+	running int
+}
+
+func newSourceLimiter(maxLimit int, latency time.Duration) *sourceLimiter {
+	return &sourceLimiter{
+		sources:  map[string]*sourceLimit{},
+		changed:  make(chan struct{}),
+		latency:  latency,
+		maxLimit: maxLimit,
+	}
+}
+
+// source returns the state of the source, it should be called with the lock held.
+func (l *sourceLimiter) source(source string) *sourceLimit {
+	state, ok := l.sources[source]
+	if !ok {
+		state = &sourceLimit{limit: l.maxLimit}
+		l.sources[source] = state
+	}
+
+	return state
+}
+
+func (l *sourceLimiter) tryAcquire(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.source(source)
+
+	if state.running >= state.limit {
+		return false
+	}
+
+	state.running++
+
+	return true
+}
+
+func (l *sourceLimiter) release(source string, duration time.Duration, timedOut bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.source(source)
+	state.running--
+
+	if state.average == 0 {
+		state.average = duration
+	} else {
+		state.average = (state.average*3 + duration) / 4
+	}
+
+	if timedOut || state.average > l.latency {
+		state.limit = max(state.limit/2, 1)
+	} else {
+		state.limit = min(state.limit+1, l.maxLimit)
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *sourceLimiter) changes() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.changed
+}
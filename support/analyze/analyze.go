@@ -0,0 +1,194 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package analyze runs the diagnostic rules over the support bundle and reports the found problems.
+package analyze
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// Severity is the finding severity.
+type Severity int
+
+// Severity values.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*s = SeverityInfo
+	case "warning":
+		*s = SeverityWarning
+	case "critical":
+		*s = SeverityCritical
+	default:
+		return fmt.Errorf("unknown severity %q", string(text))
+	}
+
+	return nil
+}
+
+// Finding is the problem found by the rule.
+//
+// Node is empty for the cluster wide findings.
+type Finding struct {
+	Rule        string   `yaml:"rule" json:"rule"`
+	Node        string   `yaml:"node,omitempty" json:"node,omitempty"`
+	Message     string   `yaml:"message" json:"message"`
+	Remediation string   `yaml:"remediation,omitempty" json:"remediation,omitempty"`
+	Severity    Severity `yaml:"severity" json:"severity"`
+}
+
+// Rule is the diagnostic rule.
+//
+// The rules skip the checks if the data they need is not in the bundle, the error is returned only if the data
+// is present but can't be parsed.
+type Rule interface {
+	Name() string
+	Analyze(b *bundlereader.Bundle) ([]Finding, error)
+}
+
+// RuleFunc is the rule implemented as a function.
+type RuleFunc struct {
+	f    func(b *bundlereader.Bundle) ([]Finding, error)
+	name string
+}
+
+// NewRule creates the rule from the function.
+func NewRule(name string, f func(b *bundlereader.Bundle) ([]Finding, error)) *RuleFunc {
+	return &RuleFunc{
+		name: name,
+		f:    f,
+	}
+}
+
+// Name implements Rule.
+func (r *RuleFunc) Name() string {
+	return r.name
+}
+
+// Analyze implements Rule.
+func (r *RuleFunc) Analyze(b *bundlereader.Bundle) ([]Finding, error) {
+	findings, err := r.f(b)
+
+	for i := range findings {
+		if findings[i].Rule == "" {
+			findings[i].Rule = r.name
+		}
+	}
+
+	return findings, err
+}
+
+// DefaultRules returns the rules run by Analyze if no rules are given.
+func DefaultRules() []Rule {
+	return []Rule{
+		ServiceHealth(),
+		CertificateExpiry(DefaultCertificateExpiryWindow),
+		KubernetesNodes(),
+		EtcdQuorum(),
+		DiskUsage(DefaultDiskUsageThreshold),
+		TimeSync(),
+	}
+}
+
+// Analyze runs the rules over the bundle and returns the findings sorted by severity, the most severe first.
+//
+// The failing rules don't stop the analysis, their errors are joined and returned together with the findings
+// of the other rules.
+func Analyze(b *bundlereader.Bundle, rules ...Rule) ([]Finding, error) {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	var (
+		findings []Finding
+		errs     []error
+	)
+
+	for _, rule := range rules {
+		res, err := rule.Analyze(b)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s failed: %w", rule.Name(), err))
+		}
+
+		findings = append(findings, res...)
+	}
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity, a.Severity),
+			strings.Compare(a.Node, b.Node),
+			strings.Compare(a.Rule, b.Rule),
+		)
+	})
+
+	return findings, errors.Join(errs...)
+}
+
+// WriteFindings renders the findings as a table.
+func WriteFindings(w io.Writer, findings []Finding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "no problems found")
+
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tNODE\tRULE\tMESSAGE\tREMEDIATION") //nolint:errcheck
+
+	for _, finding := range findings {
+		node := finding.Node
+		if node == "" {
+			node = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", finding.Severity, node, finding.Rule, finding.Message, finding.Remediation) //nolint:errcheck
+	}
+
+	return tw.Flush()
+}
+
+// referenceTime returns the time the bundle was created at, the checks which depend on time are relative to it.
+func referenceTime(b *bundlereader.Bundle) time.Time {
+	if md := b.Metadata(); md != nil && !md.Created.IsZero() {
+		return md.Created
+	}
+
+	return time.Now()
+}
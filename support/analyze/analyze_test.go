@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/siderolabs/go-talos-support/support/analyze"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+func TestAnalyze(t *testing.T) {
+	require := require.New(t)
+
+	nodes, err := yaml.Marshal(corev1.NodeList{Items: []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse},
+			}},
+		},
+	}})
+	require.NoError(err)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                  {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"kubernetesResources/nodes.yaml": {Data: nodes},
+		"10.5.0.2/service-logs/etcd.state": {Data: []byte(
+			"ID       etcd\nSTATE    Waiting\nHEALTH   Fail\nLAST HEALTH MESSAGE   connection refused\n" +
+				"EVENTS   [Failed]: exited (1s ago)\n         [Running]: started (2s ago)\n" +
+				"         [Failed]: exited (3s ago)\n         [Failed]: exited (4s ago)\n",
+		)},
+		"10.5.0.2/mounts": {Data: []byte(
+			"FILESYSTEM   SIZE(GB)   USED(GB)   AVAILABLE(GB)   PERCENT USED   MOUNTED ON\n" +
+				"/dev/loop0   0.07       0.07       0.00            100.00%        /\n" +
+				"/dev/sda6    10.00      9.50       0.50            95.00%         /var\n",
+		)},
+		"10.5.0.2/etcd-status": {Data: []byte(
+			"MEMBER   HOSTNAME   PEER URLS   CLIENT URLS   LEARNER\n" +
+				"a1       cp-1       https://10.5.0.2:2380   https://10.5.0.2:2379   false\n" +
+				"a2       cp-2       https://10.5.0.3:2380   https://10.5.0.3:2379   false\n" +
+				"a3       cp-3       https://10.5.0.4:2380   https://10.5.0.4:2379   false\n\n" +
+				"MEMBER   DB SIZE   IN USE   LEADER   RAFT INDEX   RAFT TERM   RAFT APPLIED INDEX   ERRORS\n" +
+				"a1       10 MB     5 MB     a1       100          2           100                  \n" +
+				"a2       10 MB     5 MB     a1       100          2           100                  etcdserver: no leader\n\n" +
+				"MEMBER   ALARM\n",
+		)},
+		"10.5.0.2/resources/timestatuses.v1alpha1.talos.dev.yaml": {Data: []byte(
+			"metadata:\n    id: node\nspec:\n    synced: false\n    epoch: 1\n    syncDisabled: false\n",
+		)},
+	})
+	require.NoError(err)
+
+	findings, err := analyze.Analyze(b)
+	require.NoError(err)
+
+	rules := map[string]analyze.Severity{}
+
+	for _, finding := range findings {
+		rules[finding.Rule] = max(rules[finding.Rule], finding.Severity)
+	}
+
+	require.Equal(map[string]analyze.Severity{
+		"service-health":   analyze.SeverityCritical,
+		"kubernetes-nodes": analyze.SeverityCritical,
+		"etcd-quorum":      analyze.SeverityWarning,
+		"disk-usage":       analyze.SeverityWarning,
+		"time-sync":        analyze.SeverityWarning,
+	}, rules)
+
+	require.Equal(analyze.SeverityCritical, findings[0].Severity)
+
+	var buf bytes.Buffer
+
+	require.NoError(analyze.WriteFindings(&buf, findings))
+	assert.Contains(t, buf.String(), "service etcd is crashlooping")
+	assert.Contains(t, buf.String(), "filesystem /dev/sda6 mounted on /var is 95% full")
+}
@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// DefaultCertificateExpiryWindow is the time before the certificate expiration when it gets reported.
+const DefaultCertificateExpiryWindow = 30 * 24 * time.Hour
+
+// certificateFiles are the extensions of the files which are searched for the certificates.
+var certificateFiles = []string{".yaml", ".yml", ".txt", ".pem", ".crt"}
+
+// base64CertificateRe matches the base64 encoded PEM certificates as they are stored in the Talos resources and kubeconfigs.
+var base64CertificateRe = regexp.MustCompile(`LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0t[A-Za-z0-9+/]+=*`)
+
+// CertificateExpiry creates the rule which reports the certificates which are expired or expire within the window.
+//
+// The certificates are looked up in all text files of the bundle both in the PEM and base64 encoded PEM form.
+// The expiration is checked against the bundle creation time.
+func CertificateExpiry(window time.Duration) Rule {
+	return NewRule("certificate-expiry", func(b *bundlereader.Bundle) ([]Finding, error) {
+		now := referenceTime(b)
+
+		var findings []Finding
+
+		for _, file := range b.Files() {
+			if !slices.Contains(certificateFiles, path.Ext(file)) {
+				continue
+			}
+
+			data, err := b.ReadFile(file)
+			if err != nil {
+				return findings, err
+			}
+
+			node := ""
+
+			if dir, _, ok := strings.Cut(file, "/"); ok {
+				if _, isNode := b.Node(dir); isNode {
+					node = dir
+				}
+			}
+
+			for _, cert := range findCertificates(data) {
+				var finding Finding
+
+				switch {
+				case now.After(cert.NotAfter):
+					finding = Finding{
+						Severity: SeverityCritical,
+						Message:  fmt.Sprintf("certificate %q in %s expired at %s", cert.Subject, file, cert.NotAfter.Format(time.RFC3339)),
+					}
+				case cert.NotAfter.Sub(now) < window:
+					finding = Finding{
+						Severity: SeverityWarning,
+						Message: fmt.Sprintf("certificate %q in %s expires in %s at %s",
+							cert.Subject, file, cert.NotAfter.Sub(now).Round(time.Hour), cert.NotAfter.Format(time.RFC3339)),
+					}
+				default:
+					continue
+				}
+
+				finding.Node = node
+				finding.Remediation = "rotate the certificate, the Talos managed certificates are renewed automatically, " +
+					"the CA certificates have to be rotated with `talosctl rotate-ca`"
+
+				findings = append(findings, finding)
+			}
+		}
+
+		return findings, nil
+	})
+}
+
+// findCertificates finds and parses the unique certificates in the data.
+func findCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+
+	parse := func(data []byte) {
+		for {
+			start := bytes.Index(data, []byte("-----BEGIN CERTIFICATE-----"))
+			if start < 0 {
+				return
+			}
+
+			block, rest := pem.Decode(data[start:])
+			if block == nil {
+				// skip the broken block
+				data = data[start+1:]
+
+				continue
+			}
+
+			data = rest
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				continue
+			}
+
+			if !slices.ContainsFunc(certs, cert.Equal) {
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	parse(data)
+
+	for _, match := range base64CertificateRe.FindAll(data, -1) {
+		decoded, err := base64.StdEncoding.DecodeString(string(match))
+		if err != nil {
+			continue
+		}
+
+		parse(decoded)
+	}
+
+	return certs
+}
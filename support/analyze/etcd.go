@@ -0,0 +1,187 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// etcdMember is the etcd member merged from the etcd status files of all nodes.
+type etcdMember struct {
+	Hostname string
+	Errors   string
+	Alarms   []string
+	Learner  bool
+	// StatusKnown is set if any node reported the member status.
+	StatusKnown bool
+}
+
+// columnSeparatorRe splits the table rendered by the tabwriter, the values may contain single spaces, e.g. `12 MB`.
+var columnSeparatorRe = regexp.MustCompile(`\s{2,}`)
+
+// parseEtcdStatus parses the `etcd-status` node file into the members.
+//
+// The file contains three tables separated by the empty lines: the members, the member status and the alarms.
+func parseEtcdStatus(data []byte, members map[string]*etcdMember) {
+	member := func(id string) *etcdMember {
+		m, ok := members[id]
+		if !ok {
+			m = &etcdMember{}
+			members[id] = m
+		}
+
+		return m
+	}
+
+	var header []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			header = nil
+
+			continue
+		}
+
+		columns := columnSeparatorRe.Split(line, -1)
+
+		if header == nil {
+			header = columns
+
+			continue
+		}
+
+		switch {
+		case slices.Contains(header, "HOSTNAME") && len(columns) >= 2:
+			m := member(columns[0])
+			m.Hostname = columns[1]
+			m.Learner = columns[len(columns)-1] == "true"
+		case slices.Contains(header, "DB SIZE"):
+			m := member(columns[0])
+			m.StatusKnown = true
+
+			if len(columns) >= len(header) {
+				m.Errors = columns[len(header)-1]
+			}
+		case slices.Contains(header, "ALARM") && len(columns) == 2:
+			m := member(columns[0])
+
+			if columns[1] != "NONE" && !slices.Contains(m.Alarms, columns[1]) {
+				m.Alarms = append(m.Alarms, columns[1])
+			}
+		}
+	}
+}
+
+// EtcdQuorum creates the rule which reports the etcd cluster with the lost quorum or without the fault tolerance
+// and the raised etcd alarms.
+//
+// The member is considered healthy if any node reported its status without errors, the members without the status
+// in the bundle (e.g. the node was not collected) are not counted as failed.
+func EtcdQuorum() Rule {
+	return NewRule("etcd-quorum", func(b *bundlereader.Bundle) ([]Finding, error) {
+		members := map[string]*etcdMember{}
+
+		for _, node := range b.Nodes() {
+			if !b.Has(node.Path("etcd-status")) {
+				continue
+			}
+
+			data, err := node.ReadFile("etcd-status")
+			if err != nil {
+				return nil, err
+			}
+
+			parseEtcdStatus(data, members)
+		}
+
+		ids := make([]string, 0, len(members))
+
+		for id := range members {
+			ids = append(ids, id)
+		}
+
+		slices.Sort(ids)
+
+		var (
+			findings       []Finding
+			failedMembers  []string
+			voting, failed int
+		)
+
+		for _, id := range ids {
+			m := members[id]
+
+			name := id
+			if m.Hostname != "" {
+				name = m.Hostname
+			}
+
+			for _, alarm := range m.Alarms {
+				findings = append(findings, Finding{
+					Severity:    SeverityCritical,
+					Message:     fmt.Sprintf("etcd alarm %s is raised on member %s", alarm, name),
+					Remediation: "for NOSPACE alarm defragment the database with `talosctl etcd defrag` and disarm the alarm with `talosctl etcd alarm disarm`",
+				})
+			}
+
+			if m.Learner {
+				continue
+			}
+
+			voting++
+
+			if m.StatusKnown && m.Errors != "" {
+				failed++
+
+				failedMembers = append(failedMembers, fmt.Sprintf("%s (%s)", name, m.Errors))
+			}
+		}
+
+		if voting == 0 {
+			return findings, nil
+		}
+
+		quorum := voting/2 + 1
+		remediation := "check the etcd service logs of the control plane nodes, the failed members should be recovered " +
+			"or removed with `talosctl etcd remove-member`"
+
+		switch {
+		case voting-failed < quorum:
+			findings = append(findings, Finding{
+				Severity:    SeverityCritical,
+				Message:     fmt.Sprintf("etcd quorum is lost: %d of %d members failed: %s", failed, voting, strings.Join(failedMembers, ", ")),
+				Remediation: remediation,
+			})
+		case voting > 1 && voting-failed == quorum:
+			message := fmt.Sprintf("etcd quorum is at risk: %d healthy of %d members, one more failure will lose the quorum", voting-failed, voting)
+
+			if len(failedMembers) > 0 {
+				message += ", failed: " + strings.Join(failedMembers, ", ")
+			}
+
+			findings = append(findings, Finding{
+				Severity:    SeverityWarning,
+				Message:     message,
+				Remediation: remediation,
+			})
+		}
+
+		if voting%2 == 0 {
+			findings = append(findings, Finding{
+				Severity:    SeverityInfo,
+				Message:     fmt.Sprintf("etcd cluster has an even number of members (%d) which doesn't improve the fault tolerance", voting),
+				Remediation: "run an odd number of control plane nodes",
+			})
+		}
+
+		return findings, nil
+	})
+}
@@ -0,0 +1,208 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// DefaultDiskUsageThreshold is the filesystem usage in percent above which the filesystem is reported.
+const DefaultDiskUsageThreshold = 90.0
+
+// criticalDiskUsage is the filesystem usage in percent which is reported as critical.
+const criticalDiskUsage = 98.0
+
+// maxTimeOffset is the clock offset reported by the kernel which is considered as the time skew.
+const maxTimeOffset = time.Second
+
+// KubernetesNodes creates the rule which reports the NotReady Kubernetes nodes and the nodes under the resource pressure.
+func KubernetesNodes() Rule {
+	return NewRule("kubernetes-nodes", func(b *bundlereader.Bundle) ([]Finding, error) {
+		if !b.Has(bundlereader.KubernetesResourcesDir + "/nodes.yaml") {
+			return nil, nil
+		}
+
+		nodes, err := b.KubernetesNodes()
+		if err != nil {
+			return nil, err
+		}
+
+		var findings []Finding
+
+		for _, node := range nodes.Items {
+			ready := false
+
+			for _, condition := range node.Status.Conditions {
+				switch condition.Type { //nolint:exhaustive
+				case corev1.NodeReady:
+					ready = condition.Status == corev1.ConditionTrue
+				case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure, corev1.NodeNetworkUnavailable:
+					if condition.Status == corev1.ConditionTrue {
+						findings = append(findings, Finding{
+							Severity:    SeverityWarning,
+							Message:     fmt.Sprintf("Kubernetes node %s has condition %s: %s", node.Name, condition.Type, condition.Message),
+							Remediation: "check the node resource usage and the kubelet logs",
+						})
+					}
+				}
+			}
+
+			if !ready {
+				findings = append(findings, Finding{
+					Severity: SeverityCritical,
+					Message:  fmt.Sprintf("Kubernetes node %s is NotReady", node.Name),
+					Remediation: "check the kubelet and the CNI pods on the node, " +
+						"`talosctl service kubelet` and the kubelet logs show why the node doesn't report the status",
+				})
+			}
+
+			if node.Spec.Unschedulable {
+				findings = append(findings, Finding{
+					Severity:    SeverityInfo,
+					Message:     fmt.Sprintf("Kubernetes node %s is cordoned", node.Name),
+					Remediation: "uncordon the node with `kubectl uncordon` once the maintenance is done",
+				})
+			}
+		}
+
+		return findings, nil
+	})
+}
+
+// DiskUsage creates the rule which reports the filesystems with the usage above the threshold in percent.
+//
+// The read-only loop and CD-ROM devices (e.g. the Talos root squashfs) are always full, so they are skipped.
+func DiskUsage(threshold float64) Rule {
+	return NewRule("disk-usage", func(b *bundlereader.Bundle) ([]Finding, error) {
+		var findings []Finding
+
+		for _, node := range b.Nodes() {
+			if !b.Has(node.Path("mounts")) {
+				continue
+			}
+
+			data, err := node.ReadFile("mounts")
+			if err != nil {
+				return findings, err
+			}
+
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 6 || fields[0] == "FILESYSTEM" {
+					continue
+				}
+
+				filesystem, mountpoint := fields[0], fields[len(fields)-1]
+
+				if strings.HasPrefix(filesystem, "/dev/loop") || strings.HasPrefix(filesystem, "/dev/sr") {
+					continue
+				}
+
+				used, err := strconv.ParseFloat(strings.TrimSuffix(fields[len(fields)-2], "%"), 64)
+				if err != nil || used <= threshold {
+					continue
+				}
+
+				severity := SeverityWarning
+				if used >= criticalDiskUsage {
+					severity = SeverityCritical
+				}
+
+				findings = append(findings, Finding{
+					Node:        node.Name,
+					Severity:    severity,
+					Message:     fmt.Sprintf("filesystem %s mounted on %s is %.0f%% full", filesystem, mountpoint, used),
+					Remediation: "free up the space: remove the unused container images, check the large logs and the etcd database size",
+				})
+			}
+		}
+
+		return findings, nil
+	})
+}
+
+// TimeSync creates the rule which reports the nodes with the time not synchronized and the large clock offset.
+func TimeSync() Rule {
+	return NewRule("time-sync", func(b *bundlereader.Bundle) ([]Finding, error) {
+		var findings []Finding
+
+		remediation := "check the time servers in the machine config and that they are reachable from the node, " +
+			"the time skew breaks the certificates validation and the etcd cluster"
+
+		for _, node := range b.Nodes() {
+			if b.Has(node.Path("resources/timestatuses.v1alpha1.talos.dev.yaml")) {
+				statuses, err := node.Resources("timestatuses.v1alpha1.talos.dev")
+				if err != nil {
+					return findings, err
+				}
+
+				for _, status := range statuses {
+					spec, _ := status.Spec.(map[string]any) //nolint:errcheck
+
+					if synced, _ := spec["synced"].(bool); synced { //nolint:errcheck
+						continue
+					}
+
+					if disabled, _ := spec["syncDisabled"].(bool); disabled { //nolint:errcheck
+						continue
+					}
+
+					findings = append(findings, Finding{
+						Node:        node.Name,
+						Severity:    SeverityWarning,
+						Message:     "time is not synchronized",
+						Remediation: remediation,
+					})
+				}
+			}
+
+			if b.Has(node.Path("resources/adjtimestatuses.v1alpha1.talos.dev.yaml")) {
+				statuses, err := node.Resources("adjtimestatuses.v1alpha1.talos.dev")
+				if err != nil {
+					return findings, err
+				}
+
+				for _, status := range statuses {
+					spec, _ := status.Spec.(map[string]any) //nolint:errcheck
+
+					offset, ok := parseDuration(spec["offset"])
+					if !ok || offset.Abs() <= maxTimeOffset {
+						continue
+					}
+
+					findings = append(findings, Finding{
+						Node:        node.Name,
+						Severity:    SeverityWarning,
+						Message:     fmt.Sprintf("clock offset is %s", offset),
+						Remediation: remediation,
+					})
+				}
+			}
+		}
+
+		return findings, nil
+	})
+}
+
+// parseDuration parses the duration encoded as the string (e.g. `1.5s`) or the number of nanoseconds.
+func parseDuration(v any) (time.Duration, bool) {
+	switch v := v.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+
+		return d, err == nil
+	case int:
+		return time.Duration(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,116 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze
+
+import (
+	"fmt"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// crashLoopFailures is the number of the failures in the service event history which marks the service as crashlooping.
+const crashLoopFailures = 3
+
+// podRestartsWarning is the number of the container restarts which is reported.
+const podRestartsWarning = 5
+
+// ServiceHealth creates the rule which reports the failed, unhealthy and crashlooping Talos services
+// and the crashlooping pods in the kube-system namespace.
+func ServiceHealth() Rule {
+	return NewRule("service-health", func(b *bundlereader.Bundle) ([]Finding, error) {
+		var findings []Finding
+
+		for _, node := range b.Nodes() {
+			for _, id := range node.Services() {
+				status, err := node.ServiceStatus(id)
+				if err != nil {
+					return findings, err
+				}
+
+				findings = append(findings, checkService(node.Name, id, status)...)
+			}
+		}
+
+		if !b.Has(bundlereader.KubernetesResourcesDir + "/systemPods.yaml") {
+			return findings, nil
+		}
+
+		pods, err := b.KubernetesSystemPods()
+		if err != nil {
+			return findings, err
+		}
+
+		for _, pod := range pods.Items {
+			for _, status := range pod.Status.ContainerStatuses {
+				switch {
+				case status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff":
+					findings = append(findings, Finding{
+						Severity: SeverityCritical,
+						Message: fmt.Sprintf("container %s of pod kube-system/%s on node %s is crashlooping (%d restarts)",
+							status.Name, pod.Name, pod.Spec.NodeName, status.RestartCount),
+						Remediation: fmt.Sprintf("check the container logs in the kubernetes-logs directory of node %s", pod.Spec.NodeName),
+					})
+				case status.RestartCount >= podRestartsWarning:
+					findings = append(findings, Finding{
+						Severity: SeverityWarning,
+						Message: fmt.Sprintf("container %s of pod kube-system/%s on node %s restarted %d times",
+							status.Name, pod.Name, pod.Spec.NodeName, status.RestartCount),
+						Remediation: "check the previous container logs and the pod events for the restart reason",
+					})
+				}
+			}
+		}
+
+		return findings, nil
+	})
+}
+
+func checkService(node, id string, status *bundlereader.ServiceStatus) []Finding {
+	var findings []Finding
+
+	failures := 0
+
+	for _, event := range status.Events {
+		if event.State == "Failed" {
+			failures++
+		}
+	}
+
+	remediation := fmt.Sprintf("check service-logs/%s.log of the node, `talosctl -n %s service %s` shows the current state", id, node, id)
+
+	switch {
+	case failures >= crashLoopFailures:
+		findings = append(findings, Finding{
+			Node:        node,
+			Severity:    SeverityCritical,
+			Message:     fmt.Sprintf("service %s is crashlooping: %d failures in the recent events", id, failures),
+			Remediation: remediation,
+		})
+	case status.State == "Failed":
+		findings = append(findings, Finding{
+			Node:        node,
+			Severity:    SeverityCritical,
+			Message:     fmt.Sprintf("service %s failed", id),
+			Remediation: remediation,
+		})
+	}
+
+	if status.Health == "Fail" {
+		message := fmt.Sprintf("service %s is unhealthy", id)
+
+		if status.HealthMessage != "" {
+			message += ": " + status.HealthMessage
+		}
+
+		findings = append(findings, Finding{
+			Node:        node,
+			Severity:    SeverityWarning,
+			Message:     message,
+			Remediation: remediation,
+		})
+	}
+
+	return findings
+}
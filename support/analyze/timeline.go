@@ -0,0 +1,391 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// TimelineFile is the default name of the rendered timeline.
+const TimelineFile = "timeline.txt"
+
+// DefaultTimelineWindow is the time before and after the incident included in the timeline.
+const DefaultTimelineWindow = 10 * time.Minute
+
+// maxTimelineMessageLength is the length of the timeline event message, the longer messages are truncated.
+const maxTimelineMessageLength = 500
+
+// TimelineSource is the kind of the data the timeline event comes from.
+type TimelineSource string
+
+// Timeline sources.
+const (
+	// TimelineSourceDmesg is the kernel log of the node.
+	TimelineSourceDmesg TimelineSource = "dmesg"
+	// TimelineSourceTalosEvents are the Talos service state events of the node.
+	TimelineSourceTalosEvents TimelineSource = "talos-events"
+	// TimelineSourceServiceLogs are the Talos service logs of the node.
+	TimelineSourceServiceLogs TimelineSource = "service-logs"
+	// TimelineSourceKubernetesEvents are the Kubernetes events.
+	TimelineSourceKubernetesEvents TimelineSource = "kubernetes-events"
+)
+
+// TimelineOptions configures the timeline.
+type TimelineOptions struct {
+	// Incident is the time of the incident, the timeline includes the events within Window around it.
+	//
+	// All events are included if zero.
+	Incident time.Time
+	// Window is the time before and after the incident, DefaultTimelineWindow is used if zero.
+	Window time.Duration
+	// Sources limits the timeline to the sources, all sources are used if empty.
+	Sources []TimelineSource
+	// Nodes limits the timeline to the events of the nodes, the cluster wide events are skipped if set.
+	Nodes []string
+}
+
+// TimelineEvent is the timestamped event from the bundle.
+type TimelineEvent struct {
+	Time    time.Time      `yaml:"time" json:"time"`
+	Source  TimelineSource `yaml:"source" json:"source"`
+	Node    string         `yaml:"node,omitempty" json:"node,omitempty"`
+	Path    string         `yaml:"path" json:"path"`
+	Message string         `yaml:"message" json:"message"`
+}
+
+// Timeline merges the timestamped events from dmesg, Talos service events, Talos service logs and Kubernetes events
+// into the single timeline ordered by time.
+//
+// The Talos service events are recorded with the age relative to the bundle creation, so their time is approximate,
+// and they are skipped if the bundle has no metadata with the creation time.
+// The log lines without the recognized timestamp are skipped.
+func Timeline(b *bundlereader.Bundle, opts TimelineOptions) ([]TimelineEvent, error) {
+	if opts.Window <= 0 {
+		opts.Window = DefaultTimelineWindow
+	}
+
+	t := &timeline{
+		opts:    opts,
+		ref:     referenceTime(b),
+		created: b.Metadata() != nil && !b.Metadata().Created.IsZero(),
+	}
+
+	for _, node := range b.Nodes() {
+		if len(opts.Nodes) > 0 && !slices.Contains(opts.Nodes, node.Name) {
+			continue
+		}
+
+		if err := t.collectNode(b, node); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Nodes) == 0 && t.enabled(TimelineSourceKubernetesEvents) && b.Has(bundlereader.KubernetesResourcesDir+"/events.yaml") {
+		events, err := b.KubernetesEvents()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events.Items {
+			t.addKubernetesEvent(event)
+		}
+	}
+
+	slices.SortStableFunc(t.events, func(a, b TimelineEvent) int {
+		return cmp.Or(a.Time.Compare(b.Time), strings.Compare(a.Node, b.Node), strings.Compare(a.Path, b.Path))
+	})
+
+	return t.events, nil
+}
+
+// WriteTimeline renders the timeline as a table.
+//
+// If the incident time is not zero, the incident is marked in the timeline.
+func WriteTimeline(w io.Writer, events []TimelineEvent, incident time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tNODE\tSOURCE\tMESSAGE") //nolint:errcheck
+
+	marked := incident.IsZero()
+
+	for _, event := range events {
+		if !marked && !event.Time.Before(incident) {
+			fmt.Fprintf(tw, "%s\t\t\t>>> incident\n", incident.UTC().Format(time.RFC3339Nano)) //nolint:errcheck
+
+			marked = true
+		}
+
+		node := event.Node
+		if node == "" {
+			node = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", event.Time.UTC().Format(time.RFC3339Nano), node, event.Source, event.Message) //nolint:errcheck
+	}
+
+	if !marked {
+		fmt.Fprintf(tw, "%s\t\t\t>>> incident\n", incident.UTC().Format(time.RFC3339Nano)) //nolint:errcheck
+	}
+
+	return tw.Flush()
+}
+
+// WriteTimelineFile renders the timeline to the TimelineFile in the directory.
+func WriteTimelineFile(dir string, events []TimelineEvent, incident time.Time) error {
+	var buf bytes.Buffer
+
+	if err := WriteTimeline(&buf, events, incident); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, TimelineFile), buf.Bytes(), 0o644)
+}
+
+type timeline struct {
+	ref     time.Time
+	opts    TimelineOptions
+	events  []TimelineEvent
+	created bool
+}
+
+func (t *timeline) enabled(source TimelineSource) bool {
+	return len(t.opts.Sources) == 0 || slices.Contains(t.opts.Sources, source)
+}
+
+func (t *timeline) add(event TimelineEvent) {
+	if !t.opts.Incident.IsZero() && (event.Time.Before(t.opts.Incident.Add(-t.opts.Window)) || event.Time.After(t.opts.Incident.Add(t.opts.Window))) {
+		return
+	}
+
+	event.Message = strings.TrimSpace(event.Message)
+
+	if len(event.Message) > maxTimelineMessageLength {
+		event.Message = event.Message[:maxTimelineMessageLength] + "..."
+	}
+
+	t.events = append(t.events, event)
+}
+
+func (t *timeline) collectNode(b *bundlereader.Bundle, node *bundlereader.Node) error {
+	if t.enabled(TimelineSourceDmesg) && slices.Contains(node.Files(), "dmesg.log") {
+		if err := b.Lines(node.Path("dmesg.log"), func(line string) bool {
+			if ts, message, ok := parseDmesgLine(line); ok {
+				t.add(TimelineEvent{
+					Time:    ts,
+					Source:  TimelineSourceDmesg,
+					Node:    node.Name,
+					Path:    node.Path("dmesg.log"),
+					Message: message,
+				})
+			}
+
+			return true
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range node.Files() {
+		name, ok := strings.CutPrefix(file, "service-logs/")
+		if !ok {
+			continue
+		}
+
+		if id, isState := strings.CutSuffix(name, ".state"); isState && t.enabled(TimelineSourceTalosEvents) && t.created {
+			if err := t.collectServiceEvents(node, id); err != nil {
+				return err
+			}
+		}
+
+		id, isLog := strings.CutSuffix(name, ".log")
+		if !isLog || !t.enabled(TimelineSourceServiceLogs) {
+			continue
+		}
+
+		if err := b.Lines(node.Path(file), func(line string) bool {
+			if ts, found := parseLogTimestamp(line, t.ref); found {
+				t.add(TimelineEvent{
+					Time:    ts,
+					Source:  TimelineSourceServiceLogs,
+					Node:    node.Name,
+					Path:    node.Path(file),
+					Message: id + ": " + line,
+				})
+			}
+
+			return true
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *timeline) collectServiceEvents(node *bundlereader.Node, id string) error {
+	status, err := node.ServiceStatus(id)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range status.Events {
+		t.add(TimelineEvent{
+			Time:    t.ref.Add(-event.Age),
+			Source:  TimelineSourceTalosEvents,
+			Node:    node.Name,
+			Path:    node.Path(path.Join("service-logs", id+".state")),
+			Message: fmt.Sprintf("%s [%s]: %s", id, event.State, event.Message),
+		})
+	}
+
+	return nil
+}
+
+func (t *timeline) addKubernetesEvent(event corev1.Event) {
+	ts := event.LastTimestamp.Time
+
+	for _, candidate := range []time.Time{event.EventTime.Time, event.FirstTimestamp.Time, event.CreationTimestamp.Time} {
+		if !ts.IsZero() {
+			break
+		}
+
+		ts = candidate
+	}
+
+	if ts.IsZero() {
+		return
+	}
+
+	object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+	if event.InvolvedObject.Namespace != "" {
+		object = event.InvolvedObject.Namespace + "/" + object
+	}
+
+	message := fmt.Sprintf("%s %s %s: %s", event.Type, event.Reason, object, event.Message)
+	if event.Count > 1 {
+		message += fmt.Sprintf(" (x%d)", event.Count)
+	}
+
+	t.add(TimelineEvent{
+		Time:    ts,
+		Source:  TimelineSourceKubernetesEvents,
+		Node:    event.Source.Host,
+		Path:    bundlereader.KubernetesResourcesDir + "/events.yaml",
+		Message: message,
+	})
+}
+
+// dmesgLineRe matches the kernel log line as rendered by Talos, e.g. `kern: info: [2024-09-20T10:00:00.1Z]: message`.
+var dmesgLineRe = regexp.MustCompile(`^(?:[^\[]*: )?\[([0-9]{4}-[0-9]{2}-[0-9]{2}T[^\]]+)\]:? ?(.*)$`)
+
+func parseDmesgLine(line string) (time.Time, string, bool) {
+	match := dmesgLineRe.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, "", false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, match[1])
+	if err != nil {
+		return time.Time{}, "", false
+	}
+
+	return ts, match[2], true
+}
+
+// Log timestamp layouts without the time zone, they are assumed to be UTC.
+const (
+	goLogTimestampLayout = "2006/01/02 15:04:05"
+	klogTimestampLayout  = "0102 15:04:05.000000"
+)
+
+// logfmtTimeRe matches the logfmt time field, e.g. `time="2024-09-20T10:00:00Z"`.
+var logfmtTimeRe = regexp.MustCompile(`\b(?:time|ts)="?([0-9]{4}-[0-9]{2}-[0-9]{2}T[^" ]+)"?`)
+
+// parseLogTimestamp parses the timestamp of the log line in the formats used by the Talos services: RFC3339 prefix,
+// Go log package, klog, logfmt and JSON (zap) logs.
+//
+// The year missing in klog timestamps is taken from the reference time.
+func parseLogTimestamp(line string, ref time.Time) (time.Time, bool) {
+	if field, _, ok := strings.Cut(line, " "); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, field); err == nil {
+			return ts, true
+		}
+	}
+
+	if len(line) >= len(goLogTimestampLayout) {
+		if ts, err := time.Parse(goLogTimestampLayout, line[:len(goLogTimestampLayout)]); err == nil {
+			return ts, true
+		}
+	}
+
+	// klog: `I0920 10:00:00.123456    1234 file.go:10] message`
+	if len(line) > len(klogTimestampLayout) && strings.ContainsRune("IWEF", rune(line[0])) {
+		if ts, err := time.Parse(klogTimestampLayout, line[1:len(klogTimestampLayout)+1]); err == nil {
+			return ts.AddDate(ref.Year(), 0, 0), true
+		}
+	}
+
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLogTimestamp(line)
+	}
+
+	if match := logfmtTimeRe.FindStringSubmatch(line); match != nil {
+		if ts, err := time.Parse(time.RFC3339Nano, match[1]); err == nil {
+			return ts, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func parseJSONLogTimestamp(line string) (time.Time, bool) {
+	var entry struct {
+		TS   json.RawMessage `json:"ts"`
+		Time json.RawMessage `json:"time"`
+	}
+
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return time.Time{}, false
+	}
+
+	for _, raw := range []json.RawMessage{entry.TS, entry.Time} {
+		if len(raw) == 0 {
+			continue
+		}
+
+		var s string
+
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if ts, parseErr := time.Parse(time.RFC3339Nano, s); parseErr == nil {
+				return ts, true
+			}
+
+			continue
+		}
+
+		// zap logs the Unix time in seconds as the float number
+		if seconds, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
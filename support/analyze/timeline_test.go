@@ -0,0 +1,96 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package analyze_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/siderolabs/go-talos-support/support/analyze"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+func TestTimeline(t *testing.T) {
+	require := require.New(t)
+
+	events, err := yaml.Marshal(corev1.EventList{Items: []corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "kube-apiserver.1", Namespace: "kube-system"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "kube-apiserver-node-1"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			LastTimestamp:  metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 5, 0, time.UTC)),
+			Count:          3,
+		},
+		{
+			ObjectMeta:    metav1.ObjectMeta{Name: "old.1", Namespace: "default"},
+			Reason:        "Old",
+			LastTimestamp: metav1.NewTime(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)),
+		},
+	}})
+	require.NoError(err)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                   {Data: []byte("created: 2024-01-01T00:01:00Z\n")},
+		"kubernetesResources/events.yaml": {Data: events},
+		"10.5.0.2/dmesg.log": {Data: []byte(
+			"kern:    info: [2024-01-01T00:00:01.5Z]: Linux version 6.6.0\n" +
+				"kern:     err: [2024-01-01T00:00:04Z]: ext4: I/O error\n",
+		)},
+		"10.5.0.2/service-logs/etcd.state": {Data: []byte(
+			"ID       etcd\nSTATE    Running\nHEALTH   OK\nEVENTS   [Running]: started (57s ago)\n",
+		)},
+		"10.5.0.2/service-logs/etcd.log": {Data: []byte(
+			`{"level":"warn","ts":"2024-01-01T00:00:02Z","msg":"slow fdatasync"}` + "\n" +
+				"continuation line without timestamp\n",
+		)},
+		"10.5.0.2/service-logs/kubelet.log": {Data: []byte(
+			"E0101 00:00:06.000000    1234 kubelet.go:10] node not found\n",
+		)},
+	})
+	require.NoError(err)
+
+	incident := time.Date(2024, 1, 1, 0, 0, 4, 0, time.UTC)
+
+	timeline, err := analyze.Timeline(b, analyze.TimelineOptions{Incident: incident, Window: time.Minute})
+	require.NoError(err)
+
+	messages := make([]string, 0, len(timeline))
+
+	for _, event := range timeline {
+		messages = append(messages, fmt.Sprintf("%s %s %s", event.Time.Format(time.RFC3339Nano), event.Source, event.Message))
+	}
+
+	require.Equal([]string{
+		"2024-01-01T00:00:01.5Z dmesg Linux version 6.6.0",
+		`2024-01-01T00:00:02Z service-logs etcd: {"level":"warn","ts":"2024-01-01T00:00:02Z","msg":"slow fdatasync"}`,
+		"2024-01-01T00:00:03Z talos-events etcd [Running]: started",
+		"2024-01-01T00:00:04Z dmesg ext4: I/O error",
+		"2024-01-01T00:00:05Z kubernetes-events Warning BackOff kube-system/pod/kube-apiserver-node-1: Back-off restarting failed container (x3)",
+		"2024-01-01T00:00:06Z service-logs kubelet: E0101 00:00:06.000000    1234 kubelet.go:10] node not found",
+	}, messages)
+
+	timeline, err = analyze.Timeline(b, analyze.TimelineOptions{Sources: []analyze.TimelineSource{analyze.TimelineSourceKubernetesEvents}})
+	require.NoError(err)
+	require.Len(timeline, 2)
+
+	var buf bytes.Buffer
+
+	require.NoError(analyze.WriteTimeline(&buf, timeline[1:], incident))
+	assert.Equal(t, "TIME                   NODE   SOURCE              MESSAGE\n"+
+		"2024-01-01T00:00:04Z                              >>> incident\n"+
+		"2024-01-01T00:00:05Z   -      kubernetes-events   Warning BackOff kube-system/pod/kube-apiserver-node-1: Back-off restarting failed container (x3)\n",
+		buf.String())
+}
@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// Artifacts returns the files the collectors are expected to write, to check the bundle with bundlereader.Validate.
+func Artifacts(cols []*collectors.Collector) []bundlereader.Artifact {
+	artifacts := make([]bundlereader.Artifact, 0, len(cols))
+
+	for _, c := range cols {
+		artifact := bundlereader.Artifact{
+			Path: filepath.ToSlash(c.Path()),
+		}
+
+		if c.Source() != collectors.Cluster {
+			if p, ok := strings.CutPrefix(artifact.Path, c.Source()+"/"); ok {
+				artifact.Path = p
+				artifact.Node = c.Source()
+			}
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts
+}
@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AttestationFile is the name of the file listing the collected and refused files in the allow-list mode.
+const AttestationFile = "attestation.yaml"
+
+// Attestation lists the files which were collected and refused in the allow-list mode.
+type Attestation struct {
+	AllowList []string `yaml:"allowList"`
+	Collected []string `yaml:"collected"`
+	Refused   []string `yaml:"refused"`
+	Failed    []string `yaml:"failed,omitempty"`
+}
+
+// Allowed checks if the file with the path is allowed by the allow list set by WithAllowList.
+//
+// All files are allowed if the allow-list mode is not enabled.
+func (options *Options) Allowed(p string) bool {
+	if !options.AllowListMode {
+		return true
+	}
+
+	return slices.ContainsFunc(options.AllowList, func(pattern string) bool {
+		return MatchPath(pattern, p)
+	})
+}
+
+// MatchPath matches the path against the path.Match pattern, the pattern ending with `/**` matches all files
+// in the directory recursively.
+func MatchPath(pattern, p string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		segments := strings.Split(p, "/")
+		dirSegments := strings.Count(dir, "/") + 1
+
+		if len(segments) <= dirSegments {
+			return false
+		}
+
+		p = strings.Join(segments[:dirSegments], "/")
+		pattern = dir
+	}
+
+	ok, _ := path.Match(pattern, p) //nolint:errcheck
+
+	return ok
+}
+
+// WriteAttestation writes the attestation file to the archive in the allow-list mode.
+func (options *Options) WriteAttestation(attestation Attestation) error {
+	if !options.AllowListMode {
+		return nil
+	}
+
+	attestation.AllowList = options.AllowList
+
+	for _, list := range [][]string{attestation.Collected, attestation.Refused, attestation.Failed} {
+		slices.Sort(list)
+	}
+
+	data, err := yaml.Marshal(attestation)
+	if err != nil {
+		return err
+	}
+
+	return options.Archive.Write(options.ArchivePath(AttestationFile), data)
+}
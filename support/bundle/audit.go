@@ -0,0 +1,102 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditLogFile is the name of the audit log file in the archive.
+const AuditLogFile = "audit.log"
+
+// Audit outcomes.
+const (
+	AuditOutcomeOK     = "ok"
+	AuditOutcomeFailed = "failed"
+)
+
+// AuditEntry is the record of the API calls made by a collector, the audit log has one JSON entry per line.
+type AuditEntry struct {
+	// Time is when the collector started.
+	Time time.Time `json:"time"`
+	// Requester is the identity of the bundle requester set by WithRequester.
+	Requester string `json:"requester,omitempty"`
+	// Node is the Talos node the data was pulled from, it's empty for the cluster collectors.
+	Node string `json:"node,omitempty"`
+	// Endpoint is the API endpoint the calls were made to if it's known: the node endpoint set by WithNodeEndpoints,
+	// the endpoints of the talosconfig context or the Kubernetes API server.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Collector is the name of the collector, it's the category of the API calls, e.g. `talos.dmesg` or `k8s.nodes`.
+	Collector string `json:"collector"`
+	// Path is the archive path of the collected file, it's empty for the API calls made while the collectors are created.
+	Path    string `json:"path,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+	// Duration is how long the collector ran, including the retries.
+	Duration time.Duration `json:"duration"`
+}
+
+type auditLog struct {
+	entries []AuditEntry
+	mu      sync.Mutex
+}
+
+// RecordAudit adds the collector API calls to the audit log if it's enabled by WithAuditLog.
+//
+// The requester and the endpoint are filled in from the options.
+func (options *Options) RecordAudit(entry AuditEntry) {
+	if !options.AuditLog || options.audit == nil {
+		return
+	}
+
+	entry.Requester = options.Requester
+	entry.Time = entry.Time.UTC()
+
+	if entry.Endpoint == "" {
+		if entry.Node != "" {
+			entry.Endpoint = cmp.Or(options.NodeEndpoints[entry.Node], options.talosEndpoint)
+		} else {
+			entry.Endpoint = options.kubernetesHost
+		}
+	}
+
+	options.audit.mu.Lock()
+	defer options.audit.mu.Unlock()
+
+	options.audit.entries = append(options.audit.entries, entry)
+}
+
+// WriteAuditLog writes the audit log to the archive if it's enabled by WithAuditLog.
+func (options *Options) WriteAuditLog() error {
+	if !options.AuditLog || options.audit == nil {
+		return nil
+	}
+
+	options.audit.mu.Lock()
+	defer options.audit.mu.Unlock()
+
+	entries := slices.Clone(options.audit.entries)
+
+	slices.SortStableFunc(entries, func(a, b AuditEntry) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	return options.Archive.Write(options.ArchivePath(AuditLogFile), buf.Bytes())
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+func TestAuditLog(t *testing.T) {
+	require := require.New(t)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithAuditLog(),
+		bundle.WithRequester("alice@example.com"),
+		bundle.WithNodeEndpoints(map[string]string{"n1": "10.5.0.2"}),
+	)
+
+	started := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	options.RecordAudit(bundle.AuditEntry{
+		Time:      started.Add(time.Second),
+		Node:      "n1",
+		Collector: "talos.dmesg",
+		Path:      "n1/dmesg.log",
+		Outcome:   bundle.AuditOutcomeOK,
+	})
+	options.RecordAudit(bundle.AuditEntry{
+		Time:      started,
+		Collector: "k8s.nodes",
+		Outcome:   bundle.AuditOutcomeFailed,
+		Error:     "connection refused",
+	})
+
+	require.NoError(options.WriteAuditLog())
+
+	lines := strings.Split(strings.TrimSpace(string(archive.files[bundle.AuditLogFile])), "\n")
+	require.Len(lines, 2)
+
+	entries := make([]bundle.AuditEntry, 0, len(lines))
+
+	for _, line := range lines {
+		var entry bundle.AuditEntry
+
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+
+		entries = append(entries, entry)
+	}
+
+	// the entries are sorted by time
+	require.Equal("k8s.nodes", entries[0].Collector)
+	require.Equal(started.UTC(), entries[0].Time)
+	require.Equal("alice@example.com", entries[0].Requester)
+	require.Empty(entries[0].Path)
+	require.Equal("connection refused", entries[0].Error)
+
+	require.Equal("talos.dmesg", entries[1].Collector)
+	require.Equal("10.5.0.2", entries[1].Endpoint)
+	require.Equal("n1/dmesg.log", entries[1].Path)
+
+	// nothing is recorded without the audit log
+	archive = &testArchive{}
+
+	options = bundle.NewOptions(bundle.WithArchive(archive))
+	options.RecordAudit(bundle.AuditEntry{Collector: "talos.dmesg"})
+
+	require.NoError(options.WriteAuditLog())
+	require.NotContains(archive.files, bundle.AuditLogFile)
+}
@@ -7,29 +7,163 @@ package bundle
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Options defines GetSupportBundle options.
 type Options struct {
-	TalosClient      *client.Client
-	KubernetesClient *kubernetes.Clientset
-	Archive          Archive
-	LogOutput        io.Writer
-	Progress         chan Progress
-	Nodes            []string
+	CollectorConfig
 
-	NumWorkers int
+	TalosClient         *client.Client
+	TalosConfigProvider TalosConfigProvider
+	NodeSelector        func(node NodeInfo) bool
+	ProgressFunc        func(progress Progress)
+	ErrorHandler        func(source, path string, err error) Decision
+	CompletionFunc      func(completion Completion)
+	RESTConfig          *rest.Config
+	kubernetesConfig    *rest.Config
+	NodeEndpoints       map[string]string
+	LogTailLinesFor     map[string]int32
+	Metadata            map[string]string
+	nodeClients         map[string]*client.Client
+	redactionReport     *RedactionReport
+	secretScan          *secretScan
+	manifest            *manifest
+	cache               *resultCache
+	stats               *bundleStats
+	audit               *auditLog
+	Upload              *UploadConfig
+	upload              *uploadArchive
+	uploadResult        *UploadResult
+	progressWriter      *progressWriter
+	SFTPOutput          *SFTPOutput
+	sftpArchive         *sftpArchive
+	writeQueue          *writeQueue
+	KubernetesClient    kubernetes.Interface
+	DynamicClient       dynamic.Interface
+	Archive             Archive
+	LogOutput           io.Writer
+	ProgressWriter      io.Writer
+	Clock               Clock
+	Progress            chan Progress
+	LogsSince           time.Time
+	LogsUntil           time.Time
+	Kubeconfig          string
+	TalosContext        string
+	NodeLabels          string
+	PathPrefix          string
+	TempDir             string
+	RedactionRulesFile  string
+	CompletionWebhook   string
+	Requester           string
+	kubernetesHost      string
+	talosEndpoint       string
+	Nodes               []string
+	CustomResources     []schema.GroupVersionResource
+	PodLogSelectors     []PodLogSelector
+	ExtraCollectors     []Collector
+	Redactors           []Redactor
+	AllowList           []string
+
+	NumWorkers        int
+	KubernetesWorkers int
+	ResourceWorkers   int
+	Since             time.Duration
+	CollectorTimeout  time.Duration
+	ResourceTimeout   time.Duration
+	CollectorBackoff  time.Duration
+	AdaptiveLatency   time.Duration
+	PodLogTailLines   int64
+	WriteQueueSize    int64
+	CompressThreshold int64
+	KubernetesBurst   int
+	CollectorAttempts int
+	KubernetesQPS     float32
+	Profile           Profile
+	NodeRole          NodeRole
+
+	KubernetesFromTalos bool
+	InCluster           bool
+	SkipNodeDiscovery   bool
+	SkipKubernetes      bool
+	SkipTalos           bool
+	RedactSecrets       bool
+	RedactPII           bool
+	AllowListMode       bool
+	SecretScan          bool
+	MachineConfig       bool
+	AuditLog            bool
+	ParallelCompression bool
+	talosClientCreated  bool
+}
+
+// TalosConfigProvider loads the Talos client configuration.
+type TalosConfigProvider func(ctx context.Context) (*clientconfig.Config, error)
+
+// InClusterTalosconfig is the path of the talosconfig mounted into the pods by the Talos ServiceAccount.
+var InClusterTalosconfig = path.Join(constants.ServiceAccountMountPath, constants.TalosconfigFilename)
+
+// InClusterConfig loads the Kubernetes client config of the pod ServiceAccount.
+var InClusterConfig = rest.InClusterConfig
+
+// inClusterTalosConfig loads the talosconfig mounted by the Talos ServiceAccount.
+func inClusterTalosConfig(context.Context) (*clientconfig.Config, error) {
+	return clientconfig.Open(InClusterTalosconfig)
+}
+
+// CollectorConfig configures the collectors which are expensive or produce large files.
+type CollectorConfig struct {
+	Namespaces    []string
+	PacketCapture PacketCaptureConfig
+	MaxFileSize   int64
+	MaxStreamSize int64
+	LogTailLines  int32
+	EtcdSnapshot  bool
+	// KubeProxyRules samples the rules generated by kube-proxy on a few nodes, it is always enabled by ProfileFull.
+	//
+	// The rules are dumped in the kube-proxy pods, so it needs the pods/exec and pods/proxy permissions in kube-system.
+	KubeProxyRules bool
+}
+
+// PacketCaptureConfig configures the packet capture on the nodes.
+//
+// The packets are captured on each interface for the duration, zero duration disables the capture.
+type PacketCaptureConfig struct {
+	Interfaces []string
+	Duration   time.Duration
 }
 
 // NewOptions creates new Options.
 func NewOptions(opts ...Option) *Options {
-	var options Options
+	options := Options{
+		redactionReport: &RedactionReport{},
+		secretScan:      &secretScan{},
+		manifest:        &manifest{},
+		progressWriter:  &progressWriter{},
+		cache:           &resultCache{},
+		stats:           &bundleStats{},
+		audit:           &auditLog{},
+	}
 
 	for _, o := range opts {
 		o(&options)
@@ -38,12 +172,605 @@ func NewOptions(opts ...Option) *Options {
 	return &options
 }
 
+// Validate checks the options for missing or conflicting settings.
+func (options *Options) Validate() error {
+	var errs []error
+
+	hasTalos := options.TalosClient != nil || options.TalosConfigProvider != nil || options.InCluster
+	hasKubernetes := options.KubernetesClient != nil || options.RESTConfig != nil || options.Kubeconfig != "" ||
+		(options.KubernetesFromTalos && hasTalos) || options.InCluster
+
+	if options.Archive == nil && options.Upload == nil && options.SFTPOutput == nil {
+		errs = append(errs, errors.New("archive is not set"))
+	}
+
+	if options.SFTPOutput != nil {
+		if options.Archive != nil && options.sftpArchive == nil {
+			errs = append(errs, errors.New("both archive and SFTP output are set"))
+		}
+
+		if err := options.SFTPOutput.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if options.Upload != nil {
+		if err := options.Upload.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if options.CompletionWebhook != "" {
+		if err := validateWebhook(options.CompletionWebhook); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if options.NumWorkers < 0 {
+		errs = append(errs, fmt.Errorf("number of workers must not be negative, got %d", options.NumWorkers))
+	}
+
+	if options.KubernetesWorkers < 0 {
+		errs = append(errs, fmt.Errorf("number of Kubernetes workers must not be negative, got %d", options.KubernetesWorkers))
+	}
+
+	if options.ResourceWorkers < 0 {
+		errs = append(errs, fmt.Errorf("number of resource workers must not be negative, got %d", options.ResourceWorkers))
+	}
+
+	if len(options.Nodes) > 0 && !hasTalos {
+		errs = append(errs, errors.New("nodes are set without the Talos client"))
+	}
+
+	if len(options.NodeEndpoints) > 0 && !hasTalos {
+		errs = append(errs, errors.New("node endpoints are set without the Talos client"))
+	}
+
+	if options.KubernetesFromTalos && !hasTalos {
+		errs = append(errs, errors.New("the Kubernetes client from Talos is requested without the Talos client"))
+	}
+
+	if options.SelectsNodes() && !hasKubernetes {
+		errs = append(errs, errors.New("node selection requires the Kubernetes client"))
+	}
+
+	if options.KubernetesQPS < 0 || options.KubernetesBurst < 0 {
+		errs = append(errs, errors.New("the Kubernetes rate limit must not be negative"))
+	}
+
+	if options.LogTailLines < 0 || options.PodLogTailLines < 0 {
+		errs = append(errs, errors.New("log tail lines must not be negative"))
+	}
+
+	if options.CompressThreshold < 0 {
+		errs = append(errs, fmt.Errorf("compression threshold must not be negative, got %d", options.CompressThreshold))
+	}
+
+	if options.WriteQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("write queue size must not be negative, got %d", options.WriteQueueSize))
+	}
+
+	if options.MaxFileSize < 0 {
+		errs = append(errs, fmt.Errorf("max file size must not be negative, got %d", options.MaxFileSize))
+	}
+
+	if options.MaxStreamSize < 0 {
+		errs = append(errs, fmt.Errorf("max stream size must not be negative, got %d", options.MaxStreamSize))
+	}
+
+	if options.AdaptiveLatency < 0 {
+		errs = append(errs, fmt.Errorf("adaptive workers latency must not be negative, got %s", options.AdaptiveLatency))
+	}
+
+	if options.CollectorTimeout < 0 {
+		errs = append(errs, fmt.Errorf("collector timeout must not be negative, got %s", options.CollectorTimeout))
+	}
+
+	if options.ResourceTimeout < 0 {
+		errs = append(errs, fmt.Errorf("resource timeout must not be negative, got %s", options.ResourceTimeout))
+	}
+
+	if options.PacketCapture.Duration < 0 {
+		errs = append(errs, fmt.Errorf("packet capture duration must not be negative, got %s", options.PacketCapture.Duration))
+	}
+
+	if options.PacketCapture.Duration > 0 && len(options.PacketCapture.Interfaces) == 0 {
+		errs = append(errs, errors.New("packet capture requires at least one interface"))
+	}
+
+	if options.Since < 0 {
+		errs = append(errs, fmt.Errorf("since duration must not be negative, got %s", options.Since))
+	}
+
+	if since, until := options.TimeRange(); !since.IsZero() && !until.IsZero() && until.Before(since) {
+		errs = append(errs, fmt.Errorf("time range end %s is before its start %s", until, since))
+	}
+
+	if options.Profile < ProfileStandard || options.Profile > ProfileFull {
+		errs = append(errs, fmt.Errorf("unknown profile %d", options.Profile))
+	}
+
+	if options.NodeRole < NodeRoleAny || options.NodeRole > NodeRoleWorker {
+		errs = append(errs, fmt.Errorf("unknown node role %d", options.NodeRole))
+	}
+
+	for _, pattern := range options.AllowList {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid allow list pattern %q: %w", pattern, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CollectsKubernetes checks if the data is collected using Kubernetes API.
+func (options *Options) CollectsKubernetes() bool {
+	return !options.SkipKubernetes && options.KubernetesClient != nil
+}
+
+// CollectsTalos checks if the data is collected from the nodes using Talos API.
+func (options *Options) CollectsTalos() bool {
+	return !options.SkipTalos && options.TalosClient != nil && len(options.Nodes) > 0
+}
+
+// KubernetesNamespaces returns the list of namespaces to collect Kubernetes resources from.
+//
+// kube-system is always included.
+func (options *Options) KubernetesNamespaces() []string {
+	namespaces := []string{"kube-system"}
+
+	for _, ns := range options.Namespaces {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return namespaces
+}
+
+// TailLines returns the number of log lines to collect for the service or container, 0 means no limit.
+func (options *Options) TailLines(name string) int32 {
+	if lines, ok := options.LogTailLinesFor[name]; ok {
+		return lines
+	}
+
+	return options.LogTailLines
+}
+
+// InTimeRange checks if the log entry or event timestamp is within the time range set by WithSince or WithTimeRange.
+func (options *Options) InTimeRange(t time.Time) bool {
+	since, until := options.TimeRange()
+
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+
+	return until.IsZero() || !t.After(until)
+}
+
+// TimeRange returns the time range of the collected logs and events, zero time means the range is open.
+func (options *Options) TimeRange() (since, until time.Time) {
+	if options.Since > 0 {
+		return options.Now().Add(-options.Since), options.LogsUntil
+	}
+
+	return options.LogsSince, options.LogsUntil
+}
+
+// ArchivePath returns the path of the file in the archive with the prefix set by WithPathPrefix.
+//
+// The path is changed by the redactors implementing PathRedactor.
+func (options *Options) ArchivePath(name string) string {
+	for _, redactor := range options.Redactors {
+		if pathRedactor, ok := redactor.(PathRedactor); ok {
+			name = pathRedactor.RedactPath(name)
+		}
+	}
+
+	if options.PathPrefix == "" {
+		return name
+	}
+
+	return path.Join(options.PathPrefix, name)
+}
+
+// MetadataFile is the name of the bundle metadata file in the archive.
+//
+// The metadata is written before the bundle is uploaded, the upload reference is recorded in UploadFile instead.
+const MetadataFile = "metadata.yaml"
+
+// BundleMetadata describes the bundle in the metadata file.
+type BundleMetadata struct {
+	Created time.Time         `yaml:"created"`
+	Custom  map[string]string `yaml:"custom,omitempty"`
+	Nodes   []string          `yaml:"nodes,omitempty"`
+	// LayoutVersion is the version of the bundle file layout, zero for the bundles created before it was versioned.
+	LayoutVersion int `yaml:"layoutVersion,omitempty"`
+}
+
+// WriteMetadata writes the bundle metadata file with the custom metadata set by WithMetadata to the archive.
+func (options *Options) WriteMetadata() error {
+	data, err := yaml.Marshal(BundleMetadata{
+		Created:       options.Now().UTC(),
+		Custom:        options.Metadata,
+		Nodes:         options.Nodes,
+		LayoutVersion: LayoutVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, redactor := range options.Redactors {
+		var count int
+
+		if data, count, err = redactor.Redact(MetadataFile, data); err != nil {
+			return err
+		}
+
+		options.RecordRedaction(options.ArchivePath(MetadataFile), redactor.Name(), count)
+	}
+
+	return options.Archive.Write(options.ArchivePath(MetadataFile), data)
+}
+
+// Clock provides the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Now returns the current time from the clock set by WithClock.
+func (options *Options) Now() time.Time {
+	if options.Clock == nil {
+		return time.Now()
+	}
+
+	return options.Clock.Now()
+}
+
+// Profile selects the set of collectors to run.
+type Profile int
+
+// Collection profiles.
+const (
+	// ProfileStandard collects the default set of data.
+	ProfileStandard Profile = iota
+	// ProfileMinimal collects only the node summaries, services, Talos resources and Kubernetes nodes and system pods.
+	ProfileMinimal
+	// ProfileFull collects everything in the standard profile, the logs of all Kubernetes containers, etcd snapshots
+	// and the kube-proxy rules.
+	ProfileFull
+)
+
+// NodeInfo describes the Kubernetes node passed to the node selector.
+type NodeInfo struct {
+	Labels    map[string]string
+	Name      string
+	Addresses []string
+}
+
+// SelectsNodes checks if the nodes should be selected from the Kubernetes nodes using WithNodeSelector or WithNodeLabels.
+//
+// In the in-cluster mode all Kubernetes nodes are selected if no nodes were provided.
+func (options *Options) SelectsNodes() bool {
+	return options.NodeSelector != nil || options.NodeLabels != "" || (options.InCluster && len(options.Nodes) == 0)
+}
+
+// DiscoversNodes checks if the nodes should be discovered because no nodes or node selectors were provided.
+//
+// The nodes are discovered after the Talos client is initialized, so the talosconfig context nodes take precedence.
+func (options *Options) DiscoversNodes() bool {
+	return !options.SkipNodeDiscovery && !options.SkipTalos && len(options.Nodes) == 0 && !options.SelectsNodes()
+}
+
+// NodeRole selects the nodes to collect the data from by their machine type.
+type NodeRole int
+
+// Node roles.
+const (
+	// NodeRoleAny selects all nodes.
+	NodeRoleAny NodeRole = iota
+	// NodeRoleControlPlane selects only the control plane nodes.
+	NodeRoleControlPlane
+	// NodeRoleWorker selects only the worker nodes.
+	NodeRoleWorker
+)
+
+// Matches checks if the node with the machine type matches the role.
+func (role NodeRole) Matches(machineType machine.Type) bool {
+	switch role { //nolint:exhaustive
+	case NodeRoleControlPlane:
+		return machineType.IsControlPlane()
+	case NodeRoleWorker:
+		return machineType == machine.TypeWorker
+	default:
+		return true
+	}
+}
+
+// PodLogSelector selects the pods to collect logs from using Kubernetes API.
+type PodLogSelector struct {
+	Namespace     string
+	LabelSelector string
+}
+
+// InitTalosClient creates the Talos client from the configuration returned by the TalosConfigProvider.
+//
+// The endpoints are resolved from the selected context, and the nodes of the context are used
+// if no nodes were provided. In the in-cluster mode the talosconfig mounted by the Talos ServiceAccount is used
+// if there is no config provider.
+// It does nothing if the Talos client is already set or there is no config provider.
+func (options *Options) InitTalosClient(ctx context.Context) error {
+	provider := options.TalosConfigProvider
+	if provider == nil && options.InCluster {
+		provider = inClusterTalosConfig
+	}
+
+	if options.TalosClient != nil && options.AuditLog {
+		options.talosEndpoint = strings.Join(options.TalosClient.GetEndpoints(), ",")
+	}
+
+	if options.TalosClient != nil || provider == nil {
+		return nil
+	}
+
+	config, err := provider(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load talosconfig: %w", err)
+	}
+
+	opts := []client.OptionFunc{client.WithConfig(config)}
+
+	if options.TalosContext != "" {
+		opts = append(opts, client.WithContextName(options.TalosContext))
+	}
+
+	c, err := client.New(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create Talos client: %w", err)
+	}
+
+	options.TalosClient = c
+	options.talosClientCreated = true
+	options.talosEndpoint = strings.Join(c.GetEndpoints(), ",")
+
+	if len(options.Nodes) == 0 {
+		if configContext := c.GetConfigContext(); configContext != nil {
+			options.Nodes = configContext.Nodes
+		}
+	}
+
+	return nil
+}
+
+// NodeTalosClient returns the Talos client to use for the node.
+//
+// If the node has an endpoint override, a dedicated client connected to that endpoint is created
+// with the credentials of the Talos client, otherwise the Talos client is returned as is.
+func (options *Options) NodeTalosClient(ctx context.Context, node string) (*client.Client, error) {
+	endpoint, ok := options.NodeEndpoints[node]
+	if !ok || options.TalosClient == nil {
+		return options.TalosClient, nil
+	}
+
+	if c, ok := options.nodeClients[node]; ok {
+		return c, nil
+	}
+
+	configContext := options.TalosClient.GetConfigContext()
+	if configContext == nil {
+		return nil, fmt.Errorf("failed to create Talos client for node %q: Talos client has no config context", node)
+	}
+
+	c, err := client.New(ctx, client.WithConfigContext(configContext), client.WithEndpoints(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Talos client for node %q: %w", node, err)
+	}
+
+	if options.nodeClients == nil {
+		options.nodeClients = map[string]*client.Client{}
+	}
+
+	options.nodeClients[node] = c
+
+	return c, nil
+}
+
+// Close closes the clients created by the bundle creator.
+func (options *Options) Close() error {
+	var errs []error
+
+	for node, c := range options.nodeClients {
+		errs = append(errs, c.Close())
+
+		delete(options.nodeClients, node)
+	}
+
+	if options.talosClientCreated {
+		options.talosClientCreated = false
+
+		errs = append(errs, options.TalosClient.Close())
+	}
+
+	if options.upload != nil {
+		errs = append(errs, options.upload.remove())
+	}
+
+	if options.sftpArchive != nil {
+		errs = append(errs, options.sftpArchive.disconnect())
+	}
+
+	if options.cache != nil {
+		options.cache.clear()
+	}
+
+	return errors.Join(errs...)
+}
+
+// InitKubernetesClient creates the Kubernetes clients from the REST config, the kubeconfig file, the in-cluster config
+// or the admin kubeconfig fetched via Talos API, in that order.
+//
+// It does nothing if the Kubernetes client is already set.
+func (options *Options) InitKubernetesClient(ctx context.Context) error {
+	switch {
+	case options.KubernetesClient != nil:
+		return nil
+	case options.RESTConfig != nil:
+		return options.setKubernetesClients(rest.CopyConfig(options.RESTConfig))
+	case options.Kubeconfig != "":
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = options.Kubeconfig
+
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load kubeconfig %q: %w", options.Kubeconfig, err)
+		}
+
+		return options.setKubernetesClients(config)
+	case options.InCluster:
+		config, err := InClusterConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load in-cluster config: %w", err)
+		}
+
+		return options.setKubernetesClients(config)
+	case options.KubernetesFromTalos && options.TalosClient != nil:
+		return options.initKubernetesClientFromTalos(ctx)
+	default:
+		return nil
+	}
+}
+
+// KubernetesRESTConfig returns the REST config of the Kubernetes client, it's nil if the client was passed without the config.
+//
+// The config is needed for the API calls which are not supported by the clientset, e.g. the commands run in the pods.
+func (options *Options) KubernetesRESTConfig() *rest.Config {
+	if options.kubernetesConfig != nil {
+		return options.kubernetesConfig
+	}
+
+	return options.RESTConfig
+}
+
+func (options *Options) initKubernetesClientFromTalos(ctx context.Context) error {
+	var (
+		kubeconfig []byte
+		err        error
+	)
+
+	if len(options.Nodes) == 0 {
+		kubeconfig, err = options.TalosClient.Kubeconfig(ctx)
+	}
+
+	// kubeconfig can be generated only on the control plane nodes, so try all nodes until it succeeds
+	for _, node := range options.Nodes {
+		kubeconfig, err = options.TalosClient.Kubeconfig(client.WithNode(ctx, node))
+		if err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig via Talos API: %w", err)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	return options.setKubernetesClients(config)
+}
+
+func (options *Options) setKubernetesClients(config *rest.Config) error {
+	if options.KubernetesQPS > 0 {
+		config.QPS = options.KubernetesQPS
+		config.Burst = max(options.KubernetesBurst, 1)
+		config.RateLimiter = nil
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	options.KubernetesClient = clientset
+	options.kubernetesConfig = config
+	options.kubernetesHost = config.Host
+
+	if options.DynamicClient == nil {
+		options.DynamicClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Collector is the data collector added to the standard set by collectors.WithExtraCollectors.
+//
+// It is implemented by collectors.Collector, the bundle package can't refer to it directly.
+type Collector interface {
+	Run(ctx context.Context, options *Options) error
+	Source() string
+	Name() string
+	Path() string
+	String() string
+}
+
+// Redactor transforms the collected data before it is written to the archive.
+//
+// The redactors are run by the collectors on every text file in the order they are set, the implementations
+// are provided by the redact package. The YAML files are redacted as a whole, other streamed files are redacted
+// in chunks of whole lines as they're collected, a PEM block is never split between the chunks.
+type Redactor interface {
+	// Name returns the redactor name.
+	Name() string
+	// Redact returns the data with the sensitive information removed and the number of replacements made.
+	Redact(path string, data []byte) ([]byte, int, error)
+}
+
+// PathRedactor is implemented by the redactors which also change the file paths in the archive.
+type PathRedactor interface {
+	RedactPath(path string) string
+}
+
+// Collector retry defaults.
+const (
+	DefaultCollectorAttempts = 3
+	DefaultCollectorBackoff  = time.Second
+)
+
+// DefaultResourceTimeout limits the time the Talos resources of a single type are listed, see WithResourceTimeout.
+const DefaultResourceTimeout = time.Minute
+
+// Decision is the error handler decision on the collector failure.
+type Decision int
+
+// Error handler decisions.
+const (
+	// DecisionSkip records the error in the progress and continues with the other collectors.
+	DecisionSkip Decision = iota
+	// DecisionRetry runs the failed collector again after the backoff, see WithCollectorRetries.
+	//
+	// The last error is recorded once the collector fails the configured number of attempts.
+	DecisionRetry
+	// DecisionAbort stops the bundle collection with the error.
+	DecisionAbort
+)
+
 // Progress reports current bundle collection progress.
 type Progress struct {
 	Error  error
 	Source string
-	State  string
-	Total  int
+	// Name is the stable name of the collector, e.g. `talos.dmesg`.
+	Name     string
+	State    string
+	Warnings []string
+	Total    int
+	// QueueDepth and QueueSize describe the files waiting to be written to the archive, see Options.WriteQueue.
+	QueueDepth int
+	QueueSize  int64
 }
 
 // Archive defines archive writer interface.
@@ -52,18 +779,51 @@ type Archive interface {
 	Close() error
 }
 
+// StreamArchive is implemented by the archives which can stream the file contents from the reader.
+type StreamArchive interface {
+	Archive
+	WriteFrom(path string, r io.Reader) error
+}
+
+// CompressedSuffix is the suffix of the files compressed by the collectors, see WithCompressLargeFiles.
+//
+// The bundlereader package decompresses such files transparently, so they are read by their original names.
+const CompressedSuffix = ".gz"
+
+// CompressionMethod returns the zip compression method of the file: the compressed files are stored as is.
+func CompressionMethod(path string) uint16 {
+	if strings.HasSuffix(path, CompressedSuffix) {
+		return zip.Store
+	}
+
+	return zip.Deflate
+}
+
 // archive wraps archive writer in a thread safe implementation.
 type archive struct {
-	Archive   *zip.Writer
+	Archive *zip.Writer
+	now     func() time.Time
+	// options enable the parallel compression, see WithParallelCompression
+	options   *Options
+	spools    []*spool
 	archiveMu sync.Mutex
+	spoolsMu  sync.Mutex
 }
 
 // Write creates a file in the archive.
 func (a *archive) Write(path string, contents []byte) error {
+	if a.compressesInParallel() {
+		return a.writeSpooled(path, bytes.NewReader(contents))
+	}
+
 	a.archiveMu.Lock()
 	defer a.archiveMu.Unlock()
 
-	file, err := a.Archive.Create(path)
+	file, err := a.Archive.CreateHeader(&zip.FileHeader{
+		Name:     path,
+		Method:   CompressionMethod(path),
+		Modified: a.now(),
+	})
 	if err != nil {
 		return err
 	}
@@ -76,8 +836,31 @@ func (a *archive) Write(path string, contents []byte) error {
 	return nil
 }
 
+// WriteFrom creates a file in the archive with the contents read from the reader.
+func (a *archive) WriteFrom(path string, r io.Reader) error {
+	if a.compressesInParallel() {
+		return a.writeSpooled(path, r)
+	}
+
+	a.archiveMu.Lock()
+	defer a.archiveMu.Unlock()
+
+	file, err := a.Archive.CreateHeader(&zip.FileHeader{
+		Name:     path,
+		Method:   CompressionMethod(path),
+		Modified: a.now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
+
+	return err
+}
+
 func (a *archive) Close() error {
-	return a.Archive.Close()
+	return errors.Join(a.Archive.Close(), a.removeSpools())
 }
 
 // Log writes the line to logger or to stdout if no logger was provided.
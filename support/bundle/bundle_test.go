@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+type testArchive struct {
+	files   map[string][]byte
+	filesMu sync.Mutex
+}
+
+func (a *testArchive) Write(path string, data []byte) error {
+	a.filesMu.Lock()
+	defer a.filesMu.Unlock()
+
+	if a.files == nil {
+		a.files = map[string][]byte{}
+	}
+
+	a.files[path] = data
+
+	return nil
+}
+
+func (a *testArchive) Close() error {
+	return nil
+}
+
+func TestKubernetesRateLimit(t *testing.T) {
+	require := require.New(t)
+
+	options := bundle.NewOptions(
+		bundle.WithRESTConfig(&rest.Config{
+			Host:        "https://127.0.0.1:6443",
+			RateLimiter: flowcontrol.NewTokenBucketRateLimiter(1000, 1000),
+		}),
+		bundle.WithKubernetesRateLimit(2.5, 7),
+	)
+
+	require.NoError(options.InitKubernetesClient(context.Background()))
+
+	config := options.KubernetesRESTConfig()
+	require.EqualValues(2.5, config.QPS)
+	require.Equal(7, config.Burst)
+
+	require.EqualValues(2.5, options.KubernetesClient.CoreV1().RESTClient().GetRateLimiter().QPS())
+
+	// the passed config is not modified
+	require.NotNil(options.RESTConfig.RateLimiter)
+	require.Zero(options.RESTConfig.QPS)
+}
+
+func TestInCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	require := require.New(t)
+
+	options := bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithInCluster())
+
+	require.NoError(options.Validate())
+	require.True(options.SelectsNodes())
+	require.False(bundle.NewOptions(bundle.WithInCluster(), bundle.WithNodes("172.20.0.2")).SelectsNodes())
+
+	require.ErrorIs(options.InitKubernetesClient(context.Background()), rest.ErrNotInCluster)
+}
+
+func TestNodeDiscovery(t *testing.T) {
+	require := require.New(t)
+
+	require.True(bundle.NewOptions().DiscoversNodes())
+	require.False(bundle.NewOptions(bundle.WithoutNodeDiscovery()).DiscoversNodes())
+	require.False(bundle.NewOptions(bundle.WithNodes("172.20.0.2")).DiscoversNodes())
+	require.False(bundle.NewOptions(bundle.WithNodeLabels("node-role.kubernetes.io/control-plane")).DiscoversNodes())
+	require.False(bundle.NewOptions(bundle.WithoutTalos()).DiscoversNodes())
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"context"
+	"sync"
+)
+
+// Cached returns the result of the fetch call for the key, the fetch is called once per bundle for each key
+// and the concurrent calls wait for the result of the first one.
+//
+// It's used by the collectors which need the same data from the API, so it's not queried multiple times.
+// The key must be comparable, the failed fetch calls are not cached. The results are shared, so they must not be modified.
+func (options *Options) Cached(ctx context.Context, key any, fetch func() (any, error)) (any, error) {
+	if options.cache == nil {
+		return fetch()
+	}
+
+	return options.cache.get(ctx, key, fetch)
+}
+
+// resultCache keeps the results of the API calls shared by the collectors.
+type resultCache struct {
+	results map[any]*cachedResult
+	mu      sync.Mutex
+}
+
+// cachedResult is closed when the result is fetched.
+type cachedResult struct {
+	value any
+	err   error
+	done  chan struct{}
+}
+
+func (c *resultCache) get(ctx context.Context, key any, fetch func() (any, error)) (any, error) {
+	c.mu.Lock()
+
+	if c.results == nil {
+		c.results = map[any]*cachedResult{}
+	}
+
+	result, ok := c.results[key]
+	if !ok {
+		result = &cachedResult{done: make(chan struct{})}
+		c.results[key] = result
+	}
+
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-result.done:
+			return result.value, result.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	result.value, result.err = fetch()
+
+	if result.err != nil {
+		c.mu.Lock()
+		delete(c.results, key)
+		c.mu.Unlock()
+	}
+
+	close(result.done)
+
+	return result.value, result.err
+}
+
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results = nil
+}
@@ -0,0 +1,169 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// completionWebhookTimeout limits the time spent on the completion webhook.
+const completionWebhookTimeout = 10 * time.Second
+
+// Completion is the summary of the finished bundle collection.
+type Completion struct {
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Text is the human readable summary, it makes the payload compatible with the chat incoming webhooks.
+	Text string `json:"text"`
+	// Error is set if the bundle creation failed.
+	Error string `json:"error,omitempty"`
+	// UploadReference is the reference ID returned by the support portal if the bundle was uploaded.
+	UploadReference string `json:"uploadReference,omitempty"`
+	// Errors are the collector failures which didn't stop the bundle creation.
+	Errors   []string      `json:"errors,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Size is the total size of the files written to the bundle before the compression.
+	Size  int64 `json:"size"`
+	Files int   `json:"files"`
+}
+
+// NotifyCompletion sends the completion summary to the webhook set by WithCompletionWebhook
+// and calls the callback set by WithCompletionFunc.
+//
+// The webhook failures are logged, they don't fail the bundle creation.
+func (options *Options) NotifyCompletion(ctx context.Context, started time.Time, err error) {
+	if options.CompletionWebhook == "" && options.CompletionFunc == nil {
+		return
+	}
+
+	completion := options.completion(started, err)
+
+	if options.CompletionFunc != nil {
+		options.CompletionFunc(completion)
+	}
+
+	if options.CompletionWebhook == "" {
+		return
+	}
+
+	if webhookErr := postCompletion(ctx, options.CompletionWebhook, completion); webhookErr != nil {
+		options.Log("failed to send completion webhook: %s", webhookErr)
+	}
+}
+
+func (options *Options) completion(started time.Time, err error) Completion {
+	finished := options.Now()
+
+	completion := Completion{
+		Started:  started.UTC(),
+		Finished: finished.UTC(),
+		Duration: finished.Sub(started),
+		Metadata: options.Metadata,
+	}
+
+	if err != nil {
+		completion.Error = err.Error()
+	}
+
+	if result := options.UploadResult(); result != nil {
+		completion.UploadReference = result.Reference
+	}
+
+	if m := options.manifest; m != nil {
+		m.mu.Lock()
+
+		for _, file := range m.files {
+			completion.Size += file.Size
+		}
+
+		completion.Files = len(m.files)
+
+		for _, collector := range m.collectors {
+			if collector.Error != "" {
+				completion.Errors = append(completion.Errors, fmt.Sprintf("%s %s: %s", collector.Source, collector.Path, collector.Error))
+			}
+		}
+
+		m.mu.Unlock()
+	}
+
+	completion.Text = completion.summary()
+
+	return completion
+}
+
+// summary formats the one line summary of the completion.
+func (completion *Completion) summary() string {
+	var sb strings.Builder
+
+	if completion.Error != "" {
+		fmt.Fprintf(&sb, "Support bundle failed after %s: %s", completion.Duration.Round(time.Second), completion.Error)
+	} else {
+		fmt.Fprintf(&sb, "Support bundle created in %s: %d files, %d bytes", completion.Duration.Round(time.Second), completion.Files, completion.Size)
+	}
+
+	if len(completion.Errors) > 0 {
+		fmt.Fprintf(&sb, ", %d collectors failed", len(completion.Errors))
+	}
+
+	if completion.UploadReference != "" {
+		fmt.Fprintf(&sb, ", uploaded as %s", completion.UploadReference)
+	}
+
+	return sb.String()
+}
+
+func postCompletion(ctx context.Context, webhook string, completion Completion) error {
+	data, err := json.Marshal(completion)
+	if err != nil {
+		return err
+	}
+
+	// the bundle context might be already canceled, the notification about it is still useful
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), completionWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// validateWebhook checks the completion webhook URL.
+func validateWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return fmt.Errorf("invalid completion webhook URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("completion webhook URL %q is not HTTP", webhook)
+	}
+
+	return nil
+}
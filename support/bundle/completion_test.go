@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+func TestCompletionWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	var received bundle.Completion
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	var called bundle.Completion
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(&testArchive{}),
+		bundle.WithLogOutput(io.Discard),
+		bundle.WithMetadata(map[string]string{"ticket": "1234"}),
+		bundle.WithCompletionWebhook(srv.URL),
+		bundle.WithCompletionFunc(func(c bundle.Completion) { called = c }),
+	)
+
+	options.IndexFiles()
+
+	require.NoError(options.Archive.Write("hello.txt", []byte("hello")))
+	require.NoError(options.Archive.Write("world.txt", []byte("world")))
+
+	options.RecordCollector("cluster", "hello.txt", time.Second, nil)
+	options.RecordCollector("cluster", "broken.txt", time.Second, errors.New("boom"))
+
+	options.NotifyCompletion(ctx, options.Now().Add(-time.Minute), nil)
+
+	require.Equal("1234", received.Metadata["ticket"])
+	require.Empty(received.Error)
+	require.Equal([]string{"cluster broken.txt: boom"}, received.Errors)
+	require.Equal(2, received.Files)
+	require.EqualValues(10, received.Size)
+	require.Contains(received.Text, "Support bundle created in 1m0s: 2 files, 10 bytes")
+	require.Contains(received.Text, "1 collectors failed")
+	require.Equal(called.Text, received.Text)
+}
@@ -0,0 +1,232 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManifestFile is the name of the machine-readable bundle manifest in the archive.
+const ManifestFile = "manifest.json"
+
+// ManifestSchemaVersion is the version of the manifest format.
+//
+// The version is increased on the incompatible manifest changes, the readers should refuse the manifests
+// with the unknown version instead of misinterpreting them.
+const ManifestSchemaVersion = 1
+
+// LayoutVersion is the version of the bundle file layout: the file names and the directory structure.
+//
+// The version is increased when the collector paths are renamed or moved, the bundlereader package maps the paths
+// of the older layouts to the current ones.
+const LayoutVersion = 1
+
+// Manifest describes the bundle contents.
+//
+// The paths are relative to the bundle root, i.e. without the prefix set by WithPathPrefix.
+type Manifest struct {
+	Created       time.Time           `json:"created"`
+	Collectors    []ManifestCollector `json:"collectors"`
+	Files         []ManifestEntry     `json:"files"`
+	Errors        []string            `json:"errors,omitempty"`
+	SchemaVersion int                 `json:"schemaVersion"`
+	LayoutVersion int                 `json:"layoutVersion"`
+}
+
+// ManifestCollector is the collector run during the bundle creation.
+type ManifestCollector struct {
+	Source   string        `json:"source"`
+	Path     string        `json:"path"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ManifestEntry is the file in the bundle.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// manifest accumulates the manifest contents during the bundle creation.
+type manifest struct {
+	collectors []ManifestCollector
+	files      []ManifestEntry
+	mu         sync.Mutex
+}
+
+func (m *manifest) addFile(entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files = append(m.files, entry)
+}
+
+// RecordCollector adds the collector result to the manifest.
+func (options *Options) RecordCollector(source, name string, duration time.Duration, err error) {
+	if options.manifest == nil {
+		return
+	}
+
+	collector := ManifestCollector{
+		Source:   source,
+		Path:     options.bundlePath(options.ArchivePath(name)),
+		Duration: duration,
+	}
+
+	if err != nil {
+		collector.Error = err.Error()
+	}
+
+	options.manifest.mu.Lock()
+	defer options.manifest.mu.Unlock()
+
+	options.manifest.collectors = append(options.manifest.collectors, collector)
+}
+
+// IndexFiles wraps the archive to record the files written to it in the manifest.
+//
+// It should be called before anything is written to the archive.
+func (options *Options) IndexFiles() {
+	if options.manifest == nil || options.Archive == nil {
+		return
+	}
+
+	if _, ok := options.Archive.(*indexArchive); ok {
+		return
+	}
+
+	options.Archive = &indexArchive{
+		Archive:    options.Archive,
+		manifest:   options.manifest,
+		bundlePath: options.bundlePath,
+	}
+}
+
+// bundlePath strips the bundle path prefix from the archive path.
+func (options *Options) bundlePath(archivePath string) string {
+	if options.PathPrefix == "" {
+		return archivePath
+	}
+
+	return strings.TrimPrefix(archivePath, path.Clean(options.PathPrefix)+"/")
+}
+
+// WriteManifest writes the manifest with the collectors run and the files written so far to the archive.
+func (options *Options) WriteManifest() error {
+	m := options.manifest
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+
+	res := Manifest{
+		SchemaVersion: ManifestSchemaVersion,
+		LayoutVersion: LayoutVersion,
+		Created:       options.Now().UTC(),
+		Collectors:    slices.Clone(m.collectors),
+		Files:         slices.Clone(m.files),
+	}
+
+	m.mu.Unlock()
+
+	slices.SortFunc(res.Collectors, func(a, b ManifestCollector) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	slices.SortFunc(res.Files, func(a, b ManifestEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	for _, collector := range res.Collectors {
+		if collector.Error != "" {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s %s: %s", collector.Source, collector.Path, collector.Error))
+		}
+	}
+
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return options.Archive.Write(options.ArchivePath(ManifestFile), append(data, '\n'))
+}
+
+// indexArchive records the size and the hash of the files written to the archive.
+type indexArchive struct {
+	Archive
+
+	manifest   *manifest
+	bundlePath func(string) string
+}
+
+// Write implements Archive.
+func (a *indexArchive) Write(name string, contents []byte) error {
+	if err := a.Archive.Write(name, contents); err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(contents)
+
+	a.manifest.addFile(ManifestEntry{
+		Path:   a.bundlePath(name),
+		Size:   int64(len(contents)),
+		SHA256: hex.EncodeToString(hash[:]),
+	})
+
+	return nil
+}
+
+// WriteFrom implements StreamArchive.
+func (a *indexArchive) WriteFrom(name string, r io.Reader) error {
+	counter := &hashCounter{hash: sha256.New()}
+	r = io.TeeReader(r, counter)
+
+	var err error
+
+	if archive, ok := a.Archive.(StreamArchive); ok {
+		err = archive.WriteFrom(name, r)
+	} else {
+		var data []byte
+
+		if data, err = io.ReadAll(r); err == nil {
+			err = a.Archive.Write(name, data)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	a.manifest.addFile(ManifestEntry{
+		Path:   a.bundlePath(name),
+		Size:   counter.size,
+		SHA256: hex.EncodeToString(counter.hash.Sum(nil)),
+	})
+
+	return nil
+}
+
+type hashCounter struct {
+	hash hash.Hash
+	size int64
+}
+
+func (c *hashCounter) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+
+	return c.hash.Write(p)
+}
@@ -6,10 +6,18 @@ package bundle
 
 import (
 	"archive/zip"
+	"context"
 	"io"
+	"net/http"
+	"time"
 
 	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // Option defines a single bundle option.
@@ -22,13 +30,53 @@ func WithTalosClient(client *client.Client) Option {
 	}
 }
 
+// WithTalosconfig runs bundle creator with the Talos client created from the talosconfig file.
+//
+// Empty path loads the default talosconfig, empty context name selects the current context.
+func WithTalosconfig(path, contextName string) Option {
+	return WithTalosConfigProvider(func(context.Context) (*clientconfig.Config, error) {
+		return clientconfig.Open(path)
+	}, contextName)
+}
+
+// WithTalosConfigProvider runs bundle creator with the Talos client created from the config returned by the provider.
+//
+// Empty context name selects the current context.
+func WithTalosConfigProvider(provider TalosConfigProvider, contextName string) Option {
+	return func(o *Options) {
+		o.TalosConfigProvider = provider
+		o.TalosContext = contextName
+	}
+}
+
 // WithKubernetesClient runs bundle creator with the Kubernetes client.
-func WithKubernetesClient(clientset *kubernetes.Clientset) Option {
+func WithKubernetesClient(clientset kubernetes.Interface) Option {
 	return func(o *Options) {
 		o.KubernetesClient = clientset
 	}
 }
 
+// WithKubeconfig runs bundle creator with the Kubernetes clients created from the kubeconfig file.
+func WithKubeconfig(path string) Option {
+	return func(o *Options) {
+		o.Kubeconfig = path
+	}
+}
+
+// WithRESTConfig runs bundle creator with the Kubernetes clients created from the REST config.
+func WithRESTConfig(config *rest.Config) Option {
+	return func(o *Options) {
+		o.RESTConfig = config
+	}
+}
+
+// WithDynamicClient runs bundle creator with the Kubernetes dynamic client.
+func WithDynamicClient(client dynamic.Interface) Option {
+	return func(o *Options) {
+		o.DynamicClient = client
+	}
+}
+
 // WithLogOutput runs bundle creator with logs output.
 func WithLogOutput(writer io.Writer) Option {
 	return func(o *Options) {
@@ -40,7 +88,9 @@ func WithLogOutput(writer io.Writer) Option {
 func WithArchiveOutput(writer io.Writer) Option {
 	return func(o *Options) {
 		o.Archive = &archive{
-			Archive: zip.NewWriter(writer),
+			Archive: zip.NewWriter(o.countOutput(writer)),
+			now:     o.Now,
+			options: o,
 		}
 	}
 }
@@ -52,6 +102,215 @@ func WithArchive(archive Archive) Option {
 	}
 }
 
+// WithPathPrefix puts all files in the archive under the prefix directory, e.g. `cluster-name/2024-06-01T12-00`.
+func WithPathPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.PathPrefix = prefix
+	}
+}
+
+// WithMetadata adds the custom metadata (ticket ID, customer name, etc) to the bundle metadata file.
+func WithMetadata(metadata map[string]string) Option {
+	return func(o *Options) {
+		if o.Metadata == nil {
+			o.Metadata = map[string]string{}
+		}
+
+		for k, v := range metadata {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// WithTempDir sets the directory where the large collector outputs are spilled to instead of keeping them in memory.
+func WithTempDir(dir string) Option {
+	return func(o *Options) {
+		o.TempDir = dir
+	}
+}
+
+// WithRedactors appends the redactors which remove the sensitive information from the collected data.
+func WithRedactors(redactors ...Redactor) Option {
+	return func(o *Options) {
+		o.Redactors = append(o.Redactors, redactors...)
+	}
+}
+
+// WithSecretRedaction removes the common secret formats from the collected data, see redact.Secrets.
+func WithSecretRedaction() Option {
+	return func(o *Options) {
+		o.RedactSecrets = true
+	}
+}
+
+// WithPIIRedaction removes the personal information from the collected data, see redact.PII.
+func WithPIIRedaction() Option {
+	return func(o *Options) {
+		o.RedactPII = true
+	}
+}
+
+// WithRedactionRulesFile adds the redactors defined in the YAML file, see redact.Rules for the format.
+//
+// The file is loaded when the bundle collection starts.
+func WithRedactionRulesFile(path string) Option {
+	return func(o *Options) {
+		o.RedactionRulesFile = path
+	}
+}
+
+// WithMachineConfig collects the machine config of the nodes.
+//
+// The secrets are removed from the config regardless of the redaction options, see redact.MachineConfig,
+// but the config might still contain the sensitive data, so it's not collected by default.
+func WithMachineConfig() Option {
+	return func(o *Options) {
+		o.MachineConfig = true
+	}
+}
+
+// WithSecretScan scans the collected data for the suspected secrets after the redaction.
+//
+// The data is not changed, the findings are written to the secret scan report and sent as the progress warnings.
+func WithSecretScan() Option {
+	return func(o *Options) {
+		o.SecretScan = true
+	}
+}
+
+// WithAuditLog writes the audit log of the API calls made by the collectors to the archive.
+//
+// Each collector gets an entry with the node, the API endpoint, the collector name, the time and the outcome,
+// see AuditEntry.
+func WithAuditLog() Option {
+	return func(o *Options) {
+		o.AuditLog = true
+	}
+}
+
+// WithRequester sets the identity of the bundle requester recorded in the audit log.
+func WithRequester(identity string) Option {
+	return func(o *Options) {
+		o.Requester = identity
+	}
+}
+
+// WithAllowList enables the allow-list mode: only the files matching the patterns are collected, all other collectors
+// are refused before the collection starts.
+//
+// The patterns are matched against the file paths in the bundle using path.Match, the pattern ending with `/**`
+// matches all files in the directory. The collected and refused files are listed in the attestation file.
+func WithAllowList(patterns ...string) Option {
+	return func(o *Options) {
+		o.AllowListMode = true
+		o.AllowList = append(o.AllowList, patterns...)
+	}
+}
+
+// WithUpload uploads the bundle to the support portal endpoint after it's created.
+//
+// The bundle is posted to the HTTPS URL with the bearer token, the failed uploads are retried.
+// The reference ID returned by the portal is written to the local archive (if any) as UploadFile
+// and is available from Options.UploadResult.
+func WithUpload(url, token string) Option {
+	return func(o *Options) {
+		o.uploadConfig().URL = url
+		o.uploadConfig().Token = token
+	}
+}
+
+// WithUploadClient sets the HTTP client used for the upload.
+func WithUploadClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.uploadConfig().Client = client
+	}
+}
+
+// WithUploadRetries sets the number of the upload attempts and the delay before the first retry.
+func WithUploadRetries(attempts int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.uploadConfig().Attempts = attempts
+		o.uploadConfig().Backoff = backoff
+	}
+}
+
+// WithUploadProgress sets the callback reporting the number of the bundle bytes uploaded.
+func WithUploadProgress(progress func(sent, total int64)) Option {
+	return func(o *Options) {
+		o.uploadConfig().Progress = progress
+	}
+}
+
+// WithCompressLargeFiles gzips the text files larger than the threshold in the workers before writing them
+// to the archive, the files are stored as is with the CompressedSuffix added to their names.
+//
+// It reduces the time the archive writer spends on the large logs and the memory they take.
+// The streamed files are checked by reading up to the threshold bytes ahead, so it shouldn't be too large.
+func WithCompressLargeFiles(threshold int64) Option {
+	return func(o *Options) {
+		o.CompressThreshold = threshold
+	}
+}
+
+// WithParallelCompression compresses the files in the workers writing them instead of the archive writer.
+//
+// The zip archive is written by one writer at a time, so by default the files are compressed one by one.
+// With the parallel compression each file is compressed to the spool (a temporary file in the directory
+// set by WithTempDir, or memory) first, and the compressed data is copied to the archive as is.
+func WithParallelCompression() Option {
+	return func(o *Options) {
+		o.ParallelCompression = true
+	}
+}
+
+// WithWriteQueueSize sets the size of the data waiting to be written to the archive above which
+// the workers don't start the next collectors, DefaultWriteQueueSize is used if zero.
+//
+// The queue depth and size are reported in Progress.
+func WithWriteQueueSize(size int64) Option {
+	return func(o *Options) {
+		o.WriteQueueSize = size
+	}
+}
+
+// WithSFTPOutput writes the archive to the path on the remote host over SFTP.
+//
+// The connection is established when the bundle creation starts, the config sets the SSH user,
+// the authentication methods and the host key callback. It can't be combined with the archive output.
+func WithSFTPOutput(addr string, config *ssh.ClientConfig, path string) Option {
+	return func(o *Options) {
+		o.SFTPOutput = &SFTPOutput{
+			Config: config,
+			Addr:   addr,
+			Path:   path,
+		}
+	}
+}
+
+// WithCompletionWebhook posts the JSON summary of the bundle collection (Completion) to the URL when it finishes.
+//
+// The summary is sent both on success and on failure, it has the `text` field, so the chat incoming webhooks
+// can be used directly.
+func WithCompletionWebhook(url string) Option {
+	return func(o *Options) {
+		o.CompletionWebhook = url
+	}
+}
+
+// WithCompletionFunc calls the callback with the summary of the bundle collection when it finishes.
+func WithCompletionFunc(completion func(Completion)) Option {
+	return func(o *Options) {
+		o.CompletionFunc = completion
+	}
+}
+
+// WithProfile selects the set of collectors to run, ProfileStandard is used by default.
+func WithProfile(profile Profile) Option {
+	return func(o *Options) {
+		o.Profile = profile
+	}
+}
+
 // WithNumWorkers runs bundle creator with number of workers.
 func WithNumWorkers(count int) Option {
 	return func(o *Options) {
@@ -59,6 +318,37 @@ func WithNumWorkers(count int) Option {
 	}
 }
 
+// WithKubernetesWorkers runs the cluster collectors in a separate pool of workers.
+//
+// It allows throttling the Kubernetes API server requests independently from the Talos nodes collection,
+// by default the cluster collectors share the workers set by WithNumWorkers.
+func WithKubernetesWorkers(count int) Option {
+	return func(o *Options) {
+		o.KubernetesWorkers = count
+	}
+}
+
+// WithResourceWorkers runs the Talos resource collectors in a separate pool of workers.
+//
+// The clusters with lots of resources may stall apid when all workers list them at once,
+// by default the resource collectors share the workers set by WithNumWorkers.
+func WithResourceWorkers(count int) Option {
+	return func(o *Options) {
+		o.ResourceWorkers = count
+	}
+}
+
+// WithAdaptiveWorkers adapts the number of the collectors run at once against each node to its responsiveness.
+//
+// The number is halved when the node collectors time out (see WithCollectorTimeout) or their average latency
+// goes over the latency, and it grows back by one with each collector done in time, up to the number of the workers.
+// The collectors of the other nodes are run while the slow node is held back.
+func WithAdaptiveWorkers(latency time.Duration) Option {
+	return func(o *Options) {
+		o.AdaptiveLatency = latency
+	}
+}
+
 // WithProgressChan runs bundle creator with the progress reporter to the channel.
 func WithProgressChan(progress chan Progress) Option {
 	return func(o *Options) {
@@ -66,9 +356,280 @@ func WithProgressChan(progress chan Progress) Option {
 	}
 }
 
+// WithProgressFunc runs bundle creator with the progress reporter to the callback.
+//
+// The callback is never called concurrently, but it blocks the collection, so it should return quickly.
+func WithProgressFunc(progress func(Progress)) Option {
+	return func(o *Options) {
+		o.ProgressFunc = progress
+	}
+}
+
+// WithProgressWriter writes the progress events (ProgressEvent) to the writer as NDJSON, one line per completed collector.
+//
+// It can be used together with the other progress reporters.
+func WithProgressWriter(w io.Writer) Option {
+	return func(o *Options) {
+		o.ProgressWriter = w
+	}
+}
+
+// WithErrorHandler runs bundle creator with the handler deciding whether to retry, skip or abort on the collector failure.
+//
+// The failures are skipped by default. The handler is never called concurrently.
+func WithErrorHandler(handler func(source, path string, err error) Decision) Option {
+	return func(o *Options) {
+		o.ErrorHandler = handler
+	}
+}
+
+// WithCollectorRetries sets the number of the collector attempts and the delay before the first retry
+// when the error handler decides to retry the failed collector.
+//
+// The delay is doubled after each attempt.
+func WithCollectorRetries(attempts int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.CollectorAttempts = attempts
+		o.CollectorBackoff = backoff
+	}
+}
+
+// WithClock runs bundle creator with the clock used for the timestamps in the archive and the reports.
+func WithClock(clock Clock) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// WithoutKubernetes skips the data collection using Kubernetes API even if the Kubernetes client is set.
+//
+// The Kubernetes client is still used to select the nodes set by WithNodeSelector or WithNodeLabels.
+func WithoutKubernetes() Option {
+	return func(o *Options) {
+		o.SkipKubernetes = true
+	}
+}
+
+// WithoutTalos skips the data collection from the nodes using Talos API even if the Talos client is set.
+func WithoutTalos() Option {
+	return func(o *Options) {
+		o.SkipTalos = true
+	}
+}
+
+// WithInCluster runs bundle creator inside the cluster (e.g. as a Job).
+//
+// The Kubernetes clients are created from the pod ServiceAccount credentials and the Talos client from the talosconfig
+// mounted by the Talos ServiceAccount (InClusterTalosconfig), unless the clients or their configs are set explicitly.
+// The data is collected from all Kubernetes nodes if no nodes or node selectors are set.
+//
+// The Talos ServiceAccount should have the os:reader role (os:admin for the etcd snapshots and the packet capture),
+// and the Kubernetes ServiceAccount should be allowed to read the collected resources and the pod logs.
+func WithInCluster() Option {
+	return func(o *Options) {
+		o.InCluster = true
+	}
+}
+
 // WithNodes passes the list of nodes to get the data from.
 func WithNodes(nodes ...string) Option {
 	return func(o *Options) {
 		o.Nodes = nodes
 	}
 }
+
+// WithoutNodeDiscovery disables the discovery of the nodes when no nodes are set.
+//
+// By default, if neither WithNodes nor the talosconfig context set the nodes, the data is collected from all
+// Kubernetes nodes or, without the Kubernetes client, from all Talos cluster members.
+func WithoutNodeDiscovery() Option {
+	return func(o *Options) {
+		o.SkipNodeDiscovery = true
+	}
+}
+
+// WithNodeEndpoints makes bundle creator connect to the nodes directly using the endpoints from the map
+// instead of routing the requests through the Talos client endpoints.
+//
+// The map is keyed by the node name as passed to WithNodes.
+func WithNodeEndpoints(endpoints map[string]string) Option {
+	return func(o *Options) {
+		o.NodeEndpoints = endpoints
+	}
+}
+
+// WithNodeSelector collects the data from the Kubernetes nodes accepted by the selector instead of the nodes set by WithNodes.
+//
+// The nodes are reached using their internal IP addresses.
+func WithNodeSelector(selector func(node NodeInfo) bool) Option {
+	return func(o *Options) {
+		o.NodeSelector = selector
+	}
+}
+
+// WithNodeLabels collects the data from the Kubernetes nodes matching the label selector instead of the nodes set by WithNodes.
+//
+// The nodes are reached using their internal IP addresses.
+func WithNodeLabels(labelSelector string) Option {
+	return func(o *Options) {
+		o.NodeLabels = labelSelector
+	}
+}
+
+// WithControlPlaneOnly collects the data only from the control plane nodes.
+//
+// The nodes the machine type can't be fetched of are still collected.
+func WithControlPlaneOnly() Option {
+	return func(o *Options) {
+		o.NodeRole = NodeRoleControlPlane
+	}
+}
+
+// WithWorkersOnly collects the data only from the worker nodes.
+//
+// The nodes the machine type can't be fetched of are still collected.
+func WithWorkersOnly() Option {
+	return func(o *Options) {
+		o.NodeRole = NodeRoleWorker
+	}
+}
+
+// WithCollectorConfig configures the collectors which are expensive or produce large files.
+//
+// It replaces the settings made by WithNamespaces and WithLogTailLines.
+func WithCollectorConfig(config CollectorConfig) Option {
+	return func(o *Options) {
+		o.CollectorConfig = config
+	}
+}
+
+// WithMaxFileSize limits the size of a single file in the archive.
+//
+// The middle part of the larger files is cut out, and a `.truncated` note is written next to the file.
+func WithMaxFileSize(size int64) Option {
+	return func(o *Options) {
+		o.MaxFileSize = size
+	}
+}
+
+// WithMaxStreamSize stops reading the streamed files, like the logs and dmesg, after the limit.
+//
+// The stream is canceled once the limit is reached, so the node doesn't keep sending the data,
+// and a `.truncated` note is written next to the file.
+func WithMaxStreamSize(size int64) Option {
+	return func(o *Options) {
+		o.MaxStreamSize = size
+	}
+}
+
+// WithCollectorTimeout limits the time a single collector runs.
+//
+// The API calls and the streams of the collector are canceled when the timeout is reached,
+// the timeout is reported as the collector error.
+func WithCollectorTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.CollectorTimeout = timeout
+	}
+}
+
+// WithResourceTimeout limits the time the Talos resources of a single type are listed, DefaultResourceTimeout is used if zero.
+//
+// The resources are streamed until the API reports the whole list is sent, the timeout keeps the collector
+// from waiting forever if that never happens.
+func WithResourceTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.ResourceTimeout = timeout
+	}
+}
+
+// WithNamespaces passes the list of additional Kubernetes namespaces to get the data from.
+func WithNamespaces(namespaces ...string) Option {
+	return func(o *Options) {
+		o.Namespaces = namespaces
+	}
+}
+
+// WithCustomResources passes the list of custom resources to dump using the dynamic client.
+func WithCustomResources(gvrs ...schema.GroupVersionResource) Option {
+	return func(o *Options) {
+		o.CustomResources = gvrs
+	}
+}
+
+// WithLogTailLines limits the number of lines collected from the Talos service and container logs.
+//
+// The limit also applies to the pod logs read using Kubernetes API unless set by WithPodLogTailLines.
+func WithLogTailLines(lines int32) Option {
+	return func(o *Options) {
+		o.LogTailLines = lines
+	}
+}
+
+// WithLogTailLinesFor overrides the number of log lines collected for the Talos service or container name.
+//
+// Zero lines collects the whole log.
+func WithLogTailLinesFor(name string, lines int32) Option {
+	return func(o *Options) {
+		if o.LogTailLinesFor == nil {
+			o.LogTailLinesFor = map[string]int32{}
+		}
+
+		o.LogTailLinesFor[name] = lines
+	}
+}
+
+// WithSince restricts the collected logs and events to the ones from the last duration.
+//
+// The start of the range is calculated using the clock set by WithClock.
+func WithSince(since time.Duration) Option {
+	return func(o *Options) {
+		o.Since = since
+		o.LogsSince = time.Time{}
+		o.LogsUntil = time.Time{}
+	}
+}
+
+// WithTimeRange restricts the collected logs and events to the time range, zero time leaves the range open.
+func WithTimeRange(from, to time.Time) Option {
+	return func(o *Options) {
+		o.Since = 0
+		o.LogsSince = from
+		o.LogsUntil = to
+	}
+}
+
+// WithPodLogTailLines limits the number of lines collected from the pod logs read using Kubernetes API.
+func WithPodLogTailLines(lines int64) Option {
+	return func(o *Options) {
+		o.PodLogTailLines = lines
+	}
+}
+
+// WithPodLogSelector collects the logs of all containers of the pods matching the label selector in the namespace.
+func WithPodLogSelector(namespace, labelSelector string) Option {
+	return func(o *Options) {
+		o.PodLogSelectors = append(o.PodLogSelectors, PodLogSelector{
+			Namespace:     namespace,
+			LabelSelector: labelSelector,
+		})
+	}
+}
+
+// WithKubernetesRateLimit sets the QPS and burst of the Kubernetes API requests rate limiter.
+//
+// The limits are applied to the Kubernetes clients created by the bundle creator, the client passed with
+// WithKubernetesClient keeps the rate limiter it was created with.
+func WithKubernetesRateLimit(qps float32, burst int) Option {
+	return func(o *Options) {
+		o.KubernetesQPS = qps
+		o.KubernetesBurst = burst
+	}
+}
+
+// WithKubernetesClientFromTalos creates the Kubernetes client using the admin kubeconfig fetched via Talos API
+// if no Kubernetes client was provided.
+func WithKubernetesClientFromTalos() Option {
+	return func(o *Options) {
+		o.KubernetesFromTalos = true
+	}
+}
@@ -0,0 +1,237 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+type testClock time.Time
+
+func (c testClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestKubeconfig(t *testing.T) {
+	require := require.New(t)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+
+	require.NoError(os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+    - name: talos
+      cluster:
+        server: https://10.5.0.2:6443
+contexts:
+    - name: admin@talos
+      context:
+        cluster: talos
+        user: admin@talos
+current-context: admin@talos
+users:
+    - name: admin@talos
+      user:
+        token: secret
+`), 0o600))
+
+	options := bundle.NewOptions(bundle.WithKubeconfig(kubeconfig), bundle.WithNodeLabels("node-role.kubernetes.io/control-plane"), bundle.WithArchive(&testArchive{}))
+
+	require.NoError(options.Validate())
+	require.NoError(options.InitKubernetesClient(context.Background()))
+	require.NotNil(options.KubernetesClient)
+	require.NotNil(options.DynamicClient)
+	require.Equal("https://10.5.0.2:6443", options.KubernetesRESTConfig().Host)
+
+	err := bundle.NewOptions(bundle.WithKubeconfig(filepath.Join(t.TempDir(), "missing"))).InitKubernetesClient(context.Background())
+	require.ErrorContains(err, "failed to load kubeconfig")
+}
+
+func TestTalosconfig(t *testing.T) {
+	require := require.New(t)
+
+	talosconfig := filepath.Join(t.TempDir(), "talosconfig")
+
+	require.NoError((&clientconfig.Config{
+		Context: "first",
+		Contexts: map[string]*clientconfig.Context{
+			"first":  {Endpoints: []string{"10.5.0.2"}},
+			"second": {Endpoints: []string{"10.6.0.2"}, Nodes: []string{"10.6.0.3", "10.6.0.4"}},
+		},
+	}).Save(talosconfig))
+
+	options := bundle.NewOptions(bundle.WithTalosconfig(talosconfig, ""))
+
+	require.NoError(options.InitTalosClient(context.Background()))
+	require.Equal([]string{"10.5.0.2"}, options.TalosClient.GetEndpoints())
+	require.Empty(options.Nodes)
+	require.NoError(options.Close())
+
+	// the context nodes are used when no nodes are set
+	options = bundle.NewOptions(bundle.WithTalosconfig(talosconfig, "second"))
+
+	require.NoError(options.InitTalosClient(context.Background()))
+	require.Equal([]string{"10.6.0.2"}, options.TalosClient.GetEndpoints())
+	require.Equal([]string{"10.6.0.3", "10.6.0.4"}, options.Nodes)
+	require.NoError(options.Close())
+
+	options = bundle.NewOptions(bundle.WithTalosconfig(talosconfig, "second"), bundle.WithNodes("10.6.0.5"))
+
+	require.NoError(options.InitTalosClient(context.Background()))
+	require.Equal([]string{"10.6.0.5"}, options.Nodes)
+	require.NoError(options.Close())
+
+	options = bundle.NewOptions(bundle.WithTalosConfigProvider(func(context.Context) (*clientconfig.Config, error) {
+		return nil, errors.New("vault is sealed")
+	}, ""))
+
+	require.ErrorContains(options.InitTalosClient(context.Background()), "failed to load talosconfig: vault is sealed")
+}
+
+func TestTimeRange(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	options := bundle.NewOptions(bundle.WithClock(testClock(now)), bundle.WithSince(time.Hour))
+
+	require.Equal(now, options.Now())
+
+	since, until := options.TimeRange()
+	require.Equal(now.Add(-time.Hour), since)
+	require.True(until.IsZero())
+
+	require.True(options.InTimeRange(now.Add(-30 * time.Minute)))
+	require.True(options.InTimeRange(now.Add(time.Minute)))
+	require.False(options.InTimeRange(now.Add(-2 * time.Hour)))
+
+	// the later option replaces the range
+	options = bundle.NewOptions(bundle.WithSince(time.Hour), bundle.WithTimeRange(now.Add(-3*time.Hour), now.Add(-2*time.Hour)))
+
+	require.True(options.InTimeRange(now.Add(-150 * time.Minute)))
+	require.True(options.InTimeRange(now.Add(-2 * time.Hour)))
+	require.False(options.InTimeRange(now.Add(-time.Hour)))
+	require.False(options.InTimeRange(now.Add(-4 * time.Hour)))
+
+	require.True(bundle.NewOptions().InTimeRange(time.Time{}))
+
+	err := bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithTimeRange(now, now.Add(-time.Hour))).Validate()
+	require.ErrorContains(err, "is before its start")
+
+	err = bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithSince(-time.Hour)).Validate()
+	require.ErrorContains(err, "since duration must not be negative")
+}
+
+func TestNodeRole(t *testing.T) {
+	require := require.New(t)
+
+	for _, test := range []struct {
+		option   bundle.Option
+		role     bundle.NodeRole
+		matching []machine.Type
+		other    []machine.Type
+	}{
+		{
+			option:   func(*bundle.Options) {},
+			role:     bundle.NodeRoleAny,
+			matching: []machine.Type{machine.TypeInit, machine.TypeControlPlane, machine.TypeWorker, machine.TypeUnknown},
+		},
+		{
+			option:   bundle.WithControlPlaneOnly(),
+			role:     bundle.NodeRoleControlPlane,
+			matching: []machine.Type{machine.TypeInit, machine.TypeControlPlane},
+			other:    []machine.Type{machine.TypeWorker, machine.TypeUnknown},
+		},
+		{
+			option:   bundle.WithWorkersOnly(),
+			role:     bundle.NodeRoleWorker,
+			matching: []machine.Type{machine.TypeWorker},
+			other:    []machine.Type{machine.TypeInit, machine.TypeControlPlane, machine.TypeUnknown},
+		},
+	} {
+		options := bundle.NewOptions(test.option)
+
+		require.Equal(test.role, options.NodeRole)
+
+		for _, machineType := range test.matching {
+			require.True(options.NodeRole.Matches(machineType), machineType)
+		}
+
+		for _, machineType := range test.other {
+			require.False(options.NodeRole.Matches(machineType), machineType)
+		}
+	}
+
+	options := bundle.NewOptions(bundle.WithArchive(&testArchive{}))
+	options.NodeRole = 5
+
+	require.ErrorContains(options.Validate(), "unknown node role 5")
+}
+
+func TestProfile(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(bundle.ProfileStandard, bundle.NewOptions().Profile)
+	require.Equal(bundle.ProfileFull, bundle.NewOptions(bundle.WithProfile(bundle.ProfileFull)).Profile)
+
+	require.ErrorContains(bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithProfile(bundle.Profile(5))).Validate(), "unknown profile 5")
+}
+
+func TestTailLines(t *testing.T) {
+	require := require.New(t)
+
+	options := bundle.NewOptions(
+		bundle.WithLogTailLines(100),
+		bundle.WithLogTailLinesFor("etcd", 0),
+		bundle.WithLogTailLinesFor("kubelet", 1000),
+	)
+
+	require.EqualValues(100, options.TailLines("apid"))
+	require.EqualValues(0, options.TailLines("etcd"))
+	require.EqualValues(1000, options.TailLines("kubelet"))
+
+	require.EqualValues(0, bundle.NewOptions().TailLines("apid"))
+
+	err := bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithPodLogTailLines(-1)).Validate()
+	require.ErrorContains(err, "log tail lines must not be negative")
+}
+
+func TestCollectorConfig(t *testing.T) {
+	require := require.New(t)
+
+	options := bundle.NewOptions(
+		bundle.WithNamespaces("ignored"),
+		bundle.WithCollectorConfig(bundle.CollectorConfig{
+			Namespaces:   []string{"default", "kube-system"},
+			LogTailLines: 50,
+			MaxFileSize:  1024,
+			PacketCapture: bundle.PacketCaptureConfig{
+				Interfaces: []string{"eth0"},
+				Duration:   time.Second,
+			},
+		}),
+		bundle.WithArchive(&testArchive{}),
+	)
+
+	require.NoError(options.Validate())
+	require.Equal([]string{"kube-system", "default"}, options.KubernetesNamespaces())
+	require.EqualValues(50, options.TailLines("apid"))
+	require.EqualValues(1024, options.MaxFileSize)
+
+	options.PacketCapture.Interfaces = nil
+
+	require.ErrorContains(options.Validate(), "packet capture requires at least one interface")
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ProgressEvent is the progress line written by WithProgressWriter.
+type ProgressEvent struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Name     string    `json:"name,omitempty"`
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"`
+	Warnings []string  `json:"warnings,omitempty"`
+	// Done is the number of the collectors of the source completed so far, including this one.
+	Done       int   `json:"done"`
+	Total      int   `json:"total"`
+	QueueDepth int   `json:"queueDepth"`
+	QueueSize  int64 `json:"queueSize"`
+}
+
+// progressWriter tracks the state of the progress events written as NDJSON.
+type progressWriter struct {
+	done   map[string]int
+	mu     sync.Mutex
+	failed bool
+}
+
+// WriteProgress writes the progress to the writer set by WithProgressWriter.
+//
+// The write errors are logged once, the following events are dropped.
+func (options *Options) WriteProgress(progress Progress) {
+	pw := options.progressWriter
+	if options.ProgressWriter == nil || pw == nil {
+		return
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if pw.failed {
+		return
+	}
+
+	if pw.done == nil {
+		pw.done = map[string]int{}
+	}
+
+	pw.done[progress.Source]++
+
+	event := ProgressEvent{
+		Time:       options.Now().UTC(),
+		Source:     progress.Source,
+		Name:       progress.Name,
+		State:      progress.State,
+		Warnings:   progress.Warnings,
+		Done:       pw.done[progress.Source],
+		Total:      progress.Total,
+		QueueDepth: progress.QueueDepth,
+		QueueSize:  progress.QueueSize,
+	}
+
+	if progress.Error != nil {
+		event.Error = progress.Error.Error()
+	}
+
+	data, err := json.Marshal(event)
+	if err == nil {
+		_, err = options.ProgressWriter.Write(append(data, '\n'))
+	}
+
+	if err != nil {
+		pw.failed = true
+
+		options.Log("failed to write progress: %s", err)
+	}
+}
@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+func TestProgressWriter(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(&testArchive{}),
+		bundle.WithProgressWriter(&buf),
+	)
+
+	options.WriteProgress(bundle.Progress{Source: "node", Name: "first", State: "collect first", Total: 2})
+	options.WriteProgress(bundle.Progress{Source: "node", Name: "second", State: "collect second", Total: 2, Error: errors.New("boom")})
+	options.WriteProgress(bundle.Progress{Source: "cluster", Name: "third", State: "collect third", Total: 1})
+
+	var events []bundle.ProgressEvent
+
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var event bundle.ProgressEvent
+
+		require.NoError(json.Unmarshal(line, &event))
+
+		events = append(events, event)
+	}
+
+	require.Len(events, 3)
+	require.Equal("node", events[0].Source)
+	require.Equal(1, events[0].Done)
+	require.Empty(events[0].Error)
+	require.Equal(2, events[1].Done)
+	require.Equal(2, events[1].Total)
+	require.Equal("collect second", events[1].State)
+	require.Equal("boom", events[1].Error)
+	require.Equal("cluster", events[2].Source)
+	require.Equal(1, events[2].Done)
+}
@@ -0,0 +1,166 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// DefaultWriteQueueSize is the default size of the data waiting to be written to the archive
+// above which the collectors are not started.
+const DefaultWriteQueueSize = 64 * 1024 * 1024
+
+// QueueWrites makes the writes to the archive tracked, so the collectors can wait for the queue to go down
+// with WaitWriteQueue.
+//
+// It should be called before anything is written to the archive.
+func (options *Options) QueueWrites() {
+	if options.Archive == nil || options.writeQueue != nil {
+		return
+	}
+
+	limit := options.WriteQueueSize
+	if limit == 0 {
+		limit = DefaultWriteQueueSize
+	}
+
+	options.writeQueue = &writeQueue{
+		Archive:     options.Archive,
+		limit:       limit,
+		changed:     make(chan struct{}),
+		bundleStats: options.stats,
+	}
+
+	options.Archive = options.writeQueue
+}
+
+// WaitWriteQueue blocks while the size of the data waiting to be written to the archive is over the limit
+// set by WithWriteQueueSize.
+//
+// The zip archive is written by one writer at a time, so the workers wait before running the next collector
+// instead of piling up the collected data.
+func (options *Options) WaitWriteQueue(ctx context.Context) error {
+	if options.writeQueue == nil {
+		return nil
+	}
+
+	return options.writeQueue.wait(ctx)
+}
+
+// WriteQueue returns the number of the files being written or waiting to be written to the archive
+// and the size of their data.
+//
+// The size of the streamed files is not known, they are counted in the depth only.
+func (options *Options) WriteQueue() (depth int, size int64) {
+	if options.writeQueue == nil {
+		return 0, 0
+	}
+
+	return options.writeQueue.stats()
+}
+
+// writeQueue tracks the writes waiting for the archive.
+type writeQueue struct {
+	Archive
+
+	// changed is closed and replaced when the queue goes down
+	changed     chan struct{}
+	bundleStats *bundleStats
+	mu          sync.Mutex
+	limit       int64
+	size        int64
+	depth       int
+}
+
+// Write implements Archive.
+func (q *writeQueue) Write(name string, contents []byte) error {
+	size := int64(len(contents))
+
+	q.enter(size)
+	defer q.leave(size)
+
+	if q.bundleStats != nil {
+		q.bundleStats.bytesIn.Add(size)
+	}
+
+	return q.Archive.Write(name, contents)
+}
+
+// WriteFrom implements StreamArchive.
+func (q *writeQueue) WriteFrom(name string, r io.Reader) error {
+	var size int64
+
+	if sized, ok := r.(interface{ Size() int64 }); ok {
+		size = sized.Size()
+	}
+
+	q.enter(size)
+	defer q.leave(size)
+
+	if q.bundleStats != nil {
+		r = countingReader{Reader: r, count: &q.bundleStats.bytesIn}
+	}
+
+	if archive, ok := q.Archive.(StreamArchive); ok {
+		return archive.WriteFrom(name, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return q.Archive.Write(name, data)
+}
+
+func (q *writeQueue) enter(size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.depth++
+	q.size += size
+
+	if q.bundleStats != nil {
+		q.bundleStats.inFlight(q.size)
+	}
+}
+
+func (q *writeQueue) leave(size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.depth--
+	q.size -= size
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *writeQueue) wait(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		full, changed := q.size > q.limit, q.changed
+		q.mu.Unlock()
+
+		if !full {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (q *writeQueue) stats() (int, int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.depth, q.size
+}
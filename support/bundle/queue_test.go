@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+type blockingArchive struct {
+	testArchive
+
+	release chan struct{}
+}
+
+func (a *blockingArchive) Write(path string, data []byte) error {
+	<-a.release
+
+	return a.testArchive.Write(path, data)
+}
+
+func TestWriteQueue(t *testing.T) {
+	require := require.New(t)
+
+	archive := &blockingArchive{release: make(chan struct{})}
+
+	options := bundle.NewOptions(bundle.WithArchive(archive), bundle.WithWriteQueueSize(10))
+	options.QueueWrites()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- options.Archive.Write("large.txt", make([]byte, 100))
+	}()
+
+	require.Eventually(func() bool {
+		depth, _ := options.WriteQueue()
+
+		return depth == 1
+	}, time.Second, time.Millisecond)
+
+	depth, size := options.WriteQueue()
+	require.Equal(1, depth)
+	require.EqualValues(100, size)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(options.WaitWriteQueue(ctx), context.DeadlineExceeded)
+
+	close(archive.release)
+
+	require.NoError(options.WaitWriteQueue(context.Background()))
+	require.NoError(<-errCh)
+
+	depth, size = options.WriteQueue()
+	require.Zero(depth)
+	require.Zero(size)
+}
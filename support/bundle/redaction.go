@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactionReportFile is the name of the redaction report file in the archive.
+const RedactionReportFile = "redaction-report.yaml"
+
+// RedactionReport lists the files changed by the redactors.
+type RedactionReport struct {
+	Totals map[string]int `yaml:"totals"`
+	files  map[string]map[string]int
+	Files  []RedactedFile `yaml:"files"`
+	mu     sync.Mutex
+}
+
+// RedactedFile describes the replacements made in the file by each redactor.
+type RedactedFile struct {
+	Replacements map[string]int `yaml:"replacements"`
+	Path         string         `yaml:"path"`
+}
+
+// RecordRedaction adds the replacements made by the redactor in the archive file to the redaction report.
+func (options *Options) RecordRedaction(path, redactor string, count int) {
+	if count == 0 || options.redactionReport == nil {
+		return
+	}
+
+	report := options.redactionReport
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if report.files == nil {
+		report.files = map[string]map[string]int{}
+	}
+
+	if report.files[path] == nil {
+		report.files[path] = map[string]int{}
+	}
+
+	report.files[path][redactor] += count
+}
+
+// WriteRedactionReport writes the redaction report to the archive if the redaction is enabled or anything was redacted.
+func (options *Options) WriteRedactionReport() error {
+	report := options.redactionReport
+	if report == nil {
+		return nil
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if len(options.Redactors) == 0 && !options.RedactSecrets && !options.RedactPII && len(report.files) == 0 {
+		return nil
+	}
+
+	report.Files = make([]RedactedFile, 0, len(report.files))
+	report.Totals = map[string]int{}
+
+	for path, replacements := range report.files {
+		report.Files = append(report.Files, RedactedFile{
+			Path:         path,
+			Replacements: replacements,
+		})
+
+		for redactor, count := range replacements {
+			report.Totals[redactor] += count
+		}
+	}
+
+	slices.SortFunc(report.Files, func(a, b RedactedFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	data, err := yaml.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	return options.Archive.Write(options.ArchivePath(RedactionReportFile), data)
+}
@@ -0,0 +1,109 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// SecretScanFile is the name of the secret scan report file in the archive.
+const SecretScanFile = "secret-scan.txt"
+
+// SecretFinding is the suspected secret found by the secret scan.
+type SecretFinding struct {
+	Path  string
+	Rule  string
+	Match string
+	Line  int
+}
+
+// String implements fmt.Stringer.
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("suspected secret (%s) in %s:%d: %s", f.Rule, f.Path, f.Line, f.Match)
+}
+
+type secretScan struct {
+	findings map[string][]SecretFinding
+	mu       sync.Mutex
+}
+
+// RecordSecretFindings adds the suspected secrets found in the archive file to the secret scan report.
+func (options *Options) RecordSecretFindings(path string, findings []SecretFinding) {
+	if len(findings) == 0 || options.secretScan == nil {
+		return
+	}
+
+	options.secretScan.mu.Lock()
+	defer options.secretScan.mu.Unlock()
+
+	if options.secretScan.findings == nil {
+		options.secretScan.findings = map[string][]SecretFinding{}
+	}
+
+	for _, finding := range findings {
+		finding.Path = path
+
+		options.secretScan.findings[path] = append(options.secretScan.findings[path], finding)
+	}
+}
+
+// SecretFindings returns the suspected secrets found in the archive file.
+func (options *Options) SecretFindings(path string) []SecretFinding {
+	if options.secretScan == nil {
+		return nil
+	}
+
+	options.secretScan.mu.Lock()
+	defer options.secretScan.mu.Unlock()
+
+	return slices.Clone(options.secretScan.findings[path])
+}
+
+// WriteSecretScan writes the secret scan report to the archive if the secret scan is enabled.
+func (options *Options) WriteSecretScan() error {
+	if !options.SecretScan || options.secretScan == nil {
+		return nil
+	}
+
+	options.secretScan.mu.Lock()
+	defer options.secretScan.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	paths := make([]string, 0, len(options.secretScan.findings))
+
+	for path := range options.secretScan.findings {
+		paths = append(paths, path)
+	}
+
+	slices.SortFunc(paths, strings.Compare)
+
+	if len(paths) == 0 {
+		fmt.Fprintln(&buf, "no suspected secrets found")
+
+		return options.Archive.Write(options.ArchivePath(SecretScanFile), buf.Bytes())
+	}
+
+	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "PATH\tLINE\tRULE\tMATCH") //nolint:errcheck
+
+	for _, path := range paths {
+		for _, finding := range options.secretScan.findings[path] {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", finding.Path, finding.Line, finding.Rule, finding.Match) //nolint:errcheck
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return options.Archive.Write(options.ArchivePath(SecretScanFile), buf.Bytes())
+}
@@ -0,0 +1,144 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpBufferSize is the size of the writes to the remote file, they are split into the concurrent SFTP write requests.
+const sftpBufferSize = 1024 * 1024
+
+// SFTPOutput configures writing the archive to the remote host over SFTP.
+type SFTPOutput struct {
+	// Config is the SSH client configuration: the user, the authentication methods and the host key callback.
+	Config *ssh.ClientConfig
+	// Addr is the SSH server address, e.g. `jump-host:22`.
+	Addr string
+	// Path is the path of the archive on the remote host.
+	Path string
+}
+
+// validate checks the SFTP output configuration.
+func (output *SFTPOutput) validate() error {
+	var errs []error
+
+	if output.Addr == "" {
+		errs = append(errs, errors.New("SFTP output address is not set"))
+	}
+
+	if output.Path == "" {
+		errs = append(errs, errors.New("SFTP output path is not set"))
+	}
+
+	if output.Config == nil {
+		errs = append(errs, errors.New("SFTP output SSH client config is not set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// OpenOutput connects to the remote host set by WithSFTPOutput and sets the archive writing to it.
+//
+// It does nothing if the SFTP output is not configured or the archive is already opened.
+func (options *Options) OpenOutput(ctx context.Context) error {
+	if options.SFTPOutput == nil || options.sftpArchive != nil {
+		return nil
+	}
+
+	output := options.SFTPOutput
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", output.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", output.Addr, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, output.Addr, output.Config)
+	if err != nil {
+		conn.Close() //nolint:errcheck
+
+		return fmt.Errorf("failed to establish SSH connection to %s: %w", output.Addr, err)
+	}
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+
+	sftpClient, err := sftp.NewClient(client, sftp.UseConcurrentWrites(true))
+	if err != nil {
+		client.Close() //nolint:errcheck
+
+		return fmt.Errorf("failed to start SFTP session on %s: %w", output.Addr, err)
+	}
+
+	f, err := sftpClient.Create(output.Path)
+	if err != nil {
+		sftpClient.Close() //nolint:errcheck
+		client.Close()     //nolint:errcheck
+
+		return fmt.Errorf("failed to create %s on %s: %w", output.Path, output.Addr, err)
+	}
+
+	buf := bufio.NewWriterSize(f, sftpBufferSize)
+
+	options.sftpArchive = &sftpArchive{
+		archive: &archive{
+			Archive: zip.NewWriter(options.countOutput(buf)),
+			now:     options.Now,
+			options: options,
+		},
+		buf:        buf,
+		file:       f,
+		sftpClient: sftpClient,
+		client:     client,
+	}
+
+	options.Archive = options.sftpArchive
+
+	return nil
+}
+
+// sftpArchive writes the zip archive to the remote file.
+type sftpArchive struct {
+	*archive
+
+	buf        *bufio.Writer
+	file       *sftp.File
+	sftpClient *sftp.Client
+	client     *ssh.Client
+	closed     bool
+}
+
+// Close implements Archive.
+func (a *sftpArchive) Close() error {
+	if err := a.archive.Close(); err != nil {
+		return errors.Join(err, a.disconnect())
+	}
+
+	return a.disconnect()
+}
+
+// disconnect flushes and closes the remote file and closes the SSH connection, it's also called when the bundle creation fails
+// before the archive is closed.
+func (a *sftpArchive) disconnect() error {
+	a.archiveMu.Lock()
+	defer a.archiveMu.Unlock()
+
+	if a.closed {
+		return nil
+	}
+
+	a.closed = true
+
+	return errors.Join(a.buf.Flush(), a.file.Close(), a.sftpClient.Close(), a.client.Close())
+}
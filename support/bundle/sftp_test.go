@@ -0,0 +1,144 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+func TestSFTPOutput(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(err)
+
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(err)
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("access denied")
+			}
+
+			return nil, nil //nolint:nilnil
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+
+	defer lis.Close() //nolint:errcheck
+
+	served := make(chan error, 1)
+
+	go func() {
+		conn, acceptErr := lis.Accept()
+		if acceptErr != nil {
+			served <- acceptErr
+
+			return
+		}
+
+		_, chans, reqs, handshakeErr := ssh.NewServerConn(conn, serverConfig)
+		if handshakeErr != nil {
+			served <- handshakeErr
+
+			return
+		}
+
+		go ssh.DiscardRequests(reqs)
+
+		for newChannel := range chans {
+			channel, channelReqs, _ := newChannel.Accept() //nolint:errcheck
+
+			go func() {
+				for req := range channelReqs {
+					req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil) //nolint:errcheck
+				}
+			}()
+
+			server, serverErr := sftp.NewServer(channel)
+			if serverErr != nil {
+				served <- serverErr
+
+				return
+			}
+
+			serverErr = server.Serve()
+			if errors.Is(serverErr, io.EOF) {
+				serverErr = nil
+			}
+
+			server.Close() //nolint:errcheck
+
+			served <- serverErr
+		}
+	}()
+
+	clientConfig := &ssh.ClientConfig{
+		User:            "support",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.FixedHostKey(signer.PublicKey()),
+	}
+
+	remotePath := filepath.Join(t.TempDir(), "support.zip")
+
+	options := bundle.NewOptions(
+		bundle.WithLogOutput(io.Discard),
+		bundle.WithSFTPOutput(lis.Addr().String(), clientConfig, remotePath),
+	)
+
+	// incompressible data is written in many SFTP requests
+	contents := make([]byte, 4<<20)
+
+	_, err = rand.Read(contents)
+	require.NoError(err)
+
+	require.NoError(options.OpenOutput(ctx))
+	require.NoError(options.Archive.Write("random.bin", contents))
+	require.NoError(options.Archive.Close())
+
+	select {
+	case err = <-served:
+		require.NoError(err)
+	case <-ctx.Done():
+		require.FailNow("the archive was not written")
+	}
+
+	zr, err := zip.OpenReader(remotePath)
+	require.NoError(err)
+
+	defer zr.Close() //nolint:errcheck
+
+	f, err := zr.Open("random.bin")
+	require.NoError(err)
+
+	read, err := io.ReadAll(f)
+	require.NoError(err)
+	require.Equal(contents, read)
+
+	require.Error(bundle.NewOptions(bundle.WithArchive(&testArchive{}), bundle.WithSFTPOutput("jump-host:22", clientConfig, "support.zip")).Validate())
+	require.Error(bundle.NewOptions(bundle.WithSFTPOutput("jump-host:22", nil, "")).Validate())
+}
@@ -0,0 +1,198 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// maxMemorySpoolSize is the capacity of the memory spools above which they are not reused.
+const maxMemorySpoolSize = 8 * 1024 * 1024
+
+// spool keeps the compressed file until it's copied to the archive.
+//
+// The spools are reused by the writers, so there are as many of them as the files compressed at once.
+type spool struct {
+	file       *os.File
+	compressor *flate.Writer
+	buf        bytes.Buffer
+	size       int64
+}
+
+// Write implements io.Writer.
+func (s *spool) Write(p []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+
+	if s.file != nil {
+		n, err = s.file.Write(p)
+	} else {
+		n, err = s.buf.Write(p)
+	}
+
+	s.size += int64(n)
+
+	return n, err
+}
+
+// Reader returns the reader of the spooled data.
+func (s *spool) Reader() io.Reader {
+	if s.file != nil {
+		return io.NewSectionReader(s.file, 0, s.size)
+	}
+
+	return bytes.NewReader(s.buf.Bytes())
+}
+
+func (s *spool) reset() error {
+	s.size = 0
+	s.buf.Reset()
+
+	if s.file == nil {
+		return nil
+	}
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := s.file.Seek(0, io.SeekStart)
+
+	return err
+}
+
+func (s *spool) remove() error {
+	if s.file == nil {
+		return nil
+	}
+
+	return errors.Join(s.file.Close(), os.Remove(s.file.Name()))
+}
+
+func (a *archive) compressesInParallel() bool {
+	return a.options != nil && a.options.ParallelCompression
+}
+
+// writeSpooled compresses the file to the spool and then copies the compressed data to the archive,
+// so the files are compressed in parallel by the writers.
+func (a *archive) writeSpooled(path string, r io.Reader) error {
+	s, err := a.getSpool()
+	if err != nil {
+		return err
+	}
+
+	defer a.putSpool(s)
+
+	header := &zip.FileHeader{
+		Name:   path,
+		Method: CompressionMethod(path),
+	}
+
+	header.SetModTime(a.now()) //nolint:staticcheck
+
+	hash := crc32.NewIEEE()
+	r = io.TeeReader(r, hash)
+
+	var size int64
+
+	if header.Method == zip.Deflate {
+		s.compressor.Reset(s)
+
+		if size, err = io.Copy(s.compressor, r); err != nil {
+			return err
+		}
+
+		if err = s.compressor.Close(); err != nil {
+			return err
+		}
+	} else if size, err = io.Copy(s, r); err != nil {
+		return err
+	}
+
+	header.CRC32 = hash.Sum32()
+	header.CompressedSize64 = uint64(s.size) //nolint:gosec
+	header.UncompressedSize64 = uint64(size) //nolint:gosec
+
+	a.archiveMu.Lock()
+	defer a.archiveMu.Unlock()
+
+	w, err := a.Archive.CreateRaw(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, s.Reader())
+
+	return err
+}
+
+func (a *archive) getSpool() (*spool, error) {
+	a.spoolsMu.Lock()
+
+	if n := len(a.spools); n > 0 {
+		s := a.spools[n-1]
+		a.spools = a.spools[:n-1]
+
+		a.spoolsMu.Unlock()
+
+		return s, nil
+	}
+
+	a.spoolsMu.Unlock()
+
+	s := &spool{}
+
+	var err error
+
+	if s.compressor, err = flate.NewWriter(s, flate.DefaultCompression); err != nil {
+		return nil, err
+	}
+
+	if a.options.TempDir != "" {
+		if s.file, err = os.CreateTemp(a.options.TempDir, "talos-support-spool-*"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// putSpool puts the spool back to be reused by the next writer.
+func (a *archive) putSpool(s *spool) {
+	if err := s.reset(); err != nil || (s.file == nil && s.buf.Cap() > maxMemorySpoolSize) {
+		s.remove() //nolint:errcheck
+
+		return
+	}
+
+	a.spoolsMu.Lock()
+	defer a.spoolsMu.Unlock()
+
+	a.spools = append(a.spools, s)
+}
+
+// removeSpools removes the spool files when the archive is closed.
+func (a *archive) removeSpools() error {
+	a.spoolsMu.Lock()
+	defer a.spoolsMu.Unlock()
+
+	var errs []error
+
+	for _, s := range a.spools {
+		errs = append(errs, s.remove())
+	}
+
+	a.spools = nil
+
+	return errors.Join(errs...)
+}
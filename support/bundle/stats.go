@@ -0,0 +1,157 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase is the stage of the bundle creation.
+type Phase string
+
+// Bundle creation phases.
+const (
+	// PhasePrepare opens the archive and writes the metadata.
+	PhasePrepare Phase = "prepare"
+	// PhaseCollect runs the collectors.
+	PhaseCollect Phase = "collect"
+	// PhaseFinish writes the reports, uploads and closes the archive.
+	PhaseFinish Phase = "finish"
+)
+
+// Stats are the performance statistics of the bundle creation.
+type Stats struct {
+	// Phases are the durations of the bundle creation phases.
+	Phases map[Phase]time.Duration `json:"phases"`
+	// Duration is the total duration of the phases.
+	Duration time.Duration `json:"duration"`
+	// BytesIn is the size of the data written to the archive before the compression,
+	// the files compressed by the collectors (see WithCompressLargeFiles) are counted compressed.
+	BytesIn int64 `json:"bytesIn"`
+	// BytesOut is the size of the archive written to the output, it's only known
+	// for the archives created by the bundle, see WithArchiveOutput.
+	BytesOut int64 `json:"bytesOut"`
+	// PeakInFlight is the peak size of the collected data waiting to be written to the archive.
+	//
+	// The size of the streamed files is not known, they are not counted.
+	PeakInFlight int64 `json:"peakInFlight"`
+	// CompressionRatio is BytesIn divided by BytesOut, it's zero if the output size is not known.
+	CompressionRatio float64 `json:"compressionRatio"`
+}
+
+// Stats returns the performance statistics of the bundle creation, they are complete after the bundle is created.
+func (options *Options) Stats() Stats {
+	if options.stats == nil {
+		return Stats{}
+	}
+
+	return options.stats.snapshot()
+}
+
+// RecordPhase adds the duration of the bundle creation phase to the statistics.
+func (options *Options) RecordPhase(phase Phase, duration time.Duration) {
+	if options.stats == nil {
+		return
+	}
+
+	options.stats.record(phase, duration)
+}
+
+// bundleStats collects the statistics of the bundle creation, it's shared by the copies of the options.
+type bundleStats struct {
+	phases       map[Phase]time.Duration
+	mu           sync.Mutex
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+	peakInFlight atomic.Int64
+}
+
+func (s *bundleStats) record(phase Phase, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.phases == nil {
+		s.phases = map[Phase]time.Duration{}
+	}
+
+	s.phases[phase] += duration
+}
+
+func (s *bundleStats) inFlight(size int64) {
+	for {
+		peak := s.peakInFlight.Load()
+
+		if size <= peak || s.peakInFlight.CompareAndSwap(peak, size) {
+			return
+		}
+	}
+}
+
+func (s *bundleStats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{
+		Phases:       maps.Clone(s.phases),
+		BytesIn:      s.bytesIn.Load(),
+		BytesOut:     s.bytesOut.Load(),
+		PeakInFlight: s.peakInFlight.Load(),
+	}
+
+	for _, duration := range s.phases {
+		stats.Duration += duration
+	}
+
+	if stats.BytesOut > 0 {
+		stats.CompressionRatio = float64(stats.BytesIn) / float64(stats.BytesOut)
+	}
+
+	return stats
+}
+
+// countingWriter counts the bytes written to the output.
+type countingWriter struct {
+	io.Writer
+
+	count *atomic.Int64
+}
+
+// Write implements io.Writer.
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+
+	w.count.Add(int64(n))
+
+	return n, err
+}
+
+// countOutput wraps the archive output to count the bytes written to it.
+func (options *Options) countOutput(w io.Writer) io.Writer {
+	if options.stats == nil {
+		return w
+	}
+
+	return countingWriter{Writer: w, count: &options.stats.bytesOut}
+}
+
+// countingReader counts the bytes read from the collected file.
+type countingReader struct {
+	io.Reader
+
+	count *atomic.Int64
+}
+
+// Read implements io.Reader.
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+
+	r.count.Add(int64(n))
+
+	return n, err
+}
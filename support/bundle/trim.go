@@ -0,0 +1,32 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+// TrimReportFile is the name of the trim report file in the archive.
+const TrimReportFile = "trim-report.yaml"
+
+// Trim actions.
+const (
+	TrimRemoved   = "removed"
+	TrimTruncated = "truncated"
+)
+
+// TrimReport lists the files removed or truncated to fit the bundle into the size target.
+//
+// All sizes are the uncompressed sizes of the files.
+type TrimReport struct {
+	Files                  []TrimmedFile `yaml:"files"`
+	OriginalSize           int64         `yaml:"originalSize"`
+	Size                   int64         `yaml:"size"`
+	TargetUncompressedSize int64         `yaml:"targetUncompressedSize"`
+}
+
+// TrimmedFile is the file removed or truncated by the trimming, the sizes are uncompressed.
+type TrimmedFile struct {
+	Path         string `yaml:"path"`
+	Action       string `yaml:"action"`
+	OriginalSize int64  `yaml:"originalSize"`
+	Size         int64  `yaml:"size"`
+}
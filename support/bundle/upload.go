@@ -0,0 +1,382 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UploadFile is the name of the file with the upload result in the archive.
+//
+// The file is written only to the local archive, the uploaded bundle can't contain its own reference.
+const UploadFile = "upload.yaml"
+
+// Upload defaults.
+const (
+	DefaultUploadAttempts = 3
+	DefaultUploadBackoff  = 2 * time.Second
+)
+
+// UploadConfig configures the upload of the bundle to the support portal.
+type UploadConfig struct {
+	// Client is the HTTP client used for the upload, http.DefaultClient is used if nil.
+	Client *http.Client
+	// Progress is called with the number of bytes sent and the bundle size.
+	Progress func(sent, total int64)
+	URL      string
+	Token    string
+	// Attempts is the number of the upload attempts, DefaultUploadAttempts is used if zero.
+	Attempts int
+	// Backoff is the delay before the first retry, it's doubled after each attempt, DefaultUploadBackoff is used if zero.
+	Backoff time.Duration
+}
+
+// UploadResult is the result of the bundle upload.
+type UploadResult struct {
+	Uploaded  time.Time `yaml:"uploaded"`
+	URL       string    `yaml:"url"`
+	Reference string    `yaml:"reference"`
+	SHA256    string    `yaml:"sha256"`
+	Size      int64     `yaml:"size"`
+}
+
+// uploadResponse is the response of the support portal.
+type uploadResponse struct {
+	ID string `json:"id"`
+}
+
+// validate checks the upload configuration.
+func (config *UploadConfig) validate() error {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return fmt.Errorf("invalid upload URL: %w", err)
+	}
+
+	if u.Scheme != "https" {
+		return fmt.Errorf("upload URL %q is not HTTPS", config.URL)
+	}
+
+	return nil
+}
+
+// uploadConfig returns the upload configuration creating it if needed.
+func (options *Options) uploadConfig() *UploadConfig {
+	if options.Upload == nil {
+		options.Upload = &UploadConfig{}
+	}
+
+	return options.Upload
+}
+
+// PrepareUpload wraps the archive to write the copy of the bundle to the temporary file for the upload.
+//
+// It should be called before anything is written to the archive, it does nothing if the upload is not configured.
+func (options *Options) PrepareUpload() error {
+	if options.Upload == nil || options.upload != nil {
+		return nil
+	}
+
+	f, err := os.CreateTemp(options.TempDir, "support-bundle-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create upload file: %w", err)
+	}
+
+	// the upload file is the output only if the bundle is not written to the archive too
+	var out io.Writer = f
+
+	if options.Archive == nil {
+		out = options.countOutput(f)
+	}
+
+	options.upload = &uploadArchive{
+		Archive: options.Archive,
+		file:    f,
+		zip: &archive{
+			Archive: zip.NewWriter(out),
+			now:     options.Now,
+			options: options,
+		},
+	}
+
+	options.Archive = options.upload
+
+	return nil
+}
+
+// UploadBundle uploads the bundle prepared by PrepareUpload and writes the upload result to the local archive as UploadFile.
+//
+// The upload copy is closed, so nothing can be written to the archive except the upload result after the call.
+func (options *Options) UploadBundle(ctx context.Context) (*UploadResult, error) {
+	a := options.upload
+	if a == nil {
+		return nil, errors.New("upload is not prepared")
+	}
+
+	if err := a.closeUpload(); err != nil {
+		return nil, err
+	}
+
+	result, err := options.Upload.upload(ctx, a.file, options.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Uploaded = options.Now().UTC()
+	options.uploadResult = result
+
+	// the upload-only bundle has no local archive, so the reference is kept in the log
+	options.Log("uploaded bundle as %s", result.Reference)
+
+	if a.Archive == nil {
+		return result, nil
+	}
+
+	data, err := yaml.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, a.Archive.Write(options.ArchivePath(UploadFile), data)
+}
+
+// UploadResult returns the result of the bundle upload configured by WithUpload, nil if the bundle wasn't uploaded.
+func (options *Options) UploadResult() *UploadResult {
+	return options.uploadResult
+}
+
+func (config *UploadConfig) upload(ctx context.Context, f *os.File, log func(line string, args ...interface{})) (*UploadResult, error) {
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, io.NewSectionReader(f, 0, 1<<63-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload file: %w", err)
+	}
+
+	result := &UploadResult{
+		URL:    config.URL,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Size:   size,
+	}
+
+	attempts := config.Attempts
+	if attempts <= 0 {
+		attempts = DefaultUploadAttempts
+	}
+
+	backoff := config.Backoff
+	if backoff <= 0 {
+		backoff = DefaultUploadBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		var retry bool
+
+		result.Reference, retry, err = config.send(ctx, io.NewSectionReader(f, 0, size), result)
+		if err == nil {
+			return result, nil
+		}
+
+		if !retry || attempt >= attempts {
+			return nil, fmt.Errorf("failed to upload bundle after %d attempts: %w", attempt, err)
+		}
+
+		log("upload attempt %d failed, retrying in %s: %s", attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// send makes a single upload attempt, it returns whether the failure can be retried.
+func (config *UploadConfig) send(ctx context.Context, r *io.SectionReader, result *UploadResult) (string, bool, error) {
+	body := &progressReader{
+		r:        r,
+		total:    r.Size(),
+		progress: config.Progress,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, body)
+	if err != nil {
+		return "", false, err
+	}
+
+	req.ContentLength = r.Size()
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("X-Bundle-Sha256", result.SHA256)
+
+	if config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+config.Token)
+	}
+
+	client := config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", ctx.Err() == nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+
+		return "", retry, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var response uploadResponse
+
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", false, fmt.Errorf("failed to decode upload response: %w", err)
+	}
+
+	if response.ID == "" {
+		return "", false, errors.New("upload response has no reference ID")
+	}
+
+	return response.ID, false, nil
+}
+
+// progressReader reports the number of bytes read.
+type progressReader struct {
+	r        io.Reader
+	progress func(sent, total int64)
+	sent     int64
+	total    int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+
+	r.sent += int64(n)
+
+	if r.progress != nil && n > 0 {
+		r.progress(r.sent, r.total)
+	}
+
+	return n, err
+}
+
+// uploadArchive writes the files both to the archive and to the upload copy.
+type uploadArchive struct {
+	Archive
+
+	file     *os.File
+	zip      *archive
+	closeErr error
+	mu       sync.Mutex
+	closed   bool
+	removed  bool
+}
+
+// Write implements Archive.
+func (a *uploadArchive) Write(name string, contents []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.zip.Write(name, contents); err != nil {
+		return err
+	}
+
+	if a.Archive == nil {
+		return nil
+	}
+
+	return a.Archive.Write(name, contents)
+}
+
+// WriteFrom implements StreamArchive.
+func (a *uploadArchive) WriteFrom(name string, r io.Reader) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Archive == nil {
+		return a.zip.WriteFrom(name, r)
+	}
+
+	// the upload copy entry is written while the archive reads the contents
+	a.zip.archiveMu.Lock()
+	defer a.zip.archiveMu.Unlock()
+
+	file, err := a.zip.Archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   CompressionMethod(name),
+		Modified: a.zip.now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	r = io.TeeReader(r, file)
+
+	if archive, ok := a.Archive.(StreamArchive); ok {
+		return archive.WriteFrom(name, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return a.Archive.Write(name, data)
+}
+
+func (a *uploadArchive) closeUpload() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.closed {
+		a.closed = true
+		a.closeErr = a.zip.Close()
+	}
+
+	return a.closeErr
+}
+
+// Close implements Archive, it closes the archive and removes the upload copy.
+func (a *uploadArchive) Close() error {
+	errs := []error{a.closeUpload(), a.remove()}
+
+	if a.Archive != nil {
+		errs = append(errs, a.Archive.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// remove removes the upload copy, it's also called when the bundle creation fails before the archive is closed.
+func (a *uploadArchive) remove() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.removed {
+		return nil
+	}
+
+	a.removed = true
+
+	return errors.Join(a.file.Close(), os.Remove(a.file.Name()))
+}
@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundle_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+func TestUpload(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	var (
+		uploaded []byte
+		attempts int
+	)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		uploaded, _ = io.ReadAll(r.Body) //nolint:errcheck
+
+		w.Write([]byte(`{"id":"CASE-1234"}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	archive := &testArchive{}
+
+	var sent int64
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+		bundle.WithUpload(srv.URL, "secret"),
+		bundle.WithUploadClient(srv.Client()),
+		bundle.WithUploadRetries(2, time.Millisecond),
+		bundle.WithUploadProgress(func(s, _ int64) { sent = s }),
+	)
+
+	require.NoError(options.PrepareUpload())
+	require.NoError(options.Archive.Write("hello.txt", []byte("hello")))
+
+	result, err := options.UploadBundle(ctx)
+	require.NoError(err)
+	require.Equal(options.UploadResult(), result)
+
+	require.Equal(2, attempts)
+	require.Equal(int64(len(uploaded)), sent)
+	require.Equal("CASE-1234", options.UploadResult().Reference)
+	require.Contains(string(archive.files[bundle.UploadFile]), "reference: CASE-1234")
+
+	zr, err := zip.NewReader(bytes.NewReader(uploaded), int64(len(uploaded)))
+	require.NoError(err)
+
+	_, err = fs.Stat(zr, "hello.txt")
+	require.NoError(err)
+
+	_, err = fs.Stat(zr, bundle.UploadFile)
+	require.ErrorIs(err, fs.ErrNotExist)
+
+	require.Equal("hello", string(archive.files["hello.txt"]))
+
+	require.Error(bundle.NewOptions(bundle.WithUpload("http://example.com", "")).Validate())
+}
@@ -0,0 +1,85 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// generatedFiles are written by the bundle creator itself, they are not copied from the existing bundles.
+var generatedFiles = []string{
+	bundle.MetadataFile,
+	bundle.ManifestFile,
+	bundle.RedactionReportFile,
+	bundle.SecretScanFile,
+	bundle.AttestationFile,
+	bundle.TrimReportFile,
+	bundle.UploadFile,
+}
+
+// GetBundleCollectors creates the collectors which copy the files of the existing bundle.
+//
+// The copied files go through the same redaction, allow list and size limits as the freshly collected ones,
+// so the collectors can be used to produce the sanitized copy of the bundle. The files generated by the bundle
+// creator (metadata, manifest and the reports) are not copied, they are written again for the new bundle.
+func GetBundleCollectors(b *bundlereader.Bundle) []*collectors.Collector {
+	var cols []*collectors.Collector
+
+	for _, file := range b.Files() {
+		if slices.Contains(generatedFiles, file) {
+			continue
+		}
+
+		cols = append(cols, NewBundleFileCollector(b, file, 0))
+	}
+
+	return cols
+}
+
+// NewBundleFileCollector creates the collector which copies the file of the existing bundle.
+//
+// The file larger than the limit is truncated the same way as by bundle.WithMaxFileSize, zero limit keeps the file intact.
+func NewBundleFileCollector(b *bundlereader.Bundle, file string, limit int64) *collectors.Collector {
+	collector := collectors.NewStreamCollector(file, func(_ context.Context, options *bundle.Options, w io.Writer) error {
+		options.Log("copying %s", file)
+
+		r, err := b.Open(file)
+		if err != nil {
+			return err
+		}
+
+		defer r.Close() //nolint:errcheck
+
+		if limit > 0 {
+			size, sizeErr := b.Size(file)
+			if sizeErr != nil {
+				return sizeErr
+			}
+
+			if size > limit {
+				return collectors.TruncateStream(w, r, size, limit)
+			}
+		}
+
+		_, err = io.Copy(w, r)
+
+		return err
+	})
+
+	if node, _, ok := strings.Cut(file, "/"); ok {
+		if _, isNode := b.Node(node); isNode {
+			collectors.WithSource([]*collectors.Collector{collector}, node)
+		}
+	}
+
+	return collector
+}
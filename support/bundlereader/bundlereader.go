@@ -0,0 +1,548 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package bundlereader provides the typed access to the support bundles created by the support package.
+package bundlereader
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// KubernetesResourcesDir is the directory of the cluster wide Kubernetes resources in the bundle.
+const KubernetesResourcesDir = "kubernetesResources"
+
+// ErrUnsupportedManifest is returned when the bundle manifest was written by the newer version of the bundle creator.
+var ErrUnsupportedManifest = errors.New("unsupported bundle manifest schema")
+
+// ErrMultipleBundles is returned when the archive contains several bundles, e.g. the bundles of several clusters
+// created with the different bundle.WithPathPrefix, and the prefix of the bundle to open wasn't set.
+var ErrMultipleBundles = errors.New("multiple bundles found, set the prefix of the bundle to open")
+
+// Bundle is the support bundle opened for reading.
+//
+// The paths used by the bundle methods are relative to the bundle root, the prefix set by bundle.WithPathPrefix
+// is detected using the metadata file location.
+type Bundle struct {
+	fsys     fs.FS
+	closer   io.Closer
+	metadata *bundle.BundleMetadata
+	manifest *bundle.Manifest
+	nodes    map[string]*Node
+	renamed  map[string]string
+	prefix   string
+	files    []string
+	layout   int
+}
+
+// Open opens the bundle from the zip archive, tar archive (optionally gzipped) or the directory.
+//
+// The tar archives are read into memory as they don't support random access.
+// ErrMultipleBundles is returned if the archive contains several bundles, use OpenPrefix to open each of them.
+func Open(p string) (*Bundle, error) {
+	return OpenPrefix(p, "")
+}
+
+// OpenPrefix opens the bundle under the prefix directory of the archive, see Prefixes.
+//
+// The prefix is detected if empty.
+func OpenPrefix(p, prefix string) (*Bundle, error) {
+	fsys, files, closer, err := openArchive(p)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := newBundle(fsys, files, prefix)
+	if err != nil {
+		if closer != nil {
+			closer.Close() //nolint:errcheck
+		}
+
+		return nil, err
+	}
+
+	b.closer = closer
+
+	return b, nil
+}
+
+// Prefixes returns the prefixes of the bundles in the archive, the empty prefix is the bundle in the archive root.
+//
+// The bundles nested into other bundles are not listed.
+func Prefixes(p string) ([]string, error) {
+	_, files, closer, err := openArchive(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if closer != nil {
+		defer closer.Close() //nolint:errcheck
+	}
+
+	return bundlePrefixes(files), nil
+}
+
+// FromFS opens the bundle from the file system.
+func FromFS(fsys fs.FS) (*Bundle, error) {
+	return FromFSPrefix(fsys, "")
+}
+
+// FromFSPrefix opens the bundle under the prefix directory of the file system.
+//
+// The prefix is detected if empty.
+func FromFSPrefix(fsys fs.FS, prefix string) (*Bundle, error) {
+	files, err := listFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBundle(fsys, files, prefix)
+}
+
+// openArchive opens the archive or the directory and lists its files.
+func openArchive(p string) (fs.FS, []string, io.Closer, error) {
+	st, err := os.Stat(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if st.IsDir() {
+		fsys := os.DirFS(p)
+
+		files, err := listFiles(fsys)
+
+		return fsys, files, nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	magic := make([]byte, 4)
+
+	if _, err = io.ReadFull(f, magic); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to detect the bundle format: %w", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		files, err := listFiles(zr)
+		if err != nil {
+			zr.Close() //nolint:errcheck
+
+			return nil, nil, nil, err
+		}
+
+		return zr, files, zr, nil
+	}
+
+	fsys, files, err := readTar(f)
+
+	return fsys, files, nil, err
+}
+
+func listFiles(fsys fs.FS) ([]string, error) {
+	var files []string
+
+	if err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// bundlePrefixes returns the directories of the metadata files which are not nested into the other bundles.
+func bundlePrefixes(files []string) []string {
+	var dirs []string
+
+	for _, file := range files {
+		if path.Base(file) == bundle.MetadataFile {
+			dirs = append(dirs, path.Dir(file))
+		}
+	}
+
+	// the outer bundles go first
+	slices.SortFunc(dirs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "/"), strings.Count(b, "/")), cmp.Compare(a, b))
+	})
+
+	var prefixes []string
+
+	for _, dir := range dirs {
+		if slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return prefix == "." || strings.HasPrefix(dir, prefix+"/")
+		}) {
+			continue
+		}
+
+		prefixes = append(prefixes, dir)
+	}
+
+	for i := range prefixes {
+		prefixes[i] = strings.TrimPrefix(prefixes[i], ".")
+	}
+
+	slices.Sort(prefixes)
+
+	return prefixes
+}
+
+func newBundle(fsys fs.FS, files []string, prefix string) (*Bundle, error) {
+	b := &Bundle{
+		fsys:   fsys,
+		nodes:  map[string]*Node{},
+		prefix: strings.Trim(prefix, "/"),
+	}
+
+	slices.Sort(files)
+
+	if b.prefix == "" {
+		prefixes := bundlePrefixes(files)
+
+		if len(prefixes) > 1 {
+			return nil, fmt.Errorf("%w: %s", ErrMultipleBundles, strings.Join(prefixes, ", "))
+		}
+
+		if len(prefixes) == 1 {
+			b.prefix = prefixes[0]
+		}
+	}
+
+	for _, file := range files {
+		if b.prefix != "" {
+			var ok bool
+
+			if file, ok = strings.CutPrefix(file, b.prefix+"/"); !ok {
+				continue
+			}
+		}
+
+		b.files = append(b.files, file)
+	}
+
+	if prefix != "" && len(b.files) == 0 {
+		return nil, fmt.Errorf("no bundle files found under %q: %w", prefix, fs.ErrNotExist)
+	}
+
+	b.detectCompressed()
+
+	if b.Has(bundle.MetadataFile) {
+		b.metadata = &bundle.BundleMetadata{}
+
+		data, err := b.ReadFile(bundle.MetadataFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = yaml.Unmarshal(data, b.metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse the bundle metadata: %w", err)
+		}
+	}
+
+	if b.Has(bundle.ManifestFile) {
+		data, err := b.ReadFile(bundle.ManifestFile)
+		if err != nil {
+			return nil, err
+		}
+
+		b.manifest = &bundle.Manifest{}
+
+		if err = json.Unmarshal(data, b.manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse the bundle manifest: %w", err)
+		}
+
+		if b.manifest.SchemaVersion > bundle.ManifestSchemaVersion {
+			return nil, fmt.Errorf("%w: version %d, supported up to %d", ErrUnsupportedManifest, b.manifest.SchemaVersion, bundle.ManifestSchemaVersion)
+		}
+	}
+
+	if err := b.detectLayout(); err != nil {
+		return nil, err
+	}
+
+	b.upgradeLayout()
+	b.detectNodes()
+
+	return b, nil
+}
+
+// detectCompressed maps the files compressed by the collectors to their original names.
+func (b *Bundle) detectCompressed() {
+	files := slices.Clone(b.files)
+
+	for i, file := range files {
+		name, ok := strings.CutSuffix(file, bundle.CompressedSuffix)
+		if !ok {
+			continue
+		}
+
+		if _, found := slices.BinarySearch(files, name); found {
+			continue
+		}
+
+		if b.renamed == nil {
+			b.renamed = map[string]string{}
+		}
+
+		b.renamed[name] = file
+		b.files[i] = name
+	}
+
+	slices.Sort(b.files)
+}
+
+// detectNodes finds the node directories: the top level directories other than the cluster ones.
+func (b *Bundle) detectNodes() {
+	for _, file := range b.files {
+		name, rest, ok := strings.Cut(file, "/")
+		if !ok || name == KubernetesResourcesDir {
+			continue
+		}
+
+		node, ok := b.nodes[name]
+		if !ok {
+			node = &Node{
+				Name:   name,
+				bundle: b,
+			}
+
+			b.nodes[name] = node
+		}
+
+		node.files = append(node.files, rest)
+	}
+}
+
+// Close closes the bundle.
+func (b *Bundle) Close() error {
+	if b.closer == nil {
+		return nil
+	}
+
+	return b.closer.Close()
+}
+
+// Metadata returns the bundle metadata or nil if the bundle has no metadata file.
+func (b *Bundle) Metadata() *bundle.BundleMetadata {
+	return b.metadata
+}
+
+// Manifest returns the bundle manifest or nil if the bundle was created without the manifest.
+func (b *Bundle) Manifest() *bundle.Manifest {
+	return b.manifest
+}
+
+// Upload returns the result of the bundle upload or nil if the bundle wasn't uploaded.
+//
+// Only the local copy of the uploaded bundle has the upload result.
+func (b *Bundle) Upload() (*bundle.UploadResult, error) {
+	if !b.Has(bundle.UploadFile) {
+		return nil, nil //nolint:nilnil
+	}
+
+	data, err := b.ReadFile(bundle.UploadFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bundle.UploadResult
+
+	if err = yaml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse the upload result: %w", err)
+	}
+
+	return &result, nil
+}
+
+// Files returns the paths of all files in the bundle.
+func (b *Bundle) Files() []string {
+	return b.files
+}
+
+// Has checks if the file is in the bundle.
+func (b *Bundle) Has(p string) bool {
+	_, found := slices.BinarySearch(b.files, p)
+
+	return found
+}
+
+// Open opens the file from the bundle.
+//
+// The files compressed by the collectors are decompressed.
+func (b *Bundle) Open(p string) (io.ReadCloser, error) {
+	archivePath := b.archivePath(p)
+
+	f, err := b.fsys.Open(archivePath)
+	if err != nil || !b.compressed(p, archivePath) {
+		return f, err
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("failed to decompress %s: %w", p, err)
+	}
+
+	return &gzipFile{Reader: zr, file: f}, nil
+}
+
+// ReadFile reads the file from the bundle.
+func (b *Bundle) ReadFile(p string) ([]byte, error) {
+	archivePath := b.archivePath(p)
+
+	if !b.compressed(p, archivePath) {
+		return fs.ReadFile(b.fsys, archivePath)
+	}
+
+	f, err := b.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	return io.ReadAll(f)
+}
+
+// Size returns the size of the file in the bundle.
+//
+// The size of the compressed files is found by decompressing them.
+func (b *Bundle) Size(p string) (int64, error) {
+	archivePath := b.archivePath(p)
+
+	if b.compressed(p, archivePath) {
+		f, err := b.Open(p)
+		if err != nil {
+			return 0, err
+		}
+
+		defer f.Close() //nolint:errcheck
+
+		return io.Copy(io.Discard, f)
+	}
+
+	st, err := fs.Stat(b.fsys, archivePath)
+	if err != nil {
+		return 0, err
+	}
+
+	return st.Size(), nil
+}
+
+// compressed checks if the file was compressed by the collector.
+func (b *Bundle) compressed(p, archivePath string) bool {
+	return strings.HasSuffix(archivePath, bundle.CompressedSuffix) && !strings.HasSuffix(p, bundle.CompressedSuffix)
+}
+
+// gzipFile closes both the decompressor and the file.
+type gzipFile struct {
+	*gzip.Reader
+
+	file io.Closer
+}
+
+// Close implements io.Closer.
+func (f *gzipFile) Close() error {
+	return errors.Join(f.Reader.Close(), f.file.Close())
+}
+
+// Lines calls the function for each line of the text file, the iteration stops if the function returns false.
+func (b *Bundle) Lines(p string, f func(line string) bool) error {
+	r, err := b.Open(p)
+	if err != nil {
+		return err
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" && !f(strings.TrimSuffix(line, "\n")) {
+			return nil
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// Nodes returns the Talos nodes in the bundle sorted by name.
+func (b *Bundle) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(b.nodes))
+
+	for _, node := range b.nodes {
+		nodes = append(nodes, node)
+	}
+
+	slices.SortFunc(nodes, func(a, b *Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return nodes
+}
+
+// Node returns the Talos node by name.
+func (b *Bundle) Node(name string) (*Node, bool) {
+	node, ok := b.nodes[name]
+
+	return node, ok
+}
+
+// Logs returns the paths of all log files in the bundle.
+func (b *Bundle) Logs() []string {
+	var logs []string
+
+	for _, file := range b.files {
+		if strings.HasSuffix(file, ".log") {
+			logs = append(logs, file)
+		}
+	}
+
+	return logs
+}
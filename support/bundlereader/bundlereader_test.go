@@ -0,0 +1,232 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader_test
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/siderolabs/go-talos-support/support"
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+type testArchive struct {
+	files   map[string][]byte
+	filesMu sync.Mutex
+}
+
+func (a *testArchive) Write(path string, data []byte) error {
+	a.filesMu.Lock()
+	defer a.filesMu.Unlock()
+
+	if a.files == nil {
+		a.files = map[string][]byte{}
+	}
+
+	a.files[path] = data
+
+	return nil
+}
+
+func (a *testArchive) Close() error {
+	return nil
+}
+
+// twoClusters are the files of the archive with the bundles of two clusters, the first one contains the nested bundle.
+var twoClusters = map[string]string{
+	"cluster-a/2024-06-01T12-00/metadata.yaml":                      "cluster: cluster-a\n",
+	"cluster-a/2024-06-01T12-00/10.5.0.2/dmesg.log":                 "cluster-a dmesg",
+	"cluster-a/2024-06-01T12-00/10.5.0.2/attachments/metadata.yaml": "cluster: nested\n",
+	"cluster-b/2024-06-02T08-30/metadata.yaml":                      "cluster: cluster-b\n",
+	"cluster-b/2024-06-02T08-30/10.6.0.2/dmesg.log":                 "cluster-b dmesg",
+	"cluster-b/2024-06-02T08-30/10.6.0.3/dmesg.log":                 "cluster-b dmesg",
+}
+
+func TestMultipleClusters(t *testing.T) {
+	require := require.New(t)
+
+	zipPath := filepath.Join(t.TempDir(), "bundles.zip")
+
+	f, err := os.Create(zipPath)
+	require.NoError(err)
+
+	zw := zip.NewWriter(f)
+
+	fsys := fstest.MapFS{}
+
+	for name, data := range twoClusters {
+		w, err := zw.Create(name)
+		require.NoError(err)
+
+		_, err = w.Write([]byte(data))
+		require.NoError(err)
+
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+
+	require.NoError(zw.Close())
+	require.NoError(f.Close())
+
+	_, err = bundlereader.FromFS(fsys)
+	require.ErrorIs(err, bundlereader.ErrMultipleBundles)
+	require.ErrorContains(err, "cluster-a/2024-06-01T12-00, cluster-b/2024-06-02T08-30")
+
+	_, err = bundlereader.Open(zipPath)
+	require.ErrorIs(err, bundlereader.ErrMultipleBundles)
+
+	prefixes, err := bundlereader.Prefixes(zipPath)
+	require.NoError(err)
+	require.Equal([]string{"cluster-a/2024-06-01T12-00", "cluster-b/2024-06-02T08-30"}, prefixes)
+
+	nodes := map[string][]string{}
+
+	for _, prefix := range prefixes {
+		b, err := bundlereader.OpenPrefix(zipPath, prefix)
+		require.NoError(err)
+
+		for _, node := range b.Nodes() {
+			nodes[prefix] = append(nodes[prefix], node.Name)
+		}
+
+		data, err := b.ReadFile("metadata.yaml")
+		require.NoError(err)
+		require.Contains(string(data), path.Dir(prefix))
+
+		require.NoError(b.Close())
+	}
+
+	require.Equal(map[string][]string{
+		"cluster-a/2024-06-01T12-00": {"10.5.0.2"},
+		"cluster-b/2024-06-02T08-30": {"10.6.0.2", "10.6.0.3"},
+	}, nodes)
+
+	b, err := bundlereader.FromFSPrefix(fsys, "cluster-b/2024-06-02T08-30")
+	require.NoError(err)
+	require.True(b.Has("10.6.0.3/dmesg.log"))
+	require.False(b.Has("10.5.0.2/dmesg.log"))
+
+	_, err = bundlereader.FromFSPrefix(fsys, "cluster-c")
+	require.ErrorIs(err, fs.ErrNotExist)
+}
+
+func TestSingleClusterPrefix(t *testing.T) {
+	require := require.New(t)
+
+	fsys := fstest.MapFS{}
+
+	for name, data := range twoClusters {
+		if strings.HasPrefix(name, "cluster-a/") {
+			fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		}
+	}
+
+	// the only cluster is detected, the nested bundle is a part of it
+	b, err := bundlereader.FromFS(fsys)
+	require.NoError(err)
+	require.True(b.Has("10.5.0.2/dmesg.log"))
+	require.True(b.Has("10.5.0.2/attachments/metadata.yaml"))
+}
+
+func TestBundleReader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	bundlePath := filepath.Join(t.TempDir(), "support.zip")
+
+	out, err := os.Create(bundlePath)
+	require.NoError(err)
+
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	)
+
+	cols := append(collectors.GetKubernetesCollectors(client)[:1], collectors.WithNode([]*collectors.Collector{
+		collectors.NewCollector("service-logs/kubelet.log", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte("first\nsecond\n"), nil
+		}),
+		collectors.NewCollector("service-logs/kubelet.state", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte("STATE Running\n"), nil
+		}),
+		collectors.NewCollector("resources/members.cluster.talos.dev.yaml", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte("metadata:\n    namespace: cluster\n    type: Members.cluster.talos.dev\n    id: talos-cp-1\nspec:\n    hostname: talos-cp-1\n"), nil
+		}),
+	}, "10.5.0.2")...)
+
+	options := bundle.NewOptions(
+		bundle.WithArchiveOutput(out),
+		bundle.WithKubernetesClient(client),
+		bundle.WithLogOutput(io.Discard),
+		bundle.WithPathPrefix("cluster"),
+		bundle.WithMetadata(map[string]string{"ticket": "1234"}),
+	)
+
+	require.NoError(support.CreateSupportBundle(ctx, options, cols...))
+	require.NoError(out.Close())
+
+	b, err := bundlereader.Open(bundlePath)
+	require.NoError(err)
+
+	defer b.Close() //nolint:errcheck
+
+	require.Equal("1234", b.Metadata().Custom["ticket"])
+
+	manifest := b.Manifest()
+	require.NotNil(manifest)
+	require.Equal(bundle.ManifestSchemaVersion, manifest.SchemaVersion)
+	require.Equal(bundle.LayoutVersion, b.LayoutVersion())
+	require.Len(manifest.Collectors, len(cols))
+	require.Empty(manifest.Errors)
+	require.Contains(manifest.Files, bundle.ManifestEntry{
+		Path:   "10.5.0.2/service-logs/kubelet.log",
+		Size:   13,
+		SHA256: "dbea9325179efe46ea2add94f7b6b745ca983fabb208dc6d34aa064623d7ee23",
+	})
+
+	nodes, err := b.KubernetesNodes()
+	require.NoError(err)
+	require.Len(nodes.Items, 1)
+	require.Equal("node-1", nodes.Items[0].Name)
+
+	require.Len(b.Nodes(), 1)
+
+	node, ok := b.Node("10.5.0.2")
+	require.True(ok)
+
+	require.Equal([]string{"kubelet"}, node.Services())
+
+	var lines []string
+
+	require.NoError(b.Lines(node.Path("service-logs/kubelet.log"), func(line string) bool {
+		lines = append(lines, line)
+
+		return true
+	}))
+
+	require.Equal([]string{"first", "second"}, lines)
+
+	resources, err := node.Resources("members.cluster.talos.dev")
+	require.NoError(err)
+	require.Len(resources, 1)
+	require.Equal("talos-cp-1", resources[0].Metadata.ID)
+}
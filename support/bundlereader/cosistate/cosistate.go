@@ -0,0 +1,176 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package cosistate loads the Talos resources from the support bundles into the in-memory COSI state.
+//
+// It is kept out of the bundlereader package, so that reading the bundles doesn't pull in the COSI state implementation.
+package cosistate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/cosi-project/runtime/api/v1alpha1"
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/resource/protobuf"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// New loads the Talos resources collected from the node into the in-memory COSI state.
+//
+// The resources of the types registered in the COSI protobuf registry (e.g. by importing the Talos machinery resource
+// packages) are decoded into the typed resources, the other ones are loaded as *protobuf.Resource with the YAML spec,
+// the same way the Talos API client returns them. The resource metadata is preserved as collected.
+//
+// The state is writable, but the changes are kept in memory only.
+func New(node *bundlereader.Node) (state.State, error) { //nolint:ireturn
+	namespaces := map[resource.Namespace][]resource.Resource{}
+
+	for _, resourceType := range node.ResourceTypes() {
+		resources, err := decodeResources(node, resourceType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, res := range resources {
+			namespaces[res.Metadata().Namespace()] = append(namespaces[res.Metadata().Namespace()], res)
+		}
+	}
+
+	return state.WrapCore(namespaced.NewState(func(ns resource.Namespace) state.CoreState {
+		return inmem.NewStateWithOptions(inmem.WithBackingStore(&bundleStore{resources: namespaces[ns]}))(ns)
+	})), nil
+}
+
+func decodeResources(node *bundlereader.Node, resourceType string) ([]resource.Resource, error) {
+	data, err := node.ReadFile(path.Join("resources", resourceType+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []resource.Resource
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var doc yaml.Node
+
+		if err = decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resources, nil
+			}
+
+			return nil, fmt.Errorf("failed to parse resources %s of node %s: %w", resourceType, node.Name, err)
+		}
+
+		res, err := decodeResource(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode resource %s of node %s: %w", resourceType, node.Name, err)
+		}
+
+		resources = append(resources, res)
+	}
+}
+
+// decodeResource decodes the resource from the YAML document with the metadata and the spec.
+func decodeResource(doc *yaml.Node) (resource.Resource, error) { //nolint:ireturn
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected mapping node, got %d", doc.Kind)
+	}
+
+	var mdNode, specNode *yaml.Node
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		switch doc.Content[i].Value {
+		case "metadata":
+			mdNode = doc.Content[i+1]
+		case "spec":
+			specNode = doc.Content[i+1]
+		}
+	}
+
+	if mdNode == nil || specNode == nil {
+		return nil, errors.New("metadata or spec is missing")
+	}
+
+	var md resource.Metadata
+
+	if err := md.UnmarshalYAML(mdNode); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	if res, err := protobuf.CreateResource(md.Type()); err == nil {
+		*res.Metadata() = md
+
+		if err = specNode.Decode(res.Spec()); err != nil {
+			return nil, fmt.Errorf("failed to decode spec of %s: %w", md, err)
+		}
+
+		return res, nil
+	}
+
+	spec, err := yaml.Marshal(specNode)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := protobuf.Unmarshal(&v1alpha1.Resource{
+		Metadata: &v1alpha1.Metadata{
+			Namespace: md.Namespace(),
+			Type:      md.Type(),
+			Id:        md.ID(),
+			Version:   md.Version().String(),
+			Phase:     md.Phase().String(),
+		},
+		Spec: &v1alpha1.Spec{
+			YamlSpec: string(spec),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*res.Metadata() = md
+
+	return res, nil
+}
+
+// bundleStore is the read-only backing store which loads the bundle resources into the in-memory state.
+type bundleStore struct {
+	resources []resource.Resource
+}
+
+// Load implements inmem.BackingStore.
+func (s *bundleStore) Load(_ context.Context, handler inmem.LoadHandler) error {
+	for _, res := range s.resources {
+		if err := handler(res.Metadata().Type(), res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Put implements inmem.BackingStore.
+func (s *bundleStore) Put(context.Context, resource.Type, resource.Resource) error {
+	return nil
+}
+
+// Destroy implements inmem.BackingStore.
+func (s *bundleStore) Destroy(context.Context, resource.Type, resource.Pointer) error {
+	return nil
+}
@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cosistate_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/bundlereader/cosistate"
+)
+
+func TestBundleState(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml": {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"10.5.0.2/resources/examples.test.dev.yaml": {Data: []byte(`metadata:
+    namespace: test
+    type: Examples.test.dev
+    id: first
+    version: 3
+    owner: example.Controller
+    phase: running
+    created: 2024-01-01T00:00:00Z
+    updated: 2024-01-01T00:01:00Z
+    labels:
+        foo: bar
+spec:
+    value: 1
+---
+metadata:
+    namespace: test
+    type: Examples.test.dev
+    id: second
+    version: 1
+    owner: example.Controller
+    phase: running
+    created: 2024-01-01T00:00:00Z
+    updated: 2024-01-01T00:00:00Z
+spec:
+    value: 2
+`)},
+	})
+	require.NoError(err)
+
+	node, ok := b.Node("10.5.0.2")
+	require.True(ok)
+
+	st, err := cosistate.New(node)
+	require.NoError(err)
+
+	list, err := st.List(ctx, resource.NewMetadata("test", "Examples.test.dev", "", resource.VersionUndefined))
+	require.NoError(err)
+	require.Len(list.Items, 2)
+
+	res, err := st.Get(ctx, resource.NewMetadata("test", "Examples.test.dev", "first", resource.VersionUndefined))
+	require.NoError(err)
+
+	require.Equal("3", res.Metadata().Version().String())
+	require.Equal("example.Controller", res.Metadata().Owner())
+
+	foo, _ := res.Metadata().Labels().Get("foo")
+	require.Equal("bar", foo)
+
+	spec, err := res.Spec().(interface{ MarshalYAMLBytes() ([]byte, error) }).MarshalYAMLBytes()
+	require.NoError(err)
+	require.Equal("value: 1\n", string(spec))
+}
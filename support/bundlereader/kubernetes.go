@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// KubernetesResources returns the paths of the Kubernetes resource manifests relative to the Kubernetes resources directory,
+// e.g. `nodes.yaml` or `storage/persistentVolumes.yaml`.
+func (b *Bundle) KubernetesResources() []string {
+	var resources []string
+
+	for _, file := range b.files {
+		name, ok := strings.CutPrefix(file, KubernetesResourcesDir+"/")
+		if ok && strings.HasSuffix(name, ".yaml") {
+			resources = append(resources, name)
+		}
+	}
+
+	return resources
+}
+
+// DecodeKubernetesObject decodes the Kubernetes resource manifest from the Kubernetes resources directory into the object,
+// e.g. `nodes.yaml` into corev1.NodeList.
+//
+// The manifests written by the typed clients have no kind set, so the object type has to be known by the caller.
+func (b *Bundle) DecodeKubernetesObject(name string, obj any) error {
+	data, err := b.ReadFile(path.Join(KubernetesResourcesDir, name))
+	if err != nil {
+		return err
+	}
+
+	if err = utilyaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// KubernetesObject decodes the Kubernetes resource manifest from the Kubernetes resources directory without the schema.
+func (b *Bundle) KubernetesObject(name string) (*unstructured.Unstructured, error) {
+	var obj map[string]any
+
+	if err := b.DecodeKubernetesObject(name, &obj); err != nil {
+		return nil, err
+	}
+
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// KubernetesNodes decodes the Kubernetes nodes.
+func (b *Bundle) KubernetesNodes() (*corev1.NodeList, error) {
+	var nodes corev1.NodeList
+
+	return &nodes, b.DecodeKubernetesObject("nodes.yaml", &nodes)
+}
+
+// KubernetesSystemPods decodes the pods from the kube-system namespace.
+func (b *Bundle) KubernetesSystemPods() (*corev1.PodList, error) {
+	var pods corev1.PodList
+
+	return &pods, b.DecodeKubernetesObject("systemPods.yaml", &pods)
+}
+
+// KubernetesEvents decodes the Kubernetes events.
+func (b *Bundle) KubernetesEvents() (*corev1.EventList, error) {
+	var events corev1.EventList
+
+	return &events, b.DecodeKubernetesObject("events.yaml", &events)
+}
@@ -0,0 +1,137 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// ErrUnsupportedLayout is returned when the bundle was written with the newer file layout than the reader supports.
+var ErrUnsupportedLayout = errors.New("unsupported bundle layout")
+
+// layoutRename is the file or directory renamed in the layout version.
+type layoutRename struct {
+	// from and to are the old and the new paths, the directory paths end with the slash
+	from, to string
+	// node is set if the paths are relative to the node directories
+	node bool
+}
+
+// layoutRenames are the renames made in each layout version, the key is the version the paths were renamed in.
+//
+// Layout 1 is the first versioned layout, the older bundles use the same paths, so there are no renames yet.
+var layoutRenames = map[int][]layoutRename{}
+
+// LayoutVersion returns the version of the bundle file layout the bundle was created with.
+//
+// Zero is returned for the bundles created before the layout was versioned. The paths used by the bundle methods
+// are always in the current layout (bundle.LayoutVersion), the paths of the older layouts are mapped to the current ones.
+func (b *Bundle) LayoutVersion() int {
+	return b.layout
+}
+
+// detectLayout finds the bundle layout version in the manifest or the metadata.
+func (b *Bundle) detectLayout() error {
+	switch {
+	case b.manifest != nil && b.manifest.LayoutVersion > 0:
+		b.layout = b.manifest.LayoutVersion
+	case b.metadata != nil:
+		b.layout = b.metadata.LayoutVersion
+	}
+
+	if b.layout > bundle.LayoutVersion {
+		return fmt.Errorf("%w: version %d, supported up to %d", ErrUnsupportedLayout, b.layout, bundle.LayoutVersion)
+	}
+
+	return nil
+}
+
+// upgradeLayout maps the file paths of the older layout to the current layout.
+func (b *Bundle) upgradeLayout() {
+	for version := b.layout + 1; version <= bundle.LayoutVersion; version++ {
+		renames := layoutRenames[version]
+		if len(renames) == 0 {
+			continue
+		}
+
+		for i, file := range b.files {
+			renamed := upgradePath(file, renames)
+			if renamed == file {
+				continue
+			}
+
+			if b.renamed == nil {
+				b.renamed = map[string]string{}
+			}
+
+			original, ok := b.renamed[file]
+			if !ok {
+				original = file
+			}
+
+			delete(b.renamed, file)
+
+			b.renamed[renamed] = original
+			b.files[i] = renamed
+		}
+
+		slices.Sort(b.files)
+	}
+}
+
+// upgradePath applies the first matching rename to the path.
+func upgradePath(p string, renames []layoutRename) string {
+	for _, rename := range renames {
+		dir, rest := "", p
+
+		if rename.node {
+			var ok bool
+
+			if dir, rest, ok = strings.Cut(p, "/"); !ok || dir == KubernetesResourcesDir {
+				continue
+			}
+		}
+
+		var renamed string
+
+		switch {
+		case strings.HasSuffix(rename.from, "/"):
+			tail, ok := strings.CutPrefix(rest, rename.from)
+			if !ok {
+				continue
+			}
+
+			renamed = rename.to + tail
+		case rest == rename.from:
+			renamed = rename.to
+		default:
+			continue
+		}
+
+		if rename.node {
+			return path.Join(dir, renamed)
+		}
+
+		return renamed
+	}
+
+	return p
+}
+
+// archivePath returns the path of the file in the underlying filesystem, the renamed paths of the older layouts
+// are mapped back to the original ones.
+func (b *Bundle) archivePath(p string) string {
+	if original, ok := b.renamed[p]; ok {
+		p = original
+	}
+
+	return path.Join(b.prefix, p)
+}
@@ -0,0 +1,36 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+func TestBundleLayout(t *testing.T) {
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":      {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"10.5.0.2/dmesg.log": {Data: []byte("dmesg\n")},
+	})
+	require.NoError(err)
+	require.Zero(b.LayoutVersion())
+
+	data, err := b.ReadFile("10.5.0.2/dmesg.log")
+	require.NoError(err)
+	require.Equal("dmesg\n", string(data))
+
+	_, err = bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml": {Data: []byte(fmt.Sprintf("created: 2024-01-01T00:00:00Z\nlayoutVersion: %d\n", bundle.LayoutVersion+1))},
+	})
+	require.ErrorIs(err, bundlereader.ErrUnsupportedLayout)
+}
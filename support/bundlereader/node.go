@@ -0,0 +1,183 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Node is the Talos node directory in the bundle.
+type Node struct {
+	bundle *Bundle
+	Name   string
+	files  []string
+}
+
+// Resource is the Talos resource from the node resources directory.
+type Resource struct {
+	Spec     any              `yaml:"spec"`
+	Metadata ResourceMetadata `yaml:"metadata"`
+}
+
+// ResourceMetadata is the Talos resource metadata.
+type ResourceMetadata struct {
+	Created     time.Time         `yaml:"created"`
+	Updated     time.Time         `yaml:"updated"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Namespace   string            `yaml:"namespace"`
+	Type        string            `yaml:"type"`
+	ID          string            `yaml:"id"`
+	Version     string            `yaml:"version"`
+	Owner       string            `yaml:"owner"`
+	Phase       string            `yaml:"phase"`
+}
+
+// Files returns the paths of the node files relative to the node directory.
+func (n *Node) Files() []string {
+	return n.files
+}
+
+// Path returns the path of the node file in the bundle.
+func (n *Node) Path(name string) string {
+	return path.Join(n.Name, name)
+}
+
+// Open opens the node file.
+func (n *Node) Open(name string) (io.ReadCloser, error) {
+	return n.bundle.Open(n.Path(name))
+}
+
+// ReadFile reads the node file.
+func (n *Node) ReadFile(name string) ([]byte, error) {
+	return n.bundle.ReadFile(n.Path(name))
+}
+
+// Services returns the IDs of the services which have logs or state in the bundle.
+func (n *Node) Services() []string {
+	var services []string
+
+	for _, file := range n.files {
+		name, ok := strings.CutPrefix(file, "service-logs/")
+		if !ok {
+			continue
+		}
+
+		if id, ok := strings.CutSuffix(name, ".state"); ok {
+			services = append(services, id)
+		}
+	}
+
+	return services
+}
+
+// ServiceLog reads the service log.
+func (n *Node) ServiceLog(id string) ([]byte, error) {
+	return n.ReadFile(path.Join("service-logs", id+".log"))
+}
+
+// ServiceState reads the service state.
+func (n *Node) ServiceState(id string) ([]byte, error) {
+	return n.ReadFile(path.Join("service-logs", id+".state"))
+}
+
+// ServiceStatus reads and parses the service state.
+func (n *Node) ServiceStatus(id string) (*ServiceStatus, error) {
+	data, err := n.ServiceState(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseServiceStatus(data), nil
+}
+
+// TalosVersion returns the Talos version tag of the node from the node summary.
+//
+// The empty string is returned if the summary has no server version.
+func (n *Node) TalosVersion() (string, error) {
+	data, err := n.ReadFile("summary")
+	if err != nil {
+		return "", err
+	}
+
+	_, server, ok := strings.Cut(string(data), "Server:\n")
+	if !ok {
+		return "", nil
+	}
+
+	for _, line := range strings.Split(server, "\n") {
+		if tag, ok := strings.CutPrefix(strings.TrimSpace(line), "Tag:"); ok {
+			return strings.TrimSpace(tag), nil
+		}
+	}
+
+	return "", nil
+}
+
+// KubernetesLogs returns the paths of the Kubernetes container logs relative to the node directory.
+func (n *Node) KubernetesLogs() []string {
+	var logs []string
+
+	for _, file := range n.files {
+		if strings.HasPrefix(file, "kubernetes-logs/") && strings.HasSuffix(file, ".log") {
+			logs = append(logs, file)
+		}
+	}
+
+	return logs
+}
+
+// ResourceTypes returns the resource definition IDs of the Talos resources collected from the node,
+// e.g. `members.cluster.talos.dev`.
+func (n *Node) ResourceTypes() []string {
+	var types []string
+
+	for _, file := range n.files {
+		name, ok := strings.CutPrefix(file, "resources/")
+		if !ok {
+			continue
+		}
+
+		if id, ok := strings.CutSuffix(name, ".yaml"); ok {
+			types = append(types, id)
+		}
+	}
+
+	return types
+}
+
+// Resources parses the Talos resources of the type, the type is the resource definition ID.
+func (n *Node) Resources(resourceType string) ([]Resource, error) {
+	data, err := n.ReadFile(path.Join("resources", resourceType+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var res Resource
+
+		if err = decoder.Decode(&res); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resources, nil
+			}
+
+			return nil, fmt.Errorf("failed to parse resources %s of node %s: %w", resourceType, n.Name, err)
+		}
+
+		resources = append(resources, res)
+	}
+}
@@ -0,0 +1,209 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// binaryDetectSize is the size of the file head checked for the NUL bytes to detect the binary files.
+const binaryDetectSize = 8000
+
+// SearchOptions configures the bundle search.
+type SearchOptions struct {
+	// Nodes limits the search to the files of the nodes, the cluster wide files are skipped if set.
+	Nodes []string
+	// Paths limits the search to the files matching any of the path.Match patterns, the pattern ending
+	// with `/**` matches all files in the directory recursively.
+	Paths []string
+	// Context is the number of the lines before and after the match included in the result.
+	Context int
+	// MaxMatches stops the search after the number of the matches, zero means no limit.
+	MaxMatches int
+	// IgnoreCase makes the pattern case insensitive.
+	IgnoreCase bool
+	// Literal treats the pattern as the plain text instead of the regular expression.
+	Literal bool
+}
+
+// Match is the line matching the search pattern.
+type Match struct {
+	Path string
+	// Node is the node the file belongs to, empty for the cluster wide files.
+	Node   string
+	Text   string
+	Before []string
+	After  []string
+	// Line is the line number starting from 1.
+	Line int
+}
+
+// String implements fmt.Stringer.
+func (m Match) String() string {
+	return fmt.Sprintf("%s:%d: %s", m.Path, m.Line, m.Text)
+}
+
+// Search finds the lines matching the regular expression in the text files of the bundle.
+//
+// The binary files (e.g. the etcd snapshot) are skipped. The matches are ordered by the file path and the line number.
+func (b *Bundle) Search(pattern string, opts SearchOptions) ([]Match, error) {
+	if opts.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	var matches []Match
+
+	for _, file := range b.files {
+		if !b.searchable(file, opts) {
+			continue
+		}
+
+		limit := 0
+		if opts.MaxMatches > 0 {
+			limit = opts.MaxMatches - len(matches)
+		}
+
+		fileMatches, err := b.searchFile(file, re, opts.Context, limit)
+		if err != nil {
+			return matches, fmt.Errorf("failed to search %s: %w", file, err)
+		}
+
+		matches = append(matches, fileMatches...)
+
+		if opts.MaxMatches > 0 && len(matches) >= opts.MaxMatches {
+			break
+		}
+	}
+
+	return matches, nil
+}
+
+func (b *Bundle) searchable(file string, opts SearchOptions) bool {
+	if len(opts.Nodes) > 0 && !slices.Contains(opts.Nodes, b.fileNode(file)) {
+		return false
+	}
+
+	if len(opts.Paths) > 0 && !slices.ContainsFunc(opts.Paths, func(pattern string) bool {
+		return bundle.MatchPath(pattern, file)
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// fileNode returns the node the file belongs to or the empty string for the cluster wide files.
+func (b *Bundle) fileNode(file string) string {
+	dir, _, ok := strings.Cut(file, "/")
+	if !ok {
+		return ""
+	}
+
+	if _, isNode := b.nodes[dir]; !isNode {
+		return ""
+	}
+
+	return dir
+}
+
+func (b *Bundle) searchFile(file string, re *regexp.Regexp, context, limit int) ([]Match, error) {
+	r, err := b.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	reader := bufio.NewReaderSize(r, 2*binaryDetectSize)
+
+	head, err := reader.Peek(binaryDetectSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var (
+		matches []Match
+		before  []string
+		// pending are the indexes of the matches waiting for the context lines after them
+		pending []int
+	)
+
+	node := b.fileNode(file)
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadString('\n')
+		if line == "" && readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return matches, nil
+			}
+
+			return matches, readErr
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		pending = slices.DeleteFunc(pending, func(idx int) bool {
+			matches[idx].After = append(matches[idx].After, line)
+
+			return len(matches[idx].After) == context
+		})
+
+		if re.MatchString(line) && (limit == 0 || len(matches) < limit) {
+			matches = append(matches, Match{
+				Path:   file,
+				Node:   node,
+				Line:   lineNumber,
+				Text:   line,
+				Before: slices.Clone(before),
+			})
+
+			if context > 0 {
+				pending = append(pending, len(matches)-1)
+			}
+		}
+
+		if limit > 0 && len(matches) == limit && len(pending) == 0 {
+			return matches, nil
+		}
+
+		if context > 0 {
+			if len(before) == context {
+				before = slices.Delete(before, 0, 1)
+			}
+
+			before = append(before, line)
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return matches, nil
+			}
+
+			return matches, readErr
+		}
+	}
+}
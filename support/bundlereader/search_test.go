@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+func TestSearch(t *testing.T) {
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                   {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"10.5.0.2/service-logs/etcd.log":  {Data: []byte("one\ntwo\nERROR three\nfour\nfive\nerror six\n")},
+		"10.5.0.3/service-logs/etcd.log":  {Data: []byte("error seven\n")},
+		"10.5.0.3/etcd.snapshot":          {Data: []byte("error\x00binary")},
+		"kubernetesResources/events.yaml": {Data: []byte("error eight\n")},
+	})
+	require.NoError(err)
+
+	matches, err := b.Search("error", bundlereader.SearchOptions{
+		Nodes:      []string{"10.5.0.2"},
+		Paths:      []string{"*/service-logs/**"},
+		Context:    1,
+		IgnoreCase: true,
+	})
+	require.NoError(err)
+
+	require.Equal([]bundlereader.Match{
+		{Path: "10.5.0.2/service-logs/etcd.log", Node: "10.5.0.2", Line: 3, Text: "ERROR three", Before: []string{"two"}, After: []string{"four"}},
+		{Path: "10.5.0.2/service-logs/etcd.log", Node: "10.5.0.2", Line: 6, Text: "error six", Before: []string{"five"}},
+	}, matches)
+
+	matches, err = b.Search("error", bundlereader.SearchOptions{})
+	require.NoError(err)
+	require.Len(matches, 3)
+
+	matches, err = b.Search("error", bundlereader.SearchOptions{MaxMatches: 2})
+	require.NoError(err)
+	require.Len(matches, 2)
+}
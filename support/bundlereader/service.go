@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ServiceStatus is the Talos service state from the `service-logs/<id>.state` node file.
+type ServiceStatus struct {
+	ID            string
+	State         string
+	Health        string
+	HealthMessage string
+	// Events are ordered from the newest to the oldest.
+	Events []ServiceEvent
+}
+
+// ServiceEvent is the service state change.
+type ServiceEvent struct {
+	State   string
+	Message string
+	// Age is the time elapsed since the event when the bundle was collected, zero if unknown.
+	Age time.Duration
+}
+
+// Failing checks if the service is failed or unhealthy.
+func (s *ServiceStatus) Failing() bool {
+	return s.State == "Failed" || s.Health == "Fail"
+}
+
+// eventAgeRe matches the event age appended by the formatter, e.g. ` (1m2s ago)`.
+var eventAgeRe = regexp.MustCompile(` \(([^()]*) ago\)$`)
+
+// ParseServiceStatus parses the service state rendered by the Talos services formatter.
+func ParseServiceStatus(data []byte) *ServiceStatus {
+	status := &ServiceStatus{}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "LAST HEALTH MESSAGE"):
+			status.HealthMessage = strings.TrimSpace(strings.TrimPrefix(line, "LAST HEALTH MESSAGE"))
+		case strings.HasPrefix(line, "ID "):
+			status.ID = strings.TrimSpace(strings.TrimPrefix(line, "ID"))
+		case strings.HasPrefix(line, "STATE "):
+			status.State = strings.TrimSpace(strings.TrimPrefix(line, "STATE"))
+		case strings.HasPrefix(line, "HEALTH "):
+			status.Health = strings.TrimSpace(strings.TrimPrefix(line, "HEALTH"))
+		default:
+			// the event lines: the first one is labeled `EVENTS`, the following ones are not labeled
+			event, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(line, "EVENTS")), "[")
+			if !ok {
+				continue
+			}
+
+			state, message, ok := strings.Cut(event, "]: ")
+			if !ok {
+				continue
+			}
+
+			serviceEvent := ServiceEvent{
+				State:   state,
+				Message: eventAgeRe.ReplaceAllString(message, ""),
+			}
+
+			if match := eventAgeRe.FindStringSubmatch(message); match != nil {
+				serviceEvent.Age, _ = time.ParseDuration(match[1]) //nolint:errcheck
+			}
+
+			status.Events = append(status.Events, serviceEvent)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,120 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// readTar reads the optionally gzipped tar archive into memory.
+func readTar(r io.Reader) (fs.FS, []string, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r = br
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		defer gz.Close() //nolint:errcheck
+
+		r = gz
+	}
+
+	var (
+		files = memFS{}
+		paths []string
+	)
+
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return files, paths, nil
+			}
+
+			return nil, nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := path.Clean(hdr.Name)
+
+		files[name] = &memFile{
+			name:    path.Base(name),
+			modTime: hdr.ModTime,
+			data:    data,
+		}
+
+		paths = append(paths, name)
+	}
+}
+
+// memFS is the read-only in-memory file system containing only the files.
+type memFS map[string]*memFile
+
+// Open implements fs.FS.
+func (m memFS) Open(name string) (fs.File, error) {
+	f, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return &openMemFile{
+		Reader:  bytes.NewReader(f.data),
+		memFile: f,
+	}, nil
+}
+
+type memFile struct {
+	modTime time.Time
+	name    string
+	data    []byte
+}
+
+type openMemFile struct {
+	*bytes.Reader
+	*memFile
+}
+
+func (f *openMemFile) Stat() (fs.FileInfo, error) { return f, nil }
+
+func (f *openMemFile) Close() error { return nil }
+
+func (f *openMemFile) Name() string { return f.name }
+
+func (f *openMemFile) Size() int64 { return int64(len(f.data)) }
+
+func (f *openMemFile) Mode() fs.FileMode { return 0o444 }
+
+func (f *openMemFile) ModTime() time.Time { return f.modTime }
+
+func (f *openMemFile) IsDir() bool { return false }
+
+func (f *openMemFile) Sys() any { return nil }
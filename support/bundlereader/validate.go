@@ -0,0 +1,189 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// AllNodes is the Artifact node which expects the artifact for every node in the bundle.
+const AllNodes = "*"
+
+// Hints for the default artifacts.
+const (
+	talosHint      = "collect with the Talos API access, make sure the talosconfig is set and bundle.WithoutTalos is not used"
+	profileHint    = "collect with the default or full profile (bundle.WithProfile), the minimal profile skips the logs and the node state"
+	kubernetesHint = "collect with the Kubernetes API access: set the kubeconfig (bundle.WithKubeconfig) or use bundle.WithKubernetesClientFromTalos, " +
+		"make sure bundle.WithoutKubernetes is not used"
+)
+
+// Artifact is the file expected in the bundle.
+type Artifact struct {
+	// Path is relative to the node directory for the node artifacts or to the bundle root for the cluster wide ones.
+	Path string
+	// Node is the node the artifact is expected for, AllNodes for every node, empty for the cluster wide artifacts.
+	Node string
+	// Hint explains how to collect the artifact if it is missing, e.g. the options to re-run the collection with.
+	Hint string
+}
+
+// DefaultArtifacts returns the artifacts every bundle collected with the default options is expected to have.
+func DefaultArtifacts() []Artifact {
+	return []Artifact{
+		{Path: "summary", Node: AllNodes, Hint: talosHint},
+		{Path: "dmesg.log", Node: AllNodes, Hint: profileHint},
+		{Path: "controller-runtime.log", Node: AllNodes, Hint: profileHint},
+		{Path: "mounts", Node: AllNodes, Hint: profileHint},
+		{Path: KubernetesResourcesDir + "/nodes.yaml", Hint: kubernetesHint},
+		{Path: KubernetesResourcesDir + "/systemPods.yaml", Hint: kubernetesHint},
+	}
+}
+
+// Issue is the expected artifact which is missing from the bundle or empty.
+type Issue struct {
+	// Path is the path of the artifact relative to the bundle root.
+	Path string
+	Node string
+	Hint string
+	// Error is the collector error recorded in the bundle manifest, if any.
+	Error string
+	// Empty is set if the file is in the bundle, but it has no data.
+	Empty bool
+}
+
+// String implements fmt.Stringer.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s is %s", i.Path, i.problem())
+}
+
+func (i Issue) problem() string {
+	problem := "missing"
+	if i.Empty {
+		problem = "empty"
+	}
+
+	if i.Error != "" {
+		problem += ": " + i.Error
+	}
+
+	return problem
+}
+
+// ValidationResult is the bundle completeness report.
+type ValidationResult struct {
+	Issues []Issue
+	// Checked is the number of the artifacts checked.
+	Checked int
+}
+
+// Complete checks if all expected artifacts are present.
+func (r *ValidationResult) Complete() bool {
+	return len(r.Issues) == 0
+}
+
+// Write renders the issues as a table.
+func (r *ValidationResult) Write(w io.Writer) error {
+	if r.Complete() {
+		_, err := fmt.Fprintf(w, "all %d expected artifacts are present\n", r.Checked)
+
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPATH\tPROBLEM\tHINT") //nolint:errcheck
+
+	for _, issue := range r.Issues {
+		node := issue.Node
+		if node == "" {
+			node = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", node, issue.Path, issue.problem(), issue.Hint) //nolint:errcheck
+	}
+
+	return tw.Flush()
+}
+
+// Validate checks that the expected artifacts are in the bundle and are not empty.
+//
+// The artifacts expected for every node are checked in each node directory, if the bundle has no nodes at all,
+// they are reported missing for AllNodes. The collector errors from the bundle manifest are attached to the issues
+// to explain why the artifact is missing.
+func Validate(b *Bundle, expected []Artifact) *ValidationResult {
+	res := &ValidationResult{}
+
+	collectorErrors := map[string]string{}
+
+	if b.manifest != nil {
+		for _, collector := range b.manifest.Collectors {
+			if collector.Error != "" {
+				collectorErrors[collector.Path] = collector.Error
+			}
+		}
+	}
+
+	check := func(artifact Artifact, node string) {
+		p := artifact.Path
+		if node != "" {
+			p = path.Join(node, artifact.Path)
+		}
+
+		res.Checked++
+
+		issue := Issue{
+			Path:  p,
+			Node:  node,
+			Hint:  artifact.Hint,
+			Error: collectorErrors[p],
+		}
+
+		if b.Has(p) {
+			size, err := b.Size(p)
+			if err != nil || size > 0 {
+				return
+			}
+
+			issue.Empty = true
+		}
+
+		res.Issues = append(res.Issues, issue)
+	}
+
+	for _, artifact := range expected {
+		switch artifact.Node {
+		case "":
+			check(artifact, "")
+		case AllNodes:
+			if len(b.nodes) == 0 {
+				res.Checked++
+
+				res.Issues = append(res.Issues, Issue{
+					Path: artifact.Path,
+					Node: AllNodes,
+					Hint: artifact.Hint,
+				})
+
+				continue
+			}
+
+			for _, node := range b.Nodes() {
+				check(artifact, node.Name)
+			}
+		default:
+			check(artifact, artifact.Node)
+		}
+	}
+
+	slices.SortStableFunc(res.Issues, func(a, b Issue) int {
+		return strings.Compare(a.Node, b.Node)
+	})
+
+	return res
+}
@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bundlereader_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support"
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+func TestBundleValidate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	archive := &testArchive{}
+
+	cols := collectors.WithNode([]*collectors.Collector{
+		collectors.NewCollector("summary", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte("Server:\n\tTag: v1.8.0\n"), nil
+		}),
+		collectors.NewCollector("dmesg.log", func(context.Context, *bundle.Options) ([]byte, error) {
+			return nil, errors.New("permission denied")
+		}),
+		collectors.NewCollector("mounts", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte{}, nil
+		}),
+	}, "10.5.0.2")
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(support.CreateSupportBundle(ctx, options, cols...))
+
+	fsys := fstest.MapFS{}
+
+	for name, data := range archive.files {
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+
+	b, err := bundlereader.FromFS(fsys)
+	require.NoError(err)
+
+	res := bundlereader.Validate(b, support.Artifacts(cols))
+	require.Equal(3, res.Checked)
+	require.False(res.Complete())
+	require.Equal([]bundlereader.Issue{
+		{Path: "10.5.0.2/dmesg.log", Node: "10.5.0.2", Error: "permission denied"},
+		{Path: "10.5.0.2/mounts", Node: "10.5.0.2", Empty: true},
+	}, res.Issues)
+
+	res = bundlereader.Validate(b, bundlereader.DefaultArtifacts())
+
+	issues := make([]string, 0, len(res.Issues))
+
+	for _, issue := range res.Issues {
+		issues = append(issues, issue.String())
+	}
+
+	require.Equal([]string{
+		"kubernetesResources/nodes.yaml is missing",
+		"kubernetesResources/systemPods.yaml is missing",
+		"10.5.0.2/dmesg.log is missing: permission denied",
+		"10.5.0.2/controller-runtime.log is missing",
+		"10.5.0.2/mounts is empty",
+	}, issues)
+
+	var buf bytes.Buffer
+
+	require.NoError(res.Write(&buf))
+	assert.Contains(t, buf.String(), "bundle.WithKubeconfig")
+}
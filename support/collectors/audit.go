@@ -0,0 +1,55 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// audited makes the API call needed to create the collectors and records it in the audit log.
+//
+// The name is the category of the call, the audit entry has no path as nothing is written to the archive.
+// The node is taken from the Talos client context, the call is a cluster one if the context has no node.
+func audited[T any](ctx context.Context, options *bundle.Options, name string, call func(ctx context.Context) (T, error)) (T, error) {
+	start, started := time.Now(), options.Now()
+
+	result, err := call(ctx)
+
+	entry := bundle.AuditEntry{
+		Time:      started,
+		Node:      contextNode(ctx),
+		Collector: name,
+		Outcome:   bundle.AuditOutcomeOK,
+		Duration:  time.Since(start),
+	}
+
+	if err != nil {
+		entry.Outcome = bundle.AuditOutcomeFailed
+		entry.Error = err.Error()
+	}
+
+	options.RecordAudit(entry)
+
+	return result, err
+}
+
+// contextNode returns the node set by client.WithNode.
+func contextNode(ctx context.Context) string {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if nodes := md.Get("node"); len(nodes) > 0 {
+		return nodes[0]
+	}
+
+	return ""
+}
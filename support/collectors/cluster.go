@@ -0,0 +1,185 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// versionSkew reports Talos and Kubernetes component versions and checks them against the Kubernetes version skew policy.
+func versionSkew(ctx context.Context, options *bundle.Options) ([]byte, error) {
+	options.Log("checking version skew")
+
+	var (
+		buf      bytes.Buffer
+		problems []string
+	)
+
+	if options.CollectsTalos() {
+		fmt.Fprintln(&buf, "Talos:")
+
+		resp, err := options.TalosClient.Version(client.WithNodes(ctx, options.Nodes...))
+		if err != nil {
+			fmt.Fprintf(&buf, "error: %s\n", err)
+		}
+
+		w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NODE\tVERSION") //nolint:errcheck
+
+		talosVersions := map[string]struct{}{}
+
+		for _, msg := range resp.GetMessages() {
+			talosVersions[msg.GetVersion().GetTag()] = struct{}{}
+
+			fmt.Fprintf(w, "%s\t%s\n", msg.GetMetadata().GetHostname(), msg.GetVersion().GetTag()) //nolint:errcheck
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		if len(talosVersions) > 1 {
+			problems = append(problems, "nodes are running different Talos versions")
+		}
+
+		fmt.Fprintln(&buf)
+	}
+
+	if options.CollectsKubernetes() {
+		kubernetesProblems, err := kubernetesVersionSkew(ctx, options, &buf)
+		if err != nil {
+			return nil, err
+		}
+
+		problems = append(problems, kubernetesProblems...)
+	}
+
+	fmt.Fprintln(&buf, "Problems:")
+
+	if len(problems) == 0 {
+		fmt.Fprintln(&buf, "no unsupported version skew detected")
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(&buf, "- %s\n", problem)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func kubernetesVersionSkew(ctx context.Context, options *bundle.Options, buf *bytes.Buffer) ([]string, error) {
+	var problems []string
+
+	componentVersions := map[string][]*version.Version{}
+
+	fmt.Fprintln(buf, "Kubernetes control plane:")
+
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tNODE\tVERSION") //nolint:errcheck
+
+	for _, component := range controlPlaneComponents {
+		pods, err := listControlPlanePods(ctx, options, options.KubernetesClient, component)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pod := range pods.Items {
+			tag := podImageVersion(&pod, component)
+
+			fmt.Fprintf(w, "%s\t%s\t%s\n", component, pod.Spec.NodeName, tag) //nolint:errcheck
+
+			if v, parseErr := version.ParseGeneric(tag); parseErr == nil {
+				componentVersions[component] = append(componentVersions[component], v)
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := listNodes(ctx, options, options.KubernetesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(buf, "\nKubelets:")
+
+	w = tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tVERSION") //nolint:errcheck
+
+	for _, node := range nodes.Items {
+		fmt.Fprintf(w, "%s\t%s\n", node.Name, node.Status.NodeInfo.KubeletVersion) //nolint:errcheck
+
+		if v, parseErr := version.ParseGeneric(node.Status.NodeInfo.KubeletVersion); parseErr == nil {
+			componentVersions["kubelet"] = append(componentVersions["kubelet"], v)
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(buf)
+
+	apiServers := componentVersions["kube-apiserver"]
+	if len(apiServers) == 0 {
+		return problems, nil
+	}
+
+	oldest, newest := minMaxVersions(apiServers)
+
+	if newest.Minor()-oldest.Minor() > 1 {
+		problems = append(problems, fmt.Sprintf("kube-apiserver instances differ by more than one minor version: %s - %s", oldest, newest))
+	}
+
+	// https://kubernetes.io/releases/version-skew-policy/
+	for _, policy := range []struct {
+		component    string
+		maxMinorSkew uint
+	}{
+		{component: "kube-controller-manager", maxMinorSkew: 1},
+		{component: "kube-scheduler", maxMinorSkew: 1},
+		{component: "kubelet", maxMinorSkew: 3},
+	} {
+		component, maxMinorSkew := policy.component, policy.maxMinorSkew
+
+		for _, v := range componentVersions[component] {
+			if v.Major() != oldest.Major() || v.Minor() > oldest.Minor() {
+				problems = append(problems, fmt.Sprintf("%s %s is newer than the oldest kube-apiserver %s", component, v, oldest))
+			}
+
+			if v.Major() == newest.Major() && v.Minor()+maxMinorSkew < newest.Minor() {
+				problems = append(problems, fmt.Sprintf("%s %s is more than %d minor versions older than kube-apiserver %s", component, v, maxMinorSkew, newest))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+func minMaxVersions(versions []*version.Version) (oldest, newest *version.Version) {
+	oldest, newest = versions[0], versions[0]
+
+	for _, v := range versions[1:] {
+		if v.LessThan(oldest) {
+			oldest = v
+		}
+
+		if newest.LessThan(v) {
+			newest = v
+		}
+	}
+
+	return oldest, newest
+}
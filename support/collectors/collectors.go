@@ -6,20 +6,38 @@
 package collectors
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/cosi-project/runtime/pkg/resource/meta"
 	"github.com/cosi-project/runtime/pkg/safe"
 	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/siderolabs/talos/pkg/machinery/api/common"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
 	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
 	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"github.com/siderolabs/talos/pkg/machinery/resources/cluster"
+	"github.com/siderolabs/talos/pkg/machinery/resources/config"
+	"google.golang.org/grpc/codes"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/redact"
 )
 
 // Cluster is the const for the top level cluster collectors.
@@ -28,146 +46,1107 @@ const Cluster = "cluster"
 // Collect defines a single collect call which returns data blob to be written in the file.
 type Collect func(ctx context.Context, options *bundle.Options) ([]byte, error)
 
+// StreamCollect defines a single collect call which writes the data to be written in the file to the writer.
+//
+// It is used for the large files. The data is piped to the archive as it's written if the archive supports streaming
+// and the file is not redacted, scanned or truncated, otherwise it's buffered and spilled to disk if the temporary
+// directory is set by bundle.WithTempDir.
+type StreamCollect func(ctx context.Context, options *bundle.Options, w io.Writer) error
+
 // Collector unifies implementation of a the data collector with it's path in the archive.
 type Collector struct {
 	collect         Collect
+	stream          StreamCollect
 	source          string
 	destinationPath string
+	name            string
+	talosResource   bool
+}
+
+// NewCollector creates new collector.
+//
+// The collector name is derived from the path, it can be replaced with WithName.
+func NewCollector(path string, c Collect) *Collector {
+	return &Collector{
+		source:          Cluster,
+		destinationPath: path,
+		name:            pathName(path),
+		collect:         c,
+	}
+}
+
+// NewStreamCollector creates new collector which streams the data to the archive.
+//
+// The file is not written if the collector produces no data.
+func NewStreamCollector(path string, c StreamCollect) *Collector {
+	return &Collector{
+		source:          Cluster,
+		destinationPath: path,
+		name:            pathName(path),
+		stream:          c,
+	}
+}
+
+// pathName derives the collector name from the path: `kube-system/kubelet.log` is named `kube-system.kubelet`.
+func pathName(path string) string {
+	path = filepath.ToSlash(path)
+
+	return strings.ReplaceAll(strings.TrimSuffix(path, filepath.Ext(path)), "/", ".")
+}
+
+// NewTalosResourceCollector creates new collector which dumps the Talos resources of the type.
+//
+// The resource collectors are run by the separate pool of workers if it's set by bundle.WithResourceWorkers.
+func NewTalosResourceCollector(rd *meta.ResourceDefinition) *Collector {
+	return &Collector{
+		source:          Cluster,
+		destinationPath: fmt.Sprintf("%s.yaml", rd.Metadata().ID()),
+		name:            "talos.resource." + rd.TypedSpec().Type,
+		stream:          talosResource(rd),
+		talosResource:   true,
+	}
+}
+
+// wrap runs the middleware before the collect call, the middleware can replace the context and the options.
+func (c *Collector) wrap(middleware func(ctx context.Context, options *bundle.Options) (context.Context, *bundle.Options, error)) {
+	if c.stream != nil {
+		streamFunc := c.stream
+
+		c.stream = func(ctx context.Context, options *bundle.Options, w io.Writer) error {
+			ctx, options, err := middleware(ctx, options)
+			if err != nil {
+				return err
+			}
+
+			return streamFunc(ctx, options, w)
+		}
+
+		return
+	}
+
+	collectFunc := c.collect
+
+	c.collect = func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		ctx, options, err := middleware(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return collectFunc(ctx, options)
+	}
+}
+
+// Run executes the collector.
+//
+// The API calls and the streams of the collector are canceled as soon as it's done or the collector timeout is reached.
+func (c *Collector) Run(ctx context.Context, options *bundle.Options) error {
+	var cancel context.CancelFunc
+
+	if options.CollectorTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, options.CollectorTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	defer cancel()
+
+	err := c.run(ctx, options)
+
+	// the streams stop silently on the timeout, so the collected data might be incomplete even if there's no error
+	if options.CollectorTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("collector timed out after %s: %w", options.CollectorTimeout, ctx.Err())
+	}
+
+	return err
+}
+
+func (c *Collector) run(ctx context.Context, options *bundle.Options) error {
+	if c.stream != nil {
+		return c.runStream(ctx, options)
+	}
+
+	data, err := c.collect(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	if data, err = c.redact(options, data); err != nil {
+		return err
+	}
+
+	return c.write(options, bytes.NewReader(data), int64(len(data)))
+}
+
+func (c *Collector) runStream(ctx context.Context, options *bundle.Options) error {
+	if c.streamsDirectly(options) {
+		return c.runDirectStream(ctx, options)
+	}
+
+	buf := &spillBuffer{
+		dir:       options.TempDir,
+		threshold: spillThreshold,
+	}
+
+	defer buf.Close() //nolint:errcheck
+
+	var (
+		w  io.Writer = buf
+		rw *redactWriter
+	)
+
+	if !c.redactsWholeFile(options) && len(c.redactors(options)) > 0 {
+		rw = c.newRedactWriter(options, buf)
+		w = rw
+	}
+
+	cut, err := c.collectStream(ctx, options, w)
+	if err == nil && rw != nil {
+		err = rw.Close()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if buf.Size() == 0 {
+		return nil
+	}
+
+	// the file truncated to the max file size gets its own note
+	if cut && (options.MaxFileSize == 0 || buf.Size() <= options.MaxFileSize) {
+		if err = c.writeCutNote(options); err != nil {
+			return err
+		}
+	}
+
+	if c.redactsWholeFile(options) && !isBinary(buf.ReaderAt()) {
+		data, err := io.ReadAll(io.NewSectionReader(buf.ReaderAt(), 0, buf.Size()))
+		if err != nil {
+			return err
+		}
+
+		if data, err = c.redact(options, data); err != nil {
+			return err
+		}
+
+		return c.write(options, bytes.NewReader(data), int64(len(data)))
+	}
+
+	return c.write(options, buf.ReaderAt(), buf.Size())
+}
+
+// streamsDirectly checks if the collected data can be written to the archive as it's produced.
+//
+// The data is buffered if it's scanned for the secrets, truncated or it's a redacted YAML file, as it has to be read as a whole.
+func (c *Collector) streamsDirectly(options *bundle.Options) bool {
+	_, ok := options.Archive.(bundle.StreamArchive)
+
+	return ok && options.MaxFileSize == 0 && !options.SecretScan && !c.redactsWholeFile(options)
+}
+
+// runDirectStream pipes the collected data to the archive, so the memory usage doesn't depend on the file size.
+//
+// The archive file is created once the first byte is collected, if the collector fails after that,
+// the data collected so far is kept.
+func (c *Collector) runDirectStream(ctx context.Context, options *bundle.Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+
+	streamErr := make(chan error, 1)
+	streamCut := make(chan bool, 1)
+
+	go func() {
+		w := getBufioWriter(pw)
+		defer putBufioWriter(w)
+
+		var (
+			sw io.Writer = w
+			rw *redactWriter
+		)
+
+		if len(c.redactors(options)) > 0 {
+			rw = c.newRedactWriter(options, w)
+			sw = rw
+		}
+
+		cut, err := c.collectStream(ctx, options, sw)
+		if err == nil && rw != nil {
+			err = rw.Close()
+		}
+
+		if err == nil {
+			err = w.Flush()
+		}
+
+		pw.CloseWithError(err)
+
+		streamCut <- cut
+		streamErr <- err
+	}()
+
+	bufferSize := directStreamBufferSize
+
+	// the data is read ahead to check if it's large enough to be compressed
+	if options.CompressThreshold > 0 {
+		bufferSize = max(bufferSize, int(options.CompressThreshold)+1)
+	}
+
+	r := bufio.NewReaderSize(pr, bufferSize)
+
+	_, err := r.Peek(1)
+
+	switch {
+	case err == nil:
+		err = c.writeStream(options, r)
+	case errors.Is(err, io.EOF):
+		err = nil
+	}
+
+	// unblock the collector if the archive write failed, the stream is canceled instead of being read to the end
+	pr.Close() //nolint:errcheck
+	cancel()
+
+	cut := <-streamCut
+
+	if collectErr := <-streamErr; err == nil {
+		err = collectErr
+	}
+
+	if err == nil && cut {
+		err = c.writeCutNote(options)
+	}
+
+	return err
 }
 
-// NewCollector creates new collector.
-func NewCollector(path string, c Collect) *Collector {
-	return &Collector{
-		source:          Cluster,
-		destinationPath: path,
-		collect:         c,
+// errStreamLimit stops the stream collector at the max stream size.
+var errStreamLimit = errors.New("stream size limit reached")
+
+// collectStream runs the stream collector stopping it at the max stream size, it returns true if the stream was cut.
+//
+// The API stream is canceled as soon as the collector returns, it's not left to be read to the end in the background.
+func (c *Collector) collectStream(ctx context.Context, options *bundle.Options, w io.Writer) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if options.MaxStreamSize <= 0 {
+		return false, c.stream(ctx, options, w)
+	}
+
+	limited := &limitWriter{w: w, remaining: options.MaxStreamSize}
+
+	err := c.stream(ctx, options, limited)
+
+	// any error after the limit is reached is caused by the stream being stopped
+	if limited.reached {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// writeCutNote writes the note next to the file cut at the max stream size.
+func (c *Collector) writeCutNote(options *bundle.Options) error {
+	note := fmt.Sprintf("%s was cut at %d bytes, the rest of the stream was not collected\n", c.destinationPath, options.MaxStreamSize)
+
+	redactedNote, err := c.redact(options, []byte(note))
+	if err != nil {
+		return err
+	}
+
+	return options.Archive.Write(options.ArchivePath(c.destinationPath+".truncated"), redactedNote)
+}
+
+// limitWriter fails the writes with errStreamLimit once the limit is reached.
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	reached   bool
+}
+
+// Write implements io.Writer.
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.remaining {
+		n, err := l.w.Write(p)
+		l.remaining -= int64(n)
+
+		return n, err
+	}
+
+	n, err := l.w.Write(p[:l.remaining])
+	l.remaining -= int64(n)
+
+	if err != nil {
+		return n, err
+	}
+
+	l.reached = true
+
+	return n, errStreamLimit
+}
+
+// writeStream writes the streamed data to the archive compressing it if it's larger than the compression threshold.
+func (c *Collector) writeStream(options *bundle.Options, r *bufio.Reader) error {
+	path := options.ArchivePath(c.destinationPath)
+
+	if options.CompressThreshold <= 0 {
+		return writeArchive(options, path, r)
+	}
+
+	head, err := r.Peek(int(options.CompressThreshold) + 1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if int64(len(head)) > options.CompressThreshold && !isBinary(bytes.NewReader(head)) {
+		return writeCompressed(options, path, r)
+	}
+
+	return writeArchive(options, path, r)
+}
+
+// write puts the data to the archive truncating it to the max file size.
+func (c *Collector) write(options *bundle.Options, data io.ReaderAt, size int64) error {
+	var r io.Reader = io.NewSectionReader(data, 0, size)
+
+	if options.SecretScan && !isBinary(data) {
+		findings, err := redact.Scan(io.NewSectionReader(data, 0, size))
+		if err != nil {
+			return err
+		}
+
+		options.RecordSecretFindings(options.ArchivePath(c.destinationPath), findings)
+	}
+
+	if options.MaxFileSize > 0 && size > options.MaxFileSize {
+		note := fmt.Sprintf("%s was truncated from %d to %d bytes, the middle part was removed\n", c.destinationPath, size, options.MaxFileSize)
+
+		redactedNote, err := c.redact(options, []byte(note))
+		if err != nil {
+			return err
+		}
+
+		if err = options.Archive.Write(options.ArchivePath(c.destinationPath+".truncated"), redactedNote); err != nil {
+			return err
+		}
+
+		r = truncate(data, size, options.MaxFileSize)
+		size = options.MaxFileSize
+	}
+
+	if options.CompressThreshold > 0 && size > options.CompressThreshold && !isBinary(data) {
+		return writeCompressed(options, options.ArchivePath(c.destinationPath), r)
+	}
+
+	return writeArchive(options, options.ArchivePath(c.destinationPath), r)
+}
+
+// writeCompressed gzips the data in the worker and writes it to the archive with the compressed suffix,
+// the archive stores it without compressing again.
+func writeCompressed(options *bundle.Options, path string, r io.Reader) error {
+	buf := &spillBuffer{
+		dir:       options.TempDir,
+		threshold: spillThreshold,
+	}
+
+	defer buf.Close() //nolint:errcheck
+
+	zw := getGzipWriter(buf)
+	defer putGzipWriter(zw)
+
+	if _, err := io.Copy(zw, r); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return writeArchive(options, path+bundle.CompressedSuffix, io.NewSectionReader(buf.ReaderAt(), 0, buf.Size()))
+}
+
+// truncate cuts out the middle of the data to fit the size, keeping the head and the tail.
+func truncate(data io.ReaderAt, dataSize, size int64) io.Reader {
+	head, tail, marker := truncation(dataSize, size)
+
+	return io.MultiReader(
+		io.NewSectionReader(data, 0, head),
+		strings.NewReader(marker),
+		io.NewSectionReader(data, dataSize-tail, tail),
+	)
+}
+
+// TruncateStream copies the data of dataSize from r to w truncated to the size the same way as bundle.WithMaxFileSize does:
+// the head and the tail are kept with the truncation marker between them.
+func TruncateStream(w io.Writer, r io.Reader, dataSize, size int64) error {
+	head, tail, marker := truncation(dataSize, size)
+
+	if _, err := io.CopyN(w, r, head); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, marker); err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(io.Discard, r, dataSize-head-tail); err != nil {
+		return err
+	}
+
+	_, err := io.CopyN(w, r, tail)
+
+	return err
+}
+
+// truncation returns the sizes of the head and the tail kept by truncate and the marker put between them.
+func truncation(dataSize, size int64) (head, tail int64, marker string) {
+	marker = fmt.Sprintf("\n... %d bytes truncated ...\n", dataSize-size)
+
+	if size <= int64(len(marker)) {
+		return size, 0, ""
+	}
+
+	head = (size - int64(len(marker))) / 2
+	tail = size - int64(len(marker)) - head
+
+	return head, tail, marker
+}
+
+// writeArchive streams the data to the archive if it supports streaming, or writes it at once otherwise.
+func writeArchive(options *bundle.Options, path string, r io.Reader) error {
+	if archive, ok := options.Archive.(bundle.StreamArchive); ok {
+		return archive.WriteFrom(path, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return options.Archive.Write(path, data)
+}
+
+// Source returns collector source name (Talos node name, cluster, etc).
+func (c *Collector) Source() string {
+	return c.source
+}
+
+// Name returns the stable machine-readable name of the collector, e.g. `talos.dmesg` or `k8s.nodes`.
+//
+// Unlike the path, the name doesn't depend on the node and the folder of the collector,
+// the collectors of the same kind share the name, e.g. all pod logs are named `k8s.pod-logs`.
+func (c *Collector) Name() string {
+	return c.name
+}
+
+// WithName sets the name of the collector, it returns the collector to be chained with the constructor.
+func (c *Collector) WithName(name string) *Collector {
+	c.name = name
+
+	return c
+}
+
+// TalosResource returns true if the collector dumps the Talos resources.
+func (c *Collector) TalosResource() bool {
+	return c.talosResource
+}
+
+// Path returns the path of the collected file in the archive.
+func (c *Collector) Path() string {
+	return c.destinationPath
+}
+
+// String implements fmt.Stringer interface.
+func (c *Collector) String() string {
+	return fmt.Sprintf("collect %s", filepath.Base(c.destinationPath))
+}
+
+// WithFolder appends path prefix to all collectors.
+func WithFolder(collectors []*Collector, path string) []*Collector {
+	for _, c := range collectors {
+		c.destinationPath = filepath.Join(path, c.destinationPath)
+	}
+
+	return collectors
+}
+
+// WithNode returns collectors which adds Talos node gRPC metadata to the context.
+func WithNode(collectors []*Collector, node string) []*Collector {
+	for _, c := range collectors {
+		c.wrap(func(ctx context.Context, options *bundle.Options) (context.Context, *bundle.Options, error) {
+			return client.WithNode(ctx, node), options, nil
+		})
+
+		c.source = node
+		c.destinationPath = filepath.Join(node, c.destinationPath)
+	}
+
+	return collectors
+}
+
+// WithTalosClient returns collectors which use the Talos client instead of the one from the bundle options.
+func WithTalosClient(collectors []*Collector, c *client.Client) []*Collector {
+	for _, col := range collectors {
+		col.wrap(func(ctx context.Context, options *bundle.Options) (context.Context, *bundle.Options, error) {
+			nodeOptions := *options
+			nodeOptions.TalosClient = c
+
+			return ctx, &nodeOptions, nil
+		})
+	}
+
+	return collectors
+}
+
+// WithSource returns collectors which custom source name.
+func WithSource(collectors []*Collector, source string) []*Collector {
+	for _, c := range collectors {
+		c.source = source
+	}
+
+	return collectors
+}
+
+// GetForOptions creates all collectors for the provided bundle options.
+func GetForOptions(ctx context.Context, options *bundle.Options) ([]*Collector, error) {
+	if !options.SkipTalos {
+		if err := options.InitTalosClient(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// the Kubernetes client is still needed to select the nodes
+	if !options.SkipKubernetes || options.SelectsNodes() {
+		if err := options.InitKubernetesClient(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := selectNodes(ctx, options); err != nil {
+		return nil, err
+	}
+
+	discoverNodes(ctx, options)
+
+	var collectors []*Collector
+
+	if !options.SkipKubernetes {
+		kubernetesCollectors, err := getKubernetesCollectors(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+
+		collectors = append(collectors, kubernetesCollectors...)
+	}
+
+	if options.CollectsKubernetes() || options.CollectsTalos() {
+		collectors = append(collectors, NewCollector("version-skew.txt", versionSkew).WithName("cluster.version-skew"))
+	}
+
+	if options.CollectsTalos() {
+		for _, node := range options.Nodes {
+			// the node might be down or not ready, so its failures are recorded for the node and the other nodes are still collected
+			nodeClient, err := options.NodeTalosClient(ctx, node)
+			if err != nil {
+				collectors = append(collectors, WithNode([]*Collector{newFailedCollector("talos-client", "talos.client", err)}, node)...)
+
+				continue
+			}
+
+			nodeCtx := client.WithNode(ctx, node)
+
+			machineType, err := audited(nodeCtx, options, "talos.machine-type", func(ctx context.Context) (machine.Type, error) {
+				return getMachineType(ctx, nodeClient)
+			})
+
+			// the role of the node is unknown if the lookup fails, so the node is not skipped by the role filter
+			// and gets all collectors, the etcd ones fail on the worker nodes
+			switch {
+			case err != nil:
+				options.Log("failed to get machine type of node %s, collecting everything: %s", node, err)
+			case !options.NodeRole.Matches(machineType):
+				options.Log("skipping node %s with machine type %s", node, machineType)
+
+				continue
+			}
+
+			nodeCollectors := getTalosNodeCollectors(nodeCtx, nodeClient, options, err != nil || machineType.IsControlPlane())
+
+			if nodeClient != options.TalosClient {
+				nodeCollectors = WithTalosClient(nodeCollectors, nodeClient)
+			}
+
+			collectors = append(collectors, WithNode(nodeCollectors, node)...)
+		}
+	}
+
+	for _, extra := range options.ExtraCollectors {
+		collectors = append(collectors, extraCollector(extra))
+	}
+
+	return collectors, nil
+}
+
+// WithExtraCollectors appends the collectors to the standard set of collectors created by GetForOptions.
+func WithExtraCollectors(cols ...*Collector) bundle.Option {
+	return func(o *bundle.Options) {
+		for _, c := range cols {
+			o.ExtraCollectors = append(o.ExtraCollectors, c)
+		}
+	}
+}
+
+// extraCollector creates the collector which runs the extra collector set in the options, the extra collector writes
+// the data to the archive itself.
+func extraCollector(extra bundle.Collector) *Collector {
+	c := NewCollector(extra.Path(), func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		return nil, extra.Run(ctx, options)
+	}).WithName(extra.Name())
+
+	c.source = extra.Source()
+
+	return c
+}
+
+// newFailedCollector creates a collector which fails with the error the collectors of the group couldn't be created with,
+// so the failure is recorded like the failures of the other collectors instead of aborting the whole bundle.
+func newFailedCollector(path, name string, err error) *Collector {
+	return NewCollector(path, func(context.Context, *bundle.Options) ([]byte, error) {
+		return nil, err
+	}).WithName(name)
+}
+
+func getKubernetesCollectors(ctx context.Context, options *bundle.Options) ([]*Collector, error) {
+	if options.Profile == bundle.ProfileMinimal {
+		if options.KubernetesClient == nil {
+			return nil, nil
+		}
+
+		return WithSource([]*Collector{
+			NewCollector("kubernetesResources/nodes.yaml", kubernetesNodes(options.KubernetesClient)).WithName("k8s.nodes"),
+			NewCollector("kubernetesResources/nodes.txt", nodesSummary(options.KubernetesClient)).WithName("k8s.nodes-summary"),
+			NewCollector("kubernetesResources/systemPods.yaml", systemPods(options.KubernetesClient)).WithName("k8s.system-pods"),
+		}, Cluster), nil
+	}
+
+	var collectors []*Collector
+
+	if options.DynamicClient != nil {
+		collectors = append(collectors, GetDynamicCollectors(options.DynamicClient, options.CustomResources...)...)
+	}
+
+	if options.KubernetesClient == nil {
+		return WithSource(collectors, Cluster), nil
+	}
+
+	collectors = append(collectors, GetKubernetesCollectors(options.KubernetesClient)...)
+
+	if options.Profile == bundle.ProfileFull || options.KubeProxyRules {
+		collectors = append(collectors, NewCollector("kubernetesResources/kube-proxy/rules.txt", kubeProxyRules(options.KubernetesClient)).WithName("k8s.kube-proxy.rules"))
+	}
+
+	kubeletCollectors, err := audited(ctx, options, "k8s.kubelet", func(ctx context.Context) ([]*Collector, error) {
+		return GetKubeletCollectors(ctx, options, options.KubernetesClient)
+	})
+	if err != nil {
+		kubeletCollectors = []*Collector{newFailedCollector("kubernetesResources/kubelet", "k8s.kubelet", err)}
+	}
+
+	collectors = append(collectors, kubeletCollectors...)
+
+	cniCollectors, err := audited(ctx, options, "k8s.cni", func(ctx context.Context) ([]*Collector, error) {
+		return GetCNICollectors(ctx, options.KubernetesClient)
+	})
+	if err != nil {
+		cniCollectors = []*Collector{newFailedCollector("kubernetesResources/cni", "k8s.cni", err)}
+	}
+
+	collectors = append(collectors, cniCollectors...)
+
+	previousLogCollectors, err := audited(ctx, options, "k8s.previous-logs", func(ctx context.Context) ([]*Collector, error) {
+		return GetPreviousPodLogCollectors(ctx, options.KubernetesClient, options.KubernetesNamespaces()...)
+	})
+	if err != nil {
+		previousLogCollectors = []*Collector{newFailedCollector("kubernetesResources/previous-logs", "k8s.previous-logs", err)}
+	}
+
+	collectors = append(collectors, previousLogCollectors...)
+
+	problemPodCollectors, err := audited(ctx, options, "k8s.problem-pods", func(ctx context.Context) ([]*Collector, error) {
+		return GetProblemPodCollectors(ctx, options, options.KubernetesClient)
+	})
+	if err != nil {
+		problemPodCollectors = []*Collector{newFailedCollector("kubernetesResources/problem-pods", "k8s.problem-pods", err)}
+	}
+
+	collectors = append(collectors, problemPodCollectors...)
+
+	for _, selector := range options.PodLogSelectors {
+		podLogCollectors, err := audited(ctx, options, "k8s.pod-logs", func(ctx context.Context) ([]*Collector, error) {
+			return GetPodLogCollectors(ctx, options.KubernetesClient, selector.Namespace, selector.LabelSelector)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		collectors = append(collectors, podLogCollectors...)
 	}
+
+	return WithSource(collectors, Cluster), nil
 }
 
-// Run executes the collector.
-func (c *Collector) Run(ctx context.Context, options *bundle.Options) error {
-	data, err := c.collect(ctx, options)
+// GetTalosNodeCollectors creates all collectors that rely on using Talos API.
+//
+// The etcd collectors are created only for the control plane nodes and for the nodes the machine type can't be fetched of.
+func GetTalosNodeCollectors(ctx context.Context, client *client.Client) ([]*Collector, error) {
+	machineType, err := getMachineType(ctx, client)
+
+	return getTalosNodeCollectors(ctx, client, bundle.NewOptions(), err != nil || machineType.IsControlPlane()), nil
+}
+
+// getTalosNodeCollectors creates the Talos collectors for the node.
+//
+// The failures to list the node resources, containers and services are recorded for the node as the failed collectors,
+// so the unreachable node doesn't abort the whole bundle.
+func getTalosNodeCollectors(ctx context.Context, client *client.Client, options *bundle.Options, controlPlane bool) []*Collector {
+	profile := options.Profile
+
+	base := []*Collector{
+		NewCollector("summary", summary).WithName("talos.summary"),
+	}
+
+	if profile != bundle.ProfileMinimal {
+		base = append(base,
+			NewStreamCollector("dmesg.log", dmesg).WithName("talos.dmesg"),
+			NewStreamCollector("controller-runtime.log", logs("controller-runtime", "controller-runtime", false)).WithName("talos.logs.controller-runtime"),
+			NewStreamCollector("dns-resolve-cache.log", logs("dns-resolve-cache", "dns-resolve-cache", false)).WithName("talos.logs.dns-resolve-cache"),
+			NewCollector("dependencies.dot", dependencies).WithName("talos.dependencies"),
+			NewCollector("mounts", mounts).WithName("talos.mounts"),
+			NewCollector("devices", devices).WithName("talos.devices"),
+			NewCollector("io", ioPressure).WithName("talos.io"),
+			NewCollector("processes", processes).WithName("talos.processes"),
+		)
+	}
+
+	if options.MachineConfig {
+		base = append(base, NewCollector("machine-config.yaml", machineConfig).WithName("talos.machine-config"))
+	}
+
+	if controlPlane && profile != bundle.ProfileMinimal {
+		base = append(base, NewCollector("etcd-status", etcdStatus).WithName("talos.etcd-status"))
+	}
+
+	if controlPlane && (profile == bundle.ProfileFull || options.EtcdSnapshot) {
+		base = append(base, NewStreamCollector("etcd.snapshot", etcdSnapshot).WithName("talos.etcd-snapshot"))
+	}
+
+	if options.PacketCapture.Duration > 0 {
+		for _, iface := range options.PacketCapture.Interfaces {
+			base = append(base, NewStreamCollector(
+				filepath.Join("pcap", iface+".pcap"),
+				packetCapture(iface, options.PacketCapture.Duration),
+			).WithName("talos.pcap."+iface))
+		}
+	}
+
+	collectors, err := audited(ctx, options, "talos.resources", func(ctx context.Context) ([]*Collector, error) {
+		return getTalosResources(ctx, client.COSI)
+	})
 	if err != nil {
-		return err
+		collectors = []*Collector{newFailedCollector("", "talos.resources", err)}
 	}
 
-	if data == nil {
-		return nil
+	base = append(base, WithFolder(collectors, "resources")...)
+
+	if profile != bundle.ProfileMinimal {
+		collectors, err = getKubernetesLogCollectors(ctx, client, options, profile == bundle.ProfileFull)
+		if err != nil {
+			collectors = []*Collector{newFailedCollector("", "talos.kubernetes-logs", err)}
+		}
+
+		base = append(base, WithFolder(collectors, "kubernetes-logs")...)
 	}
 
-	return options.Archive.Write(c.destinationPath, data)
-}
+	collectors, err = audited(ctx, options, "talos.service-logs", func(ctx context.Context) ([]*Collector, error) {
+		return getServiceLogCollectors(ctx, client)
+	})
+	if err != nil {
+		collectors = []*Collector{newFailedCollector("", "talos.service-logs", err)}
+	}
 
-// Source returns collector source name (Talos node name, cluster, etc).
-func (c *Collector) Source() string {
-	return c.source
+	base = append(base, WithFolder(collectors, "service-logs")...)
+
+	return base
 }
 
-// String implements fmt.Stringer interface.
-func (c *Collector) String() string {
-	return fmt.Sprintf("collect %s", filepath.Base(c.destinationPath))
+// GetKubernetesCollectors creates all kubernetes API related collectors.
+func GetKubernetesCollectors(client kubernetes.Interface) []*Collector {
+	return []*Collector{
+		NewCollector("kubernetesResources/nodes.yaml", kubernetesNodes(client)).WithName("k8s.nodes"),
+		NewCollector("kubernetesResources/nodes.txt", nodesSummary(client)).WithName("k8s.nodes-summary"),
+		NewCollector("kubernetesResources/node-health.txt", nodeHealth(client)).WithName("k8s.node-health"),
+		NewCollector("kubernetesResources/systemPods.yaml", systemPods(client)).WithName("k8s.system-pods"),
+		NewCollector("kubernetesResources/namespaces.yaml", namespaces(client)).WithName("k8s.namespaces"),
+		NewCollector("kubernetesResources/services.yaml", services(client)).WithName("k8s.services"),
+		NewCollector("kubernetesResources/endpoints.yaml", endpoints(client)).WithName("k8s.endpoints"),
+		NewCollector("kubernetesResources/endpointSlices.yaml", endpointSlices(client)).WithName("k8s.endpoint-slices"),
+		NewCollector("kubernetesResources/resourceQuotas.yaml", resourceQuotas(client)).WithName("k8s.resource-quotas"),
+		NewCollector("kubernetesResources/limitRanges.yaml", limitRanges(client)).WithName("k8s.limit-ranges"),
+		NewCollector("kubernetesResources/events.yaml", kubernetesEvents(client)).WithName("k8s.events"),
+		NewCollector("kubernetesResources/storage/persistentVolumes.yaml", persistentVolumes(client)).WithName("k8s.storage.persistent-volumes"),
+		NewCollector("kubernetesResources/storage/persistentVolumeClaims.yaml", persistentVolumeClaims(client)).WithName("k8s.storage.persistent-volume-claims"),
+		NewCollector("kubernetesResources/storage/storageClasses.yaml", storageClasses(client)).WithName("k8s.storage.storage-classes"),
+		NewCollector("kubernetesResources/storage/csiDrivers.yaml", csiDrivers(client)).WithName("k8s.storage.csi-drivers"),
+		NewCollector("kubernetesResources/storage/csiNodes.yaml", csiNodes(client)).WithName("k8s.storage.csi-nodes"),
+		NewCollector("kubernetesResources/storage/volumeAttachments.yaml", volumeAttachments(client)).WithName("k8s.storage.volume-attachments"),
+		NewCollector("kubernetesResources/apiservices.yaml", apiServices(client)).WithName("k8s.api-services"),
+		NewCollector("kubernetesResources/api-versions.txt", apiVersions(client)).WithName("k8s.api-versions"),
+		NewCollector("kubernetesResources/api-resources.txt", apiResources(client)).WithName("k8s.api-resources"),
+		NewCollector("kubernetesResources/networkPolicies/networkPolicies.yaml", networkPolicies(client)).WithName("k8s.network-policies"),
+		NewCollector("kubernetesResources/ingress/ingresses.yaml", ingresses(client)).WithName("k8s.ingress.ingresses"),
+		NewCollector("kubernetesResources/ingress/ingressClasses.yaml", ingressClasses(client)).WithName("k8s.ingress.ingress-classes"),
+		NewCollector("kubernetesResources/rbac/clusterRoles.yaml", clusterRoles(client)).WithName("k8s.rbac.cluster-roles"),
+		NewCollector("kubernetesResources/rbac/clusterRoleBindings.yaml", clusterRoleBindings(client)).WithName("k8s.rbac.cluster-role-bindings"),
+		NewCollector("kubernetesResources/rbac/roles.yaml", roles(client)).WithName("k8s.rbac.roles"),
+		NewCollector("kubernetesResources/rbac/roleBindings.yaml", roleBindings(client)).WithName("k8s.rbac.role-bindings"),
+		NewCollector("kubernetesResources/leases.yaml", leases(client)).WithName("k8s.leases"),
+		NewCollector("kubernetesResources/podDisruptionBudgets.yaml", podDisruptionBudgets(client)).WithName("k8s.pod-disruption-budgets"),
+		NewCollector("kubernetesResources/pdb-blockers.txt", podDisruptionBudgetBlockers(client)).WithName("k8s.pdb-blockers"),
+		NewCollector("kubernetesResources/unschedulable-pods.txt", unschedulablePods(client)).WithName("k8s.unschedulable-pods"),
+		NewCollector("kubernetesResources/helm-releases.txt", helmReleases(client)).WithName("k8s.helm-releases"),
+		NewCollector("kubernetesResources/restarts.txt", containerRestarts(client)).WithName("k8s.restarts"),
+		NewCollector("kubernetesResources/kube-proxy/daemonset.yaml", kubeProxyDaemonSet(client)).WithName("k8s.kube-proxy.daemonset"),
+		NewCollector("kubernetesResources/kube-proxy/configmap.yaml", kubeProxyConfig(client)).WithName("k8s.kube-proxy.configmap"),
+		NewCollector("kubernetesResources/metrics/nodes.txt", nodeMetrics(client)).WithName("k8s.metrics.nodes"),
+		NewCollector("kubernetesResources/metrics/pods.txt", podMetrics(client)).WithName("k8s.metrics.pods"),
+		NewCollector("kubernetesResources/apiserver-health.txt", apiServerHealth(client)).WithName("k8s.apiserver-health"),
+		NewCollector("kubernetesResources/controlPlaneStatus.txt", controlPlaneStatus(client)).WithName("k8s.control-plane-status"),
+		NewCollector("kubernetesResources/coredns/configmap.yaml", corednsConfig(client)).WithName("k8s.coredns.configmap"),
+		NewCollector("kubernetesResources/coredns/deployment.yaml", corednsDeployment(client)).WithName("k8s.coredns.deployment"),
+		NewCollector("kubernetesResources/coredns/resolution.txt", corednsResolution(client)).WithName("k8s.coredns.resolution"),
+	}
 }
 
-// WithFolder appends path prefix to all collectors.
-func WithFolder(collectors []*Collector, path string) []*Collector {
-	for _, c := range collectors {
-		c.destinationPath = filepath.Join(path, c.destinationPath)
+// GetKubeletCollectors creates collectors which read kubelet stats and pods through the API server node proxy.
+//
+// The node list is shared with the other collectors of the bundle.
+func GetKubeletCollectors(ctx context.Context, options *bundle.Options, client kubernetes.Interface) ([]*Collector, error) {
+	nodes, err := listNodes(ctx, options, client)
+	if err != nil {
+		return nil, err
 	}
 
-	return collectors
+	collectors := make([]*Collector, 0, len(nodes.Items)*2)
+
+	for _, node := range nodes.Items {
+		collectors = append(collectors,
+			NewCollector(fmt.Sprintf("kubernetesResources/kubelet/%s/stats-summary.json", node.Name), kubeletProxy(client, node.Name, "stats/summary")).WithName("k8s.kubelet.stats-summary"),
+			NewCollector(fmt.Sprintf("kubernetesResources/kubelet/%s/pods.json", node.Name), kubeletProxy(client, node.Name, "pods")).WithName("k8s.kubelet.pods"),
+		)
+	}
+
+	return collectors, nil
 }
 
-// WithNode returns collectors which adds Talos node gRPC metadata to the context.
-func WithNode(collectors []*Collector, node string) []*Collector {
-	for _, c := range collectors {
-		collectFunc := c.collect
+// cniDaemonSets maps well-known CNI DaemonSet names to the CNI name.
+var cniDaemonSets = map[string]string{
+	"cilium":       "cilium",
+	"kube-flannel": "flannel",
+	"calico-node":  "calico",
+}
+
+// GetCNICollectors detects the installed CNI and creates collectors for its namespace: config maps, daemon sets and pod logs.
+func GetCNICollectors(ctx context.Context, client kubernetes.Interface) ([]*Collector, error) {
+	list, err := listAll(ctx, v1.ListOptions{}, client.AppsV1().DaemonSets(v1.NamespaceAll).List)
+	if err != nil {
+		return nil, err
+	}
+
+	var collectors []*Collector
 
-		c.collect = func(ctx context.Context, options *bundle.Options) ([]byte, error) {
-			return collectFunc(client.WithNode(ctx, node), options)
+	for _, ds := range list.Items {
+		cni, ok := cniDaemonSets[ds.Name]
+		if !ok {
+			continue
 		}
 
-		c.source = node
-		c.destinationPath = filepath.Join(node, c.destinationPath)
+		folder := filepath.Join("kubernetesResources/cni", cni)
+
+		collectors = append(collectors,
+			NewCollector(filepath.Join(folder, "configmaps.yaml"), configMaps(client, ds.Namespace)).WithName("k8s.cni."+cni+".configmaps"),
+			NewCollector(filepath.Join(folder, "daemonsets.yaml"), daemonSets(client, ds.Namespace)).WithName("k8s.cni."+cni+".daemonsets"),
+		)
+
+		pods, err := cniPods(ctx, client, &ds)
+		if err != nil {
+			collectors = append(collectors, newFailedCollector(filepath.Join(folder, "logs"), "k8s.cni."+cni+".logs", err))
+
+			continue
+		}
+
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				collectors = append(collectors, NewCollector(
+					filepath.Join(folder, "logs", pod.Name, container.Name+".log"),
+					podLogs(client, pod.Namespace, pod.Name, container.Name, false),
+				).WithName("k8s.cni."+cni+".logs"))
+			}
+		}
 	}
 
-	return collectors
+	return collectors, nil
 }
 
-// WithSource returns collectors which custom source name.
-func WithSource(collectors []*Collector, source string) []*Collector {
-	for _, c := range collectors {
-		c.source = source
+// cniPods lists the pods of the CNI daemon set.
+func cniPods(ctx context.Context, client kubernetes.Interface, ds *appsv1.DaemonSet) (*corev1.PodList, error) {
+	selector, err := v1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, err
 	}
 
-	return collectors
+	return listAll(ctx, v1.ListOptions{LabelSelector: selector.String()}, client.CoreV1().Pods(ds.Namespace).List)
 }
 
-// GetForOptions creates all collectors for the provided bundle options.
-func GetForOptions(ctx context.Context, options *bundle.Options) ([]*Collector, error) {
+var ciliumPolicies = []schema.GroupVersionResource{
+	{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"},
+	{Group: "cilium.io", Version: "v2", Resource: "ciliumclusterwidenetworkpolicies"},
+}
+
+var gatewayResources = []schema.GroupVersionResource{
+	{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gatewayclasses"},
+	{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"},
+	{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"},
+}
+
+// GetPreviousPodLogCollectors creates collectors which get the logs of the previous terminated instance
+// of the restarted containers in the namespaces.
+//
+// The failure to list the pods of a namespace is recorded by the collector failing with the error.
+func GetPreviousPodLogCollectors(ctx context.Context, client kubernetes.Interface, namespaces ...string) ([]*Collector, error) {
 	var collectors []*Collector
 
-	if options.KubernetesClient != nil {
-		collectors = append(collectors, WithSource(GetKubernetesCollectors(options.KubernetesClient), Cluster)...)
-	}
+	for _, namespace := range namespaces {
+		pods, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().Pods(namespace).List)
+		if err != nil {
+			collectors = append(collectors, newFailedCollector(filepath.Join("kubernetesResources/previous-logs", namespace), "k8s.previous-logs", err))
 
-	if options.TalosClient != nil && len(options.Nodes) > 0 {
-		for _, node := range options.Nodes {
-			nodeCollectors, err := GetTalosNodeCollectors(client.WithNode(ctx, node), options.TalosClient)
-			if err != nil {
-				return nil, err
-			}
+			continue
+		}
 
-			collectors = append(collectors, WithNode(nodeCollectors, node)...)
+		for _, pod := range pods.Items {
+			for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+				if status.LastTerminationState.Terminated == nil {
+					continue
+				}
+
+				collectors = append(collectors, NewCollector(
+					filepath.Join("kubernetesResources/previous-logs", pod.Namespace, pod.Name, status.Name+".log"),
+					podLogs(client, pod.Namespace, pod.Name, status.Name, true),
+				).WithName("k8s.previous-logs"))
+			}
 		}
 	}
 
 	return collectors, nil
 }
 
-// GetTalosNodeCollectors creates all collectors that rely on using Talos API.
-func GetTalosNodeCollectors(ctx context.Context, client *client.Client) ([]*Collector, error) {
-	base := []*Collector{
-		NewCollector("dmesg.log", dmesg),
-		NewCollector("controller-runtime.log", logs("controller-runtime", false)),
-		NewCollector("dns-resolve-cache.log", logs("dns-resolve-cache", false)),
-		NewCollector("dependencies.dot", dependencies),
-		NewCollector("mounts", mounts),
-		NewCollector("devices", devices),
-		NewCollector("io", ioPressure),
-		NewCollector("processes", processes),
-		NewCollector("summary", summary),
-	}
-
-	collectors, err := getTalosResources(ctx, client.COSI)
+// GetPodLogCollectors creates collectors which get the current and previous logs of all containers
+// of the pods matching the label selector.
+func GetPodLogCollectors(ctx context.Context, client kubernetes.Interface, namespace, labelSelector string) ([]*Collector, error) {
+	pods, err := listAll(ctx, v1.ListOptions{LabelSelector: labelSelector}, client.CoreV1().Pods(namespace).List)
 	if err != nil {
 		return nil, err
 	}
 
-	base = append(base, WithFolder(collectors, "resources")...)
+	var collectors []*Collector
 
-	collectors, err = getKubernetesLogCollectors(ctx, client)
-	if err != nil {
-		return nil, err
+	for _, pod := range pods.Items {
+		folder := filepath.Join("kubernetesResources/pod-logs", pod.Namespace, pod.Name)
+
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			collectors = append(collectors, NewCollector(
+				filepath.Join(folder, status.Name+".log"),
+				podLogs(client, pod.Namespace, pod.Name, status.Name, false),
+			).WithName("k8s.pod-logs"))
+
+			if status.LastTerminationState.Terminated != nil {
+				collectors = append(collectors, NewCollector(
+					filepath.Join(folder, status.Name+"-previous.log"),
+					podLogs(client, pod.Namespace, pod.Name, status.Name, true),
+				).WithName("k8s.pod-logs.previous"))
+			}
+		}
 	}
 
-	base = append(base, WithFolder(collectors, "kubernetes-logs")...)
+	return collectors, nil
+}
 
-	collectors, err = getServiceLogCollectors(ctx, client)
+// GetProblemPodCollectors creates describe-like reports for all pods which are not running or not ready.
+//
+// The pod list is shared with the other collectors of the bundle.
+func GetProblemPodCollectors(ctx context.Context, options *bundle.Options, client kubernetes.Interface) ([]*Collector, error) {
+	pods, err := listPods(ctx, options, client)
 	if err != nil {
 		return nil, err
 	}
 
-	base = append(base, WithFolder(collectors, "service-logs")...)
+	var collectors []*Collector
+
+	for _, pod := range pods.Items {
+		if !isProblemPod(&pod) {
+			continue
+		}
 
-	return base, nil
+		collectors = append(collectors, NewCollector(
+			filepath.Join("kubernetesResources/problem-pods", pod.Namespace, pod.Name+".txt"),
+			describePod(client, &pod),
+		).WithName("k8s.problem-pods"))
+	}
+
+	return collectors, nil
 }
 
-// GetKubernetesCollectors creates all kubernetes API related collectors.
-func GetKubernetesCollectors(client *kubernetes.Clientset) []*Collector {
-	return []*Collector{
-		NewCollector("kubernetesResources/nodes.yaml", kubernetesNodes(client)),
-		NewCollector("kubernetesResources/systemPods.yaml", systemPods(client)),
+// GetDynamicCollectors creates collectors which use Kubernetes dynamic client: CRD inventory and the custom resources dumps.
+func GetDynamicCollectors(client dynamic.Interface, gvrs ...schema.GroupVersionResource) []*Collector {
+	collectors := []*Collector{
+		NewCollector("kubernetesResources/crds.txt", customResourceDefinitions(client)).WithName("k8s.crds"),
+	}
+
+	for _, gvr := range ciliumPolicies {
+		collectors = append(collectors, NewCollector(
+			fmt.Sprintf("kubernetesResources/networkPolicies/%s.yaml", gvr.Resource),
+			customResources(client, gvr, v1.NamespaceAll),
+		).WithName("k8s.network-policies."+gvr.Resource))
+	}
+
+	for _, gvr := range gatewayResources {
+		collectors = append(collectors, NewCollector(
+			fmt.Sprintf("kubernetesResources/ingress/%s.yaml", gvr.Resource),
+			customResources(client, gvr, v1.NamespaceAll),
+		).WithName("k8s.ingress."+gvr.Resource))
 	}
+
+	for _, gvr := range gvrs {
+		collectors = append(collectors, NewDynamicResourceCollector(client, gvr, v1.NamespaceAll))
+	}
+
+	return collectors
+}
+
+// NewDynamicResourceCollector creates a collector which dumps the resources of any kind using the dynamic client.
+//
+// Empty namespace collects the resources from all namespaces.
+func NewDynamicResourceCollector(client dynamic.Interface, gvr schema.GroupVersionResource, namespace string) *Collector {
+	return NewCollector(
+		filepath.Join("kubernetesResources/customResources", namespace, gvr.GroupResource().String()+".yaml"),
+		customResources(client, gvr, namespace),
+	).WithName("k8s.custom-resources." + gvr.GroupResource().String())
 }
 
 func getTalosResources(ctx context.Context, state state.State) ([]*Collector, error) {
@@ -179,15 +1158,161 @@ func getTalosResources(ctx context.Context, state state.State) ([]*Collector, er
 	var collectors []*Collector
 
 	rds.ForEach(func(res *meta.ResourceDefinition) {
-		collectors = append(collectors, NewCollector(
-			fmt.Sprintf("%s.yaml", res.Metadata().ID()),
-			talosResource(res),
-		))
+		collectors = append(collectors, NewTalosResourceCollector(res))
 	})
 
 	return collectors, nil
 }
 
+// selectNodes replaces the nodes in the options with the addresses of the Kubernetes nodes matching the node selectors.
+func selectNodes(ctx context.Context, options *bundle.Options) error {
+	if !options.SelectsNodes() {
+		return nil
+	}
+
+	if options.KubernetesClient == nil {
+		return errors.New("node selection requires the Kubernetes client")
+	}
+
+	nodes, err := audited(ctx, options, "k8s.nodes.select", func(ctx context.Context) ([]string, error) {
+		return kubernetesNodeAddresses(ctx, options)
+	})
+	if err != nil {
+		return err
+	}
+
+	options.Nodes = nodes
+
+	return nil
+}
+
+// discoverNodes sets the nodes to all cluster nodes if no nodes were provided.
+//
+// The Kubernetes nodes are used if the Kubernetes client is available, otherwise the Talos cluster members
+// are fetched via the Talos client endpoint. The discovery failures are logged, the Kubernetes data is still collected.
+func discoverNodes(ctx context.Context, options *bundle.Options) {
+	if !options.DiscoversNodes() || options.TalosClient == nil {
+		return
+	}
+
+	var (
+		nodes []string
+		err   error
+	)
+
+	if options.KubernetesClient != nil {
+		nodes, err = audited(ctx, options, "k8s.nodes.discover", func(ctx context.Context) ([]string, error) {
+			return kubernetesNodeAddresses(ctx, options)
+		})
+	} else {
+		nodes, err = audited(ctx, options, "talos.members.discover", func(ctx context.Context) ([]string, error) {
+			return talosMemberAddresses(ctx, options)
+		})
+	}
+
+	if err != nil {
+		options.Log("failed to discover nodes: %s", err)
+
+		return
+	}
+
+	options.Log("discovered %d nodes", len(nodes))
+
+	options.Nodes = nodes
+}
+
+// kubernetesNodeAddresses returns the internal IP addresses of the Kubernetes nodes matching the node selectors.
+//
+// The labels are matched against the node list shared with the node reports, so the nodes are listed once per bundle.
+func kubernetesNodeAddresses(ctx context.Context, options *bundle.Options) ([]string, error) {
+	selector, err := labels.Parse(options.NodeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node labels: %w", err)
+	}
+
+	nodes, err := listNodes(ctx, options, options.KubernetesClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var addresses []string
+
+	for _, node := range nodes.Items {
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+
+		info := bundle.NodeInfo{
+			Name:   node.Name,
+			Labels: node.Labels,
+		}
+
+		var internalIP string
+
+		for _, address := range node.Status.Addresses {
+			info.Addresses = append(info.Addresses, address.Address)
+
+			if address.Type == corev1.NodeInternalIP && internalIP == "" {
+				internalIP = address.Address
+			}
+		}
+
+		if options.NodeSelector != nil && !options.NodeSelector(info) {
+			continue
+		}
+
+		if internalIP == "" {
+			options.Log("skipping node %s without internal IP", node.Name)
+
+			continue
+		}
+
+		addresses = append(addresses, internalIP)
+	}
+
+	return addresses, nil
+}
+
+// talosMemberAddresses returns the first addresses of the Talos cluster members.
+//
+// The members are known only if the cluster discovery is enabled in the machine config.
+func talosMemberAddresses(ctx context.Context, options *bundle.Options) ([]string, error) {
+	members, err := safe.StateListAll[*cluster.Member](ctx, options.TalosClient.COSI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+
+	var addresses []string
+
+	members.ForEach(func(member *cluster.Member) {
+		spec := member.TypedSpec()
+
+		if len(spec.Addresses) == 0 {
+			options.Log("skipping cluster member %s without addresses", spec.Hostname)
+
+			return
+		}
+
+		addresses = append(addresses, spec.Addresses[0].String())
+	})
+
+	if len(addresses) == 0 {
+		return nil, errors.New("no cluster members found, is the cluster discovery enabled?")
+	}
+
+	return addresses, nil
+}
+
+// getMachineType returns the machine type of the node the context points to.
+func getMachineType(ctx context.Context, c *client.Client) (machine.Type, error) {
+	machineType, err := safe.StateGet[*config.MachineType](ctx, c.COSI, config.NewMachineType().Metadata())
+	if err != nil {
+		return machine.TypeUnknown, err
+	}
+
+	return machineType.MachineType(), nil
+}
+
 func getServiceLogCollectors(ctx context.Context, c *client.Client) ([]*Collector, error) {
 	resp, err := c.ServiceList(ctx)
 	if err != nil {
@@ -200,8 +1325,8 @@ func getServiceLogCollectors(ctx context.Context, c *client.Client) ([]*Collecto
 		for _, s := range msg.Services {
 			collectors = append(
 				collectors,
-				NewCollector(fmt.Sprintf("%s.log", s.Id), logs(s.Id, false)),
-				NewCollector(fmt.Sprintf("%s.state", s.Id), serviceInfo(s.Id)),
+				NewStreamCollector(fmt.Sprintf("%s.log", s.Id), logs(s.Id, s.Id, false)).WithName("talos.logs."+s.Id),
+				NewCollector(fmt.Sprintf("%s.state", s.Id), serviceInfo(s.Id)).WithName("talos.service."+s.Id),
 			)
 		}
 	}
@@ -209,11 +1334,13 @@ func getServiceLogCollectors(ctx context.Context, c *client.Client) ([]*Collecto
 	return collectors, nil
 }
 
-func getKubernetesLogCollectors(ctx context.Context, c *client.Client) ([]*Collector, error) {
+func getKubernetesLogCollectors(ctx context.Context, c *client.Client, options *bundle.Options, allNamespaces bool) ([]*Collector, error) {
 	namespace := constants.K8sContainerdNamespace
 	driver := common.ContainerDriver_CRI
 
-	resp, err := c.Containers(ctx, namespace, driver)
+	resp, err := audited(ctx, options, "talos.kubernetes-logs", func(ctx context.Context) (*machineapi.ContainersResponse, error) {
+		return c.Containers(ctx, namespace, driver)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -235,17 +1362,109 @@ func getKubernetesLogCollectors(ctx context.Context, c *client.Client) ([]*Colle
 				exited = "-exited"
 			}
 
-			if parts[0] == "kube-system" {
+			switch {
+			case parts[0] == "kube-system":
 				collectors = append(
 					collectors,
-					NewCollector(
+					NewStreamCollector(
 						fmt.Sprintf("%s/%s%s.log", parts[0], container.Name, exited),
-						logs(container.Id, true),
-					),
+						logs(container.Id, container.Name, true),
+					).WithName("talos.kubernetes-logs"),
+				)
+			case allNamespaces && len(parts) == 2:
+				// container names are not unique outside of kube-system, so group the logs by pod
+				collectors = append(
+					collectors,
+					NewStreamCollector(
+						fmt.Sprintf("%s/%s/%s%s.log", parts[0], parts[1], container.Name, exited),
+						logs(container.Id, container.Name, true),
+					).WithName("talos.kubernetes-logs"),
 				)
 			}
 		}
 	}
 
-	return collectors, err
+	// the log history is optional, the failure to list the log files is recorded for the node instead of aborting the bundle
+	history, err := audited(ctx, options, "talos.kubernetes-logs.history", func(ctx context.Context) ([]*Collector, error) {
+		return getKubernetesLogHistoryCollectors(ctx, c)
+	})
+	if err != nil {
+		history = []*Collector{newFailedCollector("kube-system/history", "talos.kubernetes-logs.history", err)}
+	}
+
+	return append(collectors, history...), nil
+}
+
+// kubernetesPodLogsPath is the directory where kubelet keeps the container logs of each pod instance.
+const kubernetesPodLogsPath = "/var/log/pods"
+
+// getKubernetesLogHistoryCollectors creates collectors which read the log files of the previous kube-system container instances
+// and the rotated log files directly from the node.
+//
+// The log of the latest instance of each container is skipped as it is collected using CRI.
+func getKubernetesLogHistoryCollectors(ctx context.Context, c *client.Client) ([]*Collector, error) {
+	stream, err := c.LS(ctx, &machineapi.ListRequest{
+		Root:           kubernetesPodLogsPath,
+		Recurse:        true,
+		RecursionDepth: 3,
+		Types:          []machineapi.ListRequest_Type{machineapi.ListRequest_REGULAR},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files  []string
+		latest = map[string]int{}
+	)
+
+	for {
+		info, recvErr := stream.Recv()
+		if recvErr != nil {
+			if errors.Is(recvErr, io.EOF) || client.StatusCode(recvErr) == codes.Canceled {
+				break
+			}
+
+			return nil, fmt.Errorf("error reading from stream: %w", recvErr)
+		}
+
+		if info.Error != "" {
+			continue
+		}
+
+		// <namespace>_<pod>_<uid>/<container>/<instance>.log, rotated logs get a timestamp suffix
+		parts := strings.Split(info.RelativeName, "/")
+		if len(parts) != 3 || !strings.HasPrefix(parts[0], "kube-system_") {
+			continue
+		}
+
+		files = append(files, info.RelativeName)
+
+		if instance, ok := strings.CutSuffix(parts[2], ".log"); ok {
+			if n, parseErr := strconv.Atoi(instance); parseErr == nil {
+				dir := filepath.Dir(info.RelativeName)
+
+				if current, seen := latest[dir]; !seen || n > current {
+					latest[dir] = n
+				}
+			}
+		}
+	}
+
+	var collectors []*Collector
+
+	for _, file := range files {
+		dir := filepath.Dir(file)
+
+		if n, ok := latest[dir]; ok && filepath.Base(file) == fmt.Sprintf("%d.log", n) {
+			continue
+		}
+
+		collectors = append(collectors, NewStreamCollector(
+			filepath.Join("kube-system/history", file),
+			nodeFile(filepath.Join(kubernetesPodLogsPath, file)),
+		).WithName("talos.kubernetes-logs.history"))
+	}
+
+	return collectors, nil
 }
@@ -0,0 +1,840 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
+	"github.com/siderolabs/talos/pkg/machinery/resources/cluster"
+	"github.com/siderolabs/talos/pkg/machinery/resources/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+func findCollector(cols []*collectors.Collector, name string) *collectors.Collector {
+	for _, col := range cols {
+		if col.Name() == name {
+			return col
+		}
+	}
+
+	return nil
+}
+
+// runCollectors runs the collectors one by one writing the results to the archive.
+func runCollectors(ctx context.Context, options *bundle.Options, cols []*collectors.Collector) error {
+	for _, col := range cols {
+		if err := col.Run(ctx, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestFailedCollectorGroups(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := fake.NewSimpleClientset()
+
+	for _, resource := range []string{"nodes", "daemonsets", "pods"} {
+		client.PrependReactor("list", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New(resource + " are unavailable")
+		})
+	}
+
+	options := bundle.NewOptions(
+		bundle.WithKubernetesClient(client),
+		bundle.WithoutTalos(),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	for name, expected := range map[string]string{
+		"k8s.kubelet":       "nodes are unavailable",
+		"k8s.cni":           "daemonsets are unavailable",
+		"k8s.previous-logs": "pods are unavailable",
+		"k8s.problem-pods":  "pods are unavailable",
+	} {
+		col := findCollector(cols, name)
+		require.NotNil(col, name)
+
+		require.ErrorContains(col.Run(ctx, options), expected)
+	}
+}
+
+func TestCNICollectorsFailedPods(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := fake.NewSimpleClientset(&appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-flannel", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "flannel"}},
+		},
+	})
+
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("pods are unavailable")
+	})
+
+	cols, err := collectors.GetCNICollectors(ctx, client)
+	require.NoError(err)
+
+	require.NotNil(findCollector(cols, "k8s.cni.flannel.configmaps"))
+
+	logs := findCollector(cols, "k8s.cni.flannel.logs")
+	require.NotNil(logs)
+	require.ErrorContains(logs.Run(ctx, bundle.NewOptions(bundle.WithLogOutput(io.Discard))), "pods are unavailable")
+}
+
+// fakeMachineClient implements the Talos machine API calls made while the node collectors are created.
+type fakeMachineClient struct {
+	machineapi.MachineServiceClient
+
+	listErr error
+
+	// unreachableNode fails all calls proxied to the node the same way apid does for the node which is down
+	unreachableNode string
+}
+
+func (c *fakeMachineClient) Containers(ctx context.Context, _ *machineapi.ContainersRequest, _ ...grpc.CallOption) (*machineapi.ContainersResponse, error) {
+	if err := checkReachable(ctx, c.unreachableNode); err != nil {
+		return nil, err
+	}
+
+	return &machineapi.ContainersResponse{}, nil
+}
+
+func (c *fakeMachineClient) ServiceList(ctx context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (*machineapi.ServiceListResponse, error) {
+	if err := checkReachable(ctx, c.unreachableNode); err != nil {
+		return nil, err
+	}
+
+	return &machineapi.ServiceListResponse{}, nil
+}
+
+func (c *fakeMachineClient) List(context.Context, *machineapi.ListRequest, ...grpc.CallOption) (machineapi.MachineService_ListClient, error) {
+	return nil, c.listErr
+}
+
+// unreachableState fails the calls to the node which is down.
+type unreachableState struct {
+	state.CoreState
+
+	node string
+}
+
+func (s *unreachableState) Get(ctx context.Context, ptr resource.Pointer, opts ...state.GetOption) (resource.Resource, error) { //nolint:ireturn
+	if err := checkReachable(ctx, s.node); err != nil {
+		return nil, err
+	}
+
+	return s.CoreState.Get(ctx, ptr, opts...)
+}
+
+func (s *unreachableState) List(ctx context.Context, kind resource.Kind, opts ...state.ListOption) (resource.List, error) {
+	if err := checkReachable(ctx, s.node); err != nil {
+		return resource.List{}, err
+	}
+
+	return s.CoreState.List(ctx, kind, opts...)
+}
+
+// checkReachable fails if the context points to the unreachable node.
+func checkReachable(ctx context.Context, unreachableNode string) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	if unreachableNode != "" && slices.Contains(md.Get("node"), unreachableNode) {
+		return status.Errorf(codes.Unavailable, "node %s is not reachable", unreachableNode)
+	}
+
+	return nil
+}
+
+func TestKubernetesLogHistoryFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	talosClient := &client.Client{
+		MachineClient: &fakeMachineClient{listErr: status.Error(codes.PermissionDenied, "access denied")},
+		COSI:          state.WrapCore(namespaced.NewState(inmem.Build)),
+	}
+
+	cols, err := collectors.GetTalosNodeCollectors(ctx, talosClient)
+	require.NoError(err)
+
+	history := findCollector(cols, "talos.kubernetes-logs.history")
+	require.NotNil(history)
+	require.Equal("kubernetes-logs/kube-system/history", history.Path())
+	require.ErrorContains(history.Run(ctx, bundle.NewOptions(bundle.WithLogOutput(io.Discard))), "access denied")
+}
+
+func TestExtraCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithoutTalos(),
+		bundle.WithoutKubernetes(),
+		bundle.WithLogOutput(io.Discard),
+		collectors.WithExtraCollectors(collectors.NewCollector("custom/hello.txt", func(context.Context, *bundle.Options) ([]byte, error) {
+			return []byte("hello"), nil
+		}).WithName("custom.hello")),
+	)
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	hello := findCollector(cols, "custom.hello")
+	require.NotNil(hello)
+	require.Equal("custom/hello.txt", hello.Path())
+	require.Equal(collectors.Cluster, hello.Source())
+
+	require.NoError(hello.Run(ctx, options))
+	require.Equal("hello", string(archive.files["custom/hello.txt"]))
+}
+
+func TestTalosNodeCollectorsMachineType(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	for _, test := range []struct {
+		machineType machine.Type
+		etcd        bool
+	}{
+		{machineType: machine.TypeControlPlane, etcd: true},
+		{machineType: machine.TypeWorker, etcd: false},
+		// the machine type lookup fails, so the etcd collectors are created too
+		{machineType: machine.TypeUnknown, etcd: true},
+	} {
+		t.Run(test.machineType.String(), func(t *testing.T) {
+			require := require.New(t)
+
+			cosi := state.WrapCore(namespaced.NewState(inmem.Build))
+
+			if test.machineType != machine.TypeUnknown {
+				machineType := config.NewMachineType()
+				machineType.SetMachineType(test.machineType)
+
+				require.NoError(cosi.Create(ctx, machineType))
+			}
+
+			cols, err := collectors.GetTalosNodeCollectors(ctx, &client.Client{MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented")}, COSI: cosi})
+			require.NoError(err)
+
+			require.Equal(test.etcd, findCollector(cols, "talos.etcd-status") != nil)
+		})
+	}
+}
+
+func TestMachineTypeLookupFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	for _, option := range []bundle.Option{bundle.WithControlPlaneOnly(), bundle.WithWorkersOnly()} {
+		// the machine type isn't set in the state
+		options := bundle.NewOptions(
+			bundle.WithTalosClient(&client.Client{
+				MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented")},
+				COSI:          state.WrapCore(namespaced.NewState(inmem.Build)),
+			}),
+			bundle.WithNodes("10.5.0.2"),
+			bundle.WithoutKubernetes(),
+			bundle.WithLogOutput(io.Discard),
+			option,
+		)
+
+		cols, err := collectors.GetForOptions(ctx, options)
+		require.NoError(err)
+
+		sources := map[string][]string{}
+
+		for _, col := range cols {
+			sources[col.Source()] = append(sources[col.Source()], col.Name())
+		}
+
+		require.Contains(sources["10.5.0.2"], "talos.summary", "the node is not skipped")
+		require.Contains(sources["10.5.0.2"], "talos.etcd-status")
+	}
+}
+
+func TestTalosNodeDiscovery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	cosi := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	for id, spec := range map[string]cluster.MemberSpec{
+		"controlplane": {Hostname: "talos-cp-1", Addresses: []netip.Addr{netip.MustParseAddr("172.20.0.2"), netip.MustParseAddr("fd00::2")}},
+		"worker":       {Hostname: "talos-worker-1", Addresses: []netip.Addr{netip.MustParseAddr("172.20.0.5")}},
+		"no-addresses": {Hostname: "talos-worker-2"},
+	} {
+		member := cluster.NewMember(cluster.NamespaceName, id)
+		*member.TypedSpec() = spec
+
+		require.NoError(cosi.Create(ctx, member))
+	}
+
+	options := bundle.NewOptions(
+		bundle.WithTalosClient(&client.Client{MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented")}, COSI: cosi}),
+		bundle.WithoutKubernetes(),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	require.ElementsMatch([]string{"172.20.0.2", "172.20.0.5"}, options.Nodes)
+
+	sources := map[string]struct{}{}
+
+	for _, col := range cols {
+		sources[col.Source()] = struct{}{}
+	}
+
+	require.Contains(sources, "172.20.0.2")
+	require.Contains(sources, "172.20.0.5")
+}
+
+func TestUnreachableDiscoveredNode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	cosi := namespaced.NewState(inmem.Build)
+
+	for id, address := range map[string]string{
+		"controlplane": "172.20.0.2",
+		"worker":       "172.20.0.5",
+	} {
+		member := cluster.NewMember(cluster.NamespaceName, id)
+		member.TypedSpec().Addresses = []netip.Addr{netip.MustParseAddr(address)}
+
+		require.NoError(cosi.Create(ctx, member))
+	}
+
+	options := bundle.NewOptions(
+		bundle.WithTalosClient(&client.Client{
+			MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented"), unreachableNode: "172.20.0.5"},
+			COSI:          state.WrapCore(&unreachableState{CoreState: cosi, node: "172.20.0.5"}),
+		}),
+		bundle.WithoutKubernetes(),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	require.ElementsMatch([]string{"172.20.0.2", "172.20.0.5"}, options.Nodes)
+
+	names := map[string][]string{}
+
+	for _, col := range cols {
+		names[col.Source()] = append(names[col.Source()], col.Name())
+	}
+
+	require.Contains(names["172.20.0.2"], "talos.summary")
+	require.Contains(names["172.20.0.5"], "talos.summary", "the collectors of the unreachable node are still created")
+
+	nodeCollector := func(node, name string) *collectors.Collector {
+		for _, col := range cols {
+			if col.Source() == node && col.Name() == name {
+				return col
+			}
+		}
+
+		return nil
+	}
+
+	for _, name := range []string{"talos.resources", "talos.kubernetes-logs", "talos.service-logs"} {
+		col := nodeCollector("172.20.0.5", name)
+		require.NotNil(col, name)
+
+		require.ErrorContains(col.Run(ctx, options), "node 172.20.0.5 is not reachable", name)
+	}
+}
+
+func TestMachineConfigCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	input, err := generate.NewInput("test", "https://10.5.0.2:6443", "1.31.0")
+	require.NoError(err)
+
+	provider, err := input.Config(machine.TypeControlPlane)
+	require.NoError(err)
+
+	cosi := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	require.NoError(cosi.Create(ctx, config.NewMachineConfig(provider)))
+
+	talosClient := &client.Client{MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented")}, COSI: cosi}
+
+	newOptions := func(opts ...bundle.Option) (*bundle.Options, *testArchive) {
+		archive := &testArchive{}
+
+		return bundle.NewOptions(append([]bundle.Option{
+			bundle.WithArchive(archive),
+			bundle.WithTalosClient(talosClient),
+			bundle.WithNodes("10.5.0.2"),
+			bundle.WithoutKubernetes(),
+			bundle.WithLogOutput(io.Discard),
+		}, opts...)...), archive
+	}
+
+	options, _ := newOptions()
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+	require.Nil(findCollector(cols, "talos.machine-config"), "the machine config is collected only if enabled")
+
+	options, archive := newOptions(bundle.WithMachineConfig())
+
+	cols, err = collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	machineConfig := findCollector(cols, "talos.machine-config")
+	require.NotNil(machineConfig)
+	require.NoError(machineConfig.Run(ctx, options))
+
+	data := string(archive.files[machineConfig.Path()])
+	require.Contains(data, "clusterName: test")
+	require.NotContains(data, provider.RawV1Alpha1().MachineConfig.MachineToken)
+	require.NotContains(data, provider.RawV1Alpha1().ClusterConfig.ClusterSecret)
+}
+
+func TestCollectorsAudit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	talosClient, err := client.New(ctx, client.WithConfig(&clientconfig.Config{
+		Context:  "test",
+		Contexts: map[string]*clientconfig.Context{"test": {Endpoints: []string{"10.5.0.1"}}},
+	}))
+	require.NoError(err)
+
+	t.Cleanup(func() { talosClient.Close() }) //nolint:errcheck
+
+	talosClient.MachineClient = &fakeMachineClient{listErr: status.Error(codes.PermissionDenied, "access denied")}
+	talosClient.COSI = state.WrapCore(namespaced.NewState(inmem.Build))
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithTalosClient(talosClient),
+		bundle.WithNodes("10.5.0.2"),
+		bundle.WithoutKubernetes(),
+		bundle.WithAuditLog(),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	_, err = collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	require.NoError(options.WriteAuditLog())
+
+	entries := map[string]bundle.AuditEntry{}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(archive.files[bundle.AuditLogFile])), "\n") {
+		var entry bundle.AuditEntry
+
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+
+		entries[entry.Collector] = entry
+	}
+
+	for _, name := range []string{
+		"talos.machine-type",
+		"talos.resources",
+		"talos.kubernetes-logs",
+		"talos.kubernetes-logs.history",
+		"talos.service-logs",
+	} {
+		entry, ok := entries[name]
+		require.True(ok, name)
+
+		require.Equal("10.5.0.2", entry.Node, name)
+		require.Equal("10.5.0.1", entry.Endpoint, name)
+		require.Empty(entry.Path, name)
+	}
+
+	// the machine type isn't set in the fake state
+	require.Equal(bundle.AuditOutcomeFailed, entries["talos.machine-type"].Outcome)
+	require.Equal(bundle.AuditOutcomeOK, entries["talos.service-logs"].Outcome)
+	require.Equal(bundle.AuditOutcomeFailed, entries["talos.kubernetes-logs.history"].Outcome)
+	require.Contains(entries["talos.kubernetes-logs.history"].Error, "access denied")
+}
+
+func TestKubernetesCollectorsAudit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := fake.NewSimpleClientset()
+
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("pods are unavailable")
+	})
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithKubernetesClient(client),
+		bundle.WithoutTalos(),
+		bundle.WithAuditLog(),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	_, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	require.NoError(options.WriteAuditLog())
+
+	outcomes := map[string]string{}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(archive.files[bundle.AuditLogFile])), "\n") {
+		var entry bundle.AuditEntry
+
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+		require.Empty(entry.Node)
+
+		outcomes[entry.Collector] = entry.Outcome
+	}
+
+	require.Equal(map[string]string{
+		"k8s.kubelet":       bundle.AuditOutcomeOK,
+		"k8s.cni":           bundle.AuditOutcomeOK,
+		"k8s.previous-logs": bundle.AuditOutcomeOK,
+		"k8s.problem-pods":  bundle.AuditOutcomeFailed,
+	}, outcomes)
+}
+
+func TestInClusterNodeSelection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1", Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""}},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "talos-cp-1"},
+				{Type: corev1.NodeInternalIP, Address: "172.20.0.2"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-worker-1"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "172.20.0.5"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-worker-2"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "talos-worker-2"},
+			}},
+		},
+	}
+
+	// the fake API server supports only the control plane label selector
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		list := &corev1.NodeList{TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"}}
+
+		for _, node := range nodes {
+			if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok || r.URL.Query().Get("labelSelector") == "" {
+				list.Items = append(list.Items, node)
+			}
+		}
+
+		respondJSON(list)(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	inClusterConfig := bundle.InClusterConfig
+
+	t.Cleanup(func() { bundle.InClusterConfig = inClusterConfig })
+
+	bundle.InClusterConfig = func() (*rest.Config, error) {
+		return &rest.Config{Host: srv.URL}, nil
+	}
+
+	talosClient := &client.Client{MachineClient: &fakeMachineClient{listErr: status.Error(codes.Unimplemented, "not implemented")}, COSI: state.WrapCore(namespaced.NewState(inmem.Build))}
+
+	for _, test := range []struct {
+		name     string
+		opts     []bundle.Option
+		expected []string
+	}{
+		{
+			name:     "all nodes",
+			expected: []string{"172.20.0.2", "172.20.0.5"},
+		},
+		{
+			name:     "node labels",
+			opts:     []bundle.Option{bundle.WithNodeLabels("node-role.kubernetes.io/control-plane")},
+			expected: []string{"172.20.0.2"},
+		},
+		{
+			name: "node selector",
+			opts: []bundle.Option{bundle.WithNodeSelector(func(node bundle.NodeInfo) bool {
+				return node.Name == "talos-worker-1"
+			})},
+			expected: []string{"172.20.0.5"},
+		},
+		{
+			name:     "explicit nodes",
+			opts:     []bundle.Option{bundle.WithNodes("172.20.0.9")},
+			expected: []string{"172.20.0.9"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			options := bundle.NewOptions(append([]bundle.Option{
+				bundle.WithInCluster(),
+				bundle.WithTalosClient(talosClient),
+				bundle.WithoutKubernetes(),
+				bundle.WithLogOutput(io.Discard),
+			}, test.opts...)...)
+
+			cols, err := collectors.GetForOptions(ctx, options)
+			require.NoError(err)
+
+			require.Equal(test.expected, options.Nodes)
+
+			sources := map[string]struct{}{}
+
+			for _, col := range cols {
+				if col.Source() != collectors.Cluster {
+					sources[col.Source()] = struct{}{}
+				}
+			}
+
+			require.Len(sources, len(test.expected))
+
+			for _, node := range test.expected {
+				require.Contains(sources, node)
+			}
+		})
+	}
+}
+
+func TestInClusterTalosconfig(t *testing.T) {
+	require := require.New(t)
+
+	talosconfig := filepath.Join(t.TempDir(), "config")
+
+	require.NoError((&clientconfig.Config{
+		Context:  "in-cluster",
+		Contexts: map[string]*clientconfig.Context{"in-cluster": {Endpoints: []string{"10.96.0.10"}}},
+	}).Save(talosconfig))
+
+	inClusterTalosconfig := bundle.InClusterTalosconfig
+
+	t.Cleanup(func() { bundle.InClusterTalosconfig = inClusterTalosconfig })
+
+	bundle.InClusterTalosconfig = talosconfig
+
+	options := bundle.NewOptions(bundle.WithInCluster(), bundle.WithLogOutput(io.Discard))
+
+	require.NoError(options.InitTalosClient(context.Background()))
+	require.NotNil(options.TalosClient)
+	require.Equal([]string{"10.96.0.10"}, options.TalosClient.GetEndpoints())
+	require.NoError(options.Close())
+}
+
+func TestCollectorNames(t *testing.T) {
+	require := require.New(t)
+
+	hello := func(context.Context, *bundle.Options) ([]byte, error) {
+		return []byte("hello"), nil
+	}
+
+	cols := collectors.WithNode([]*collectors.Collector{
+		collectors.NewCollector("kube-system/hello.log", hello),
+		collectors.NewCollector("hello.txt", hello).WithName("custom.hello"),
+	}, "n1")
+
+	require.Equal("kube-system.hello", cols[0].Name())
+	require.Equal("custom.hello", cols[1].Name())
+	require.Equal("n1/hello.txt", cols[1].Path())
+
+	names := map[string]struct{}{}
+
+	for _, col := range collectors.GetKubernetesCollectors(fake.NewSimpleClientset()) {
+		require.True(strings.HasPrefix(col.Name(), "k8s."), col.Name())
+		require.NotContains(names, col.Name())
+
+		names[col.Name()] = struct{}{}
+	}
+}
+
+func TestGetForOptionsProfiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	for _, test := range []struct {
+		name     string
+		options  []bundle.Option
+		expected []string
+		missing  []string
+		exact    bool
+	}{
+		{
+			name:     "standard",
+			options:  []bundle.Option{bundle.WithPodLogSelector("default", "app=web")},
+			expected: []string{"k8s.services", "k8s.crds", "k8s.kubelet.stats-summary", "k8s.previous-logs", "k8s.problem-pods", "k8s.pod-logs", "cluster.version-skew"},
+			missing:  []string{"k8s.kube-proxy.rules"},
+		},
+		{
+			name:     "full",
+			options:  []bundle.Option{bundle.WithProfile(bundle.ProfileFull)},
+			expected: []string{"k8s.services", "k8s.kube-proxy.rules"},
+		},
+		{
+			name:     "minimal",
+			options:  []bundle.Option{bundle.WithProfile(bundle.ProfileMinimal), bundle.WithPodLogSelector("default", "app=web")},
+			expected: []string{"k8s.nodes", "k8s.nodes-summary", "k8s.system-pods", "cluster.version-skew"},
+			exact:    true,
+		},
+		{
+			name:    "without kubernetes",
+			options: []bundle.Option{bundle.WithoutKubernetes()},
+			missing: []string{"k8s.nodes", "k8s.crds", "cluster.version-skew"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			options := bundle.NewOptions(append([]bundle.Option{
+				bundle.WithKubernetesClient(testClusterClient(t)),
+				bundle.WithDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())),
+				bundle.WithoutTalos(),
+				bundle.WithLogOutput(io.Discard),
+			}, test.options...)...)
+
+			cols, err := collectors.GetForOptions(ctx, options)
+			require.NoError(err)
+
+			names := map[string]struct{}{}
+
+			for _, col := range cols {
+				names[col.Name()] = struct{}{}
+			}
+
+			for _, name := range test.expected {
+				require.Contains(names, name)
+			}
+
+			for _, name := range test.missing {
+				require.NotContains(names, name)
+			}
+
+			if test.exact {
+				require.Len(names, len(test.expected))
+			}
+		})
+	}
+}
+
+func TestVersionSkew(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithKubernetesClient(testClusterClient(t)),
+		bundle.WithoutTalos(),
+		bundle.WithProfile(bundle.ProfileMinimal),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	skew := findCollector(cols, "cluster.version-skew")
+	require.NotNil(skew)
+	require.NoError(skew.Run(ctx, options))
+
+	data := string(archive.files["version-skew.txt"])
+
+	require.NotContains(data, "Talos:")
+	require.Regexp(`kube-apiserver\s+talos-cp-2\s+v1.29.6`, data)
+	require.Regexp(`talos-worker-1\s+v1.26.0`, data)
+	require.Contains(data, "- kube-controller-manager 1.30.3 is newer than the oldest kube-apiserver 1.29.6\n")
+	require.Contains(data, "- kubelet 1.26.0 is more than 3 minor versions older than kube-apiserver 1.30.3\n")
+	require.NotContains(data, "instances differ")
+}
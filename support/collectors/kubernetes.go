@@ -7,20 +7,39 @@ package collectors
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 
 	"github.com/siderolabs/go-talos-support/support/bundle"
 )
 
-func kubernetesNodes(client *kubernetes.Clientset) Collect {
+func kubernetesNodes(client kubernetes.Interface) Collect {
 	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		options.Log("getting kubernetes nodes manifests")
 
-		nodes, err := client.CoreV1().Nodes().List(ctx, v1.ListOptions{})
+		nodes, err := listNodes(ctx, options, client)
 		if err != nil {
 			return nil, err
 		}
@@ -29,11 +48,11 @@ func kubernetesNodes(client *kubernetes.Clientset) Collect {
 	}
 }
 
-func systemPods(client *kubernetes.Clientset) Collect {
+func systemPods(client kubernetes.Interface) Collect {
 	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		options.Log("getting pods manifests in kube-system namespace")
 
-		nodes, err := client.CoreV1().Pods("kube-system").List(ctx, v1.ListOptions{})
+		nodes, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().Pods("kube-system").List)
 		if err != nil {
 			return nil, err
 		}
@@ -42,6 +61,1056 @@ func systemPods(client *kubernetes.Clientset) Collect {
 	}
 }
 
+func namespaces(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting namespaces manifests")
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().Namespaces().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func services(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting services manifests")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Services(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func endpoints(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting endpoints manifests")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Endpoints(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func endpointSlices(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting endpoint slices manifests")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.DiscoveryV1().EndpointSlices(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func resourceQuotas(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting resource quotas manifests")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().ResourceQuotas(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func limitRanges(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting limit ranges manifests")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().LimitRanges(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func kubernetesEvents(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting events")
+
+		return listNamespaced(ctx, options.KubernetesNamespaces(), func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Events(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func persistentVolumes(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting persistent volumes manifests")
+
+		pvs, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().PersistentVolumes().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(pvs)
+	}
+}
+
+func persistentVolumeClaims(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting persistent volume claims manifests")
+
+		pvcs, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().PersistentVolumeClaims(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(pvcs)
+	}
+}
+
+func storageClasses(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting storage classes manifests")
+
+		scs, err := listAll(ctx, v1.ListOptions{}, client.StorageV1().StorageClasses().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(scs)
+	}
+}
+
+func csiDrivers(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting CSI drivers manifests")
+
+		drivers, err := listAll(ctx, v1.ListOptions{}, client.StorageV1().CSIDrivers().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(drivers)
+	}
+}
+
+func csiNodes(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting CSI nodes manifests")
+
+		nodes, err := listAll(ctx, v1.ListOptions{}, client.StorageV1().CSINodes().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(nodes)
+	}
+}
+
+func volumeAttachments(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting volume attachments manifests")
+
+		attachments, err := listAll(ctx, v1.ListOptions{}, client.StorageV1().VolumeAttachments().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(attachments)
+	}
+}
+
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+func customResourceDefinitions(client dynamic.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting custom resource definitions")
+
+		crds, err := listAll(ctx, v1.ListOptions{}, client.Resource(crdGVR).List)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tGROUP\tKIND\tSCOPE\tVERSIONS\tCREATED") //nolint:errcheck
+
+		for _, crd := range crds.Items {
+			versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions") //nolint:errcheck
+
+			served := make([]string, 0, len(versions))
+
+			for _, v := range versions {
+				version, ok := v.(map[string]any)
+				if !ok || !nestedBool(version, "served") {
+					continue
+				}
+
+				name := nestedString(version, "name")
+
+				if nestedBool(version, "deprecated") {
+					name += "(deprecated)"
+				}
+
+				served = append(served, name)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+				crd.GetName(),
+				nestedString(crd.Object, "spec", "group"),
+				nestedString(crd.Object, "spec", "names", "kind"),
+				nestedString(crd.Object, "spec", "scope"),
+				strings.Join(served, ","),
+				crd.GetCreationTimestamp().UTC().Format(time.RFC3339),
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func customResources(client dynamic.Interface, gvr schema.GroupVersionResource, namespace string) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting %s manifests", gvr.GroupResource())
+
+		resources, err := listAll(ctx, v1.ListOptions{}, client.Resource(gvr).Namespace(namespace).List)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				options.Log("%s is not served by the API server, skipping", gvr.GroupResource())
+
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		if len(resources.Items) == 0 {
+			return nil, nil
+		}
+
+		return marshalKubernetesResources(resources)
+	}
+}
+
+func apiServices(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting API services manifests")
+
+		list, err := getRawList(ctx, client, "/apis/apiregistration.k8s.io/v1/apiservices")
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func networkPolicies(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting network policies manifests")
+
+		policies, err := listAll(ctx, v1.ListOptions{}, client.NetworkingV1().NetworkPolicies(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(policies)
+	}
+}
+
+func ingresses(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting ingresses manifests")
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.NetworkingV1().Ingresses(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func ingressClasses(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting ingress classes manifests")
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.NetworkingV1().IngressClasses().List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func clusterRoleBindings(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting system cluster role bindings manifests")
+
+		bindings, err := systemClusterRoleBindings(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(bindings)
+	}
+}
+
+func roleBindings(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting system role bindings manifests")
+
+		bindings, err := systemRoleBindings(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(bindings)
+	}
+}
+
+func clusterRoles(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting system cluster roles manifests")
+
+		referenced := map[string]struct{}{}
+
+		clusterBindings, err := systemClusterRoleBindings(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, binding := range clusterBindings.Items {
+			referenced[binding.RoleRef.Name] = struct{}{}
+		}
+
+		bindings, err := systemRoleBindings(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, binding := range bindings.Items {
+			if binding.RoleRef.Kind == "ClusterRole" {
+				referenced[binding.RoleRef.Name] = struct{}{}
+			}
+		}
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.RbacV1().ClusterRoles().List)
+		if err != nil {
+			return nil, err
+		}
+
+		list.Items = slices.DeleteFunc(list.Items, func(role rbacv1.ClusterRole) bool {
+			_, ok := referenced[role.Name]
+
+			return !ok && !isSystemName(role.Name)
+		})
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func roles(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting system roles manifests")
+
+		referenced := map[string]struct{}{}
+
+		bindings, err := systemRoleBindings(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, binding := range bindings.Items {
+			if binding.RoleRef.Kind == "Role" {
+				referenced[binding.Namespace+"/"+binding.RoleRef.Name] = struct{}{}
+			}
+		}
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.RbacV1().Roles(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		list.Items = slices.DeleteFunc(list.Items, func(role rbacv1.Role) bool {
+			_, ok := referenced[role.Namespace+"/"+role.Name]
+
+			return !ok && role.Namespace != "kube-system"
+		})
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func systemClusterRoleBindings(ctx context.Context, client kubernetes.Interface) (*rbacv1.ClusterRoleBindingList, error) {
+	bindings, err := listAll(ctx, v1.ListOptions{}, client.RbacV1().ClusterRoleBindings().List)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings.Items = slices.DeleteFunc(bindings.Items, func(binding rbacv1.ClusterRoleBinding) bool {
+		return !isSystemBinding(binding.Name, binding.Subjects)
+	})
+
+	return bindings, nil
+}
+
+func systemRoleBindings(ctx context.Context, client kubernetes.Interface) (*rbacv1.RoleBindingList, error) {
+	bindings, err := listAll(ctx, v1.ListOptions{}, client.RbacV1().RoleBindings(v1.NamespaceAll).List)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings.Items = slices.DeleteFunc(bindings.Items, func(binding rbacv1.RoleBinding) bool {
+		return binding.Namespace != "kube-system" && !isSystemBinding(binding.Name, binding.Subjects)
+	})
+
+	return bindings, nil
+}
+
+// isSystemBinding returns true for the bindings created by Kubernetes or Talos, or granting access to system subjects.
+func isSystemBinding(name string, subjects []rbacv1.Subject) bool {
+	if isSystemName(name) {
+		return true
+	}
+
+	return slices.ContainsFunc(subjects, func(subject rbacv1.Subject) bool {
+		switch subject.Kind {
+		case rbacv1.ServiceAccountKind:
+			return subject.Namespace == "kube-system"
+		case rbacv1.UserKind, rbacv1.GroupKind:
+			return isSystemName(subject.Name)
+		default:
+			return false
+		}
+	})
+}
+
+func isSystemName(name string) bool {
+	return strings.HasPrefix(name, "system:") || strings.HasPrefix(name, "talos")
+}
+
+func podDisruptionBudgets(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting pod disruption budgets manifests")
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.PolicyV1().PodDisruptionBudgets(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func leases(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting leases manifests")
+
+		return listNamespaced(ctx, []string{"kube-system", "kube-node-lease"}, func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error) {
+			return client.CoordinationV1().Leases(namespace).List(ctx, opts)
+		})
+	}
+}
+
+func nodeMetrics(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting nodes metrics")
+
+		list, err := getRawList(ctx, client, "/apis/metrics.k8s.io/v1beta1/nodes")
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCPU(cores)\tMEMORY(bytes)") //nolint:errcheck
+
+		for _, item := range list.Items {
+			usage, _, _ := unstructured.NestedStringMap(item.Object, "usage") //nolint:errcheck
+
+			cpu, memory := parseUsage(usage)
+
+			fmt.Fprintf(w, "%s\t%dm\t%dMi\n", item.GetName(), cpu.MilliValue(), memory.Value()/(1024*1024)) //nolint:errcheck
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func podMetrics(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting pods metrics")
+
+		list, err := getRawList(ctx, client, "/apis/metrics.k8s.io/v1beta1/pods")
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tCPU(cores)\tMEMORY(bytes)") //nolint:errcheck
+
+		for _, item := range list.Items {
+			containers, _, _ := unstructured.NestedSlice(item.Object, "containers") //nolint:errcheck
+
+			var cpu, memory resource.Quantity
+
+			for _, c := range containers {
+				container, ok := c.(map[string]any)
+				if !ok {
+					continue
+				}
+
+				usage, _, _ := unstructured.NestedStringMap(container, "usage") //nolint:errcheck
+
+				containerCPU, containerMemory := parseUsage(usage)
+
+				cpu.Add(containerCPU)
+				memory.Add(containerMemory)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%dm\t%dMi\n", item.GetNamespace(), item.GetName(), cpu.MilliValue(), memory.Value()/(1024*1024)) //nolint:errcheck
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func parseUsage(usage map[string]string) (cpu, memory resource.Quantity) {
+	if v, err := resource.ParseQuantity(usage["cpu"]); err == nil {
+		cpu = v
+	}
+
+	if v, err := resource.ParseQuantity(usage["memory"]); err == nil {
+		memory = v
+	}
+
+	return cpu, memory
+}
+
+func kubeletProxy(client kubernetes.Interface, node, path string) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting kubelet %s from node %s", path, node)
+
+		restClient, err := checkRESTClient(client.CoreV1().RESTClient())
+		if err != nil {
+			return nil, err
+		}
+
+		return restClient.Get().
+			Resource("nodes").
+			Name(node).
+			SubResource("proxy").
+			Suffix(path).
+			DoRaw(ctx)
+	}
+}
+
+// execInPod runs the command in the first container of the pod and writes its output to stdout.
+func execInPod(ctx context.Context, config *rest.Config, client kubernetes.Interface, pod *corev1.Pod, command []string, stdout io.Writer) error {
+	restClient, err := checkRESTClient(client.CoreV1().RESTClient())
+	if err != nil {
+		return err
+	}
+
+	req := restClient.Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: pod.Spec.Containers[0].Name,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+
+	if err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: &stderr}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func apiServerHealth(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting API server health")
+
+		restClient, err := checkRESTClient(client.Discovery().RESTClient())
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+
+		for _, endpoint := range []string{"/readyz", "/livez"} {
+			fmt.Fprintf(&buf, "%s:\n", endpoint)
+
+			// failed checks produce a non-2xx response, but the body still contains the breakdown
+			body, reqErr := restClient.Get().AbsPath(endpoint).Param("verbose", "").DoRaw(ctx)
+			if reqErr != nil && len(body) == 0 {
+				fmt.Fprintf(&buf, "error: %s\n", reqErr)
+			}
+
+			buf.Write(body)
+			fmt.Fprintln(&buf)
+		}
+
+		fmt.Fprintln(&buf, "/version:")
+
+		info, err := client.Discovery().ServerVersion()
+		if err != nil {
+			fmt.Fprintf(&buf, "error: %s\n", err)
+
+			return buf.Bytes(), nil
+		}
+
+		fmt.Fprintf(&buf, "GitVersion: %s\nGitCommit: %s\nBuildDate: %s\nGoVersion: %s\nPlatform: %s\n",
+			info.GitVersion,
+			info.GitCommit,
+			info.BuildDate,
+			info.GoVersion,
+			info.Platform,
+		)
+
+		return buf.Bytes(), nil
+	}
+}
+
+func corednsConfig(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting CoreDNS config map")
+
+		cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "coredns", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return marshalKubernetesResources(cm)
+	}
+}
+
+func corednsDeployment(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting CoreDNS deployment")
+
+		deployment, err := client.AppsV1().Deployments("kube-system").Get(ctx, "coredns", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return marshalKubernetesResources(deployment)
+	}
+}
+
+func configMaps(client kubernetes.Interface, namespace string) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting config maps in %s namespace", namespace)
+
+		cms, err := listAll(ctx, v1.ListOptions{}, client.CoreV1().ConfigMaps(namespace).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(cms)
+	}
+}
+
+func daemonSets(client kubernetes.Interface, namespace string) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting daemon sets in %s namespace", namespace)
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.AppsV1().DaemonSets(namespace).List)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalKubernetesResources(list)
+	}
+}
+
+func podLogs(client kubernetes.Interface, namespace, pod, container string, previous bool) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting %s/%s/%s pod logs", namespace, pod, container)
+
+		logOptions := &corev1.PodLogOptions{
+			Container: container,
+			Previous:  previous,
+		}
+
+		tailLines := options.PodLogTailLines
+		if tailLines <= 0 {
+			tailLines = int64(options.TailLines(container))
+		}
+
+		if tailLines > 0 {
+			logOptions.TailLines = &tailLines
+		}
+
+		since, until := options.TimeRange()
+
+		if !since.IsZero() {
+			logOptions.SinceTime = &v1.Time{Time: since}
+		}
+
+		// the API server can't limit the end of the range, so filter the lines by their timestamps
+		if !until.IsZero() {
+			logOptions.Timestamps = true
+		}
+
+		data, err := client.CoreV1().Pods(namespace).GetLogs(pod, logOptions).DoRaw(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return filterLogLines(options, data), nil
+	}
+}
+
+func kubeProxyDaemonSet(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting kube-proxy daemon set")
+
+		ds, err := client.AppsV1().DaemonSets("kube-system").Get(ctx, "kube-proxy", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return marshalKubernetesResources(ds)
+	}
+}
+
+func kubeProxyConfig(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting kube-proxy config map")
+
+		cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "kube-proxy", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return marshalKubernetesResources(cm)
+	}
+}
+
+func apiVersions(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting API versions")
+
+		groups, err := client.Discovery().ServerGroups()
+		if err != nil {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUPVERSION\tPREFERRED") //nolint:errcheck
+
+		for _, group := range groups.Groups {
+			for _, version := range group.Versions {
+				fmt.Fprintf(w, "%s\t%t\n", version.GroupVersion, version.GroupVersion == group.PreferredVersion.GroupVersion) //nolint:errcheck
+			}
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func apiResources(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting API resources")
+
+		// partial discovery failures (e.g. unavailable aggregated APIs) still return the rest of the resources
+		_, resourceLists, err := client.Discovery().ServerGroupsAndResources()
+		if err != nil && len(resourceLists) == 0 {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		if err != nil {
+			fmt.Fprintf(buf, "discovery error: %s\n\n", err)
+		}
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSHORTNAMES\tAPIVERSION\tNAMESPACED\tKIND\tVERBS") //nolint:errcheck
+
+		for _, list := range resourceLists {
+			for _, res := range list.APIResources {
+				// skip subresources
+				if strings.Contains(res.Name, "/") {
+					continue
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\n", //nolint:errcheck
+					res.Name,
+					strings.Join(res.ShortNames, ","),
+					list.GroupVersion,
+					res.Namespaced,
+					res.Kind,
+					strings.Join(res.Verbs, ","),
+				)
+			}
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+// getRawList fetches the list of resources which don't have typed clients in the clientset.
+func getRawList(ctx context.Context, client kubernetes.Interface, path string) (*unstructured.UnstructuredList, error) {
+	restClient, err := checkRESTClient(client.Discovery().RESTClient())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := restClient.Get().AbsPath(path).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var list unstructured.UnstructuredList
+
+	if err = list.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return &list, nil
+}
+
+// checkRESTClient verifies that the clientset provides the REST client, which is not the case for the fake clientsets.
+func checkRESTClient(restClient rest.Interface) (rest.Interface, error) {
+	if restClient == nil {
+		return nil, errors.New("REST client is not available")
+	}
+
+	if c, ok := restClient.(*rest.RESTClient); ok && c == nil {
+		return nil, errors.New("REST client is not available")
+	}
+
+	return restClient, nil
+}
+
+// listNamespaced runs the list function for each namespace and merges the results into a single list.
+func listNamespaced(ctx context.Context, namespaces []string, list func(ctx context.Context, namespace string, opts v1.ListOptions) (runtime.Object, error)) ([]byte, error) {
+	var (
+		result runtime.Object
+		items  []runtime.Object
+	)
+
+	for _, namespace := range namespaces {
+		res, err := listAll(ctx, v1.ListOptions{}, func(ctx context.Context, opts v1.ListOptions) (runtime.Object, error) {
+			return list(ctx, namespace, opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		extracted, err := meta.ExtractList(res)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, extracted...)
+
+		if result == nil {
+			result = res
+		}
+	}
+
+	if err := meta.SetList(result, items); err != nil {
+		return nil, err
+	}
+
+	return marshalKubernetesResources(result)
+}
+
+// listCacheKey identifies the list call of the client shared by the collectors.
+type listCacheKey struct {
+	client kubernetes.Interface
+	list   string
+}
+
+// listNodes lists all nodes once per bundle, the list is shared by the node reports.
+func listNodes(ctx context.Context, options *bundle.Options, client kubernetes.Interface) (*corev1.NodeList, error) {
+	return cachedList(ctx, options, listCacheKey{client: client, list: "nodes"}, func() (*corev1.NodeList, error) {
+		return listAll(ctx, v1.ListOptions{}, client.CoreV1().Nodes().List)
+	})
+}
+
+// listPods lists the pods in all namespaces once per bundle, the list is shared by the pod reports.
+func listPods(ctx context.Context, options *bundle.Options, client kubernetes.Interface) (*corev1.PodList, error) {
+	return cachedList(ctx, options, listCacheKey{client: client, list: "pods"}, func() (*corev1.PodList, error) {
+		return listAll(ctx, v1.ListOptions{}, client.CoreV1().Pods(v1.NamespaceAll).List)
+	})
+}
+
+// listControlPlanePods lists the kube-system pods of the control plane component once per bundle,
+// the list is shared by the control plane status and the version skew reports.
+func listControlPlanePods(ctx context.Context, options *bundle.Options, client kubernetes.Interface, component string) (*corev1.PodList, error) {
+	selector := "k8s-app=" + component
+
+	return cachedList(ctx, options, listCacheKey{client: client, list: "pods/kube-system/" + selector}, func() (*corev1.PodList, error) {
+		return listAll(ctx, v1.ListOptions{LabelSelector: selector}, client.CoreV1().Pods("kube-system").List)
+	})
+}
+
+func cachedList[T runtime.Object](ctx context.Context, options *bundle.Options, key listCacheKey, list func() (T, error)) (T, error) {
+	result, err := options.Cached(ctx, key, func() (any, error) {
+		return list()
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return result.(T), nil //nolint:forcetypeassert
+}
+
+// listPageSize is the maximum number of items requested from the API server in a single list call.
+const listPageSize = 500
+
+// listAll lists the resources in pages of listPageSize items using the continue token and merges
+// the pages into a single list.
+//
+// If the continue token expires before all pages are read, the list is restarted without pagination.
+func listAll[T runtime.Object](ctx context.Context, opts v1.ListOptions, list func(context.Context, v1.ListOptions) (T, error)) (T, error) {
+	var (
+		result, zero T
+		items        []runtime.Object
+	)
+
+	opts.Limit = listPageSize
+	opts.Continue = ""
+
+	for first := true; ; first = false {
+		page, err := list(ctx, opts)
+		if err != nil {
+			if !apierrors.IsResourceExpired(err) || opts.Continue == "" {
+				return zero, err
+			}
+
+			opts.Limit = 0
+			opts.Continue = ""
+			items = nil
+			first = true
+
+			if page, err = list(ctx, opts); err != nil {
+				return zero, err
+			}
+		}
+
+		extracted, err := meta.ExtractList(page)
+		if err != nil {
+			return zero, err
+		}
+
+		items = append(items, extracted...)
+
+		if first {
+			result = page
+		}
+
+		listMeta, err := meta.ListAccessor(page)
+		if err != nil {
+			return zero, err
+		}
+
+		if opts.Continue = listMeta.GetContinue(); opts.Continue == "" {
+			break
+		}
+	}
+
+	if err := meta.SetList(result, items); err != nil {
+		return zero, err
+	}
+
+	listMeta, err := meta.ListAccessor(result)
+	if err != nil {
+		return zero, err
+	}
+
+	listMeta.SetContinue("")
+	listMeta.SetRemainingItemCount(nil)
+
+	return result, nil
+}
+
+func nestedString(obj map[string]any, fields ...string) string {
+	val, _, _ := unstructured.NestedString(obj, fields...) //nolint:errcheck
+
+	return val
+}
+
+func nestedBool(obj map[string]any, fields ...string) bool {
+	val, _, _ := unstructured.NestedBool(obj, fields...) //nolint:errcheck
+
+	return val
+}
+
 func marshalKubernetesResources(resource runtime.Object) ([]byte, error) {
 	serializer := json.NewSerializerWithOptions(
 		json.DefaultMetaFactory, nil, nil,
@@ -0,0 +1,1206 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+var controlPlaneComponents = []string{
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+}
+
+func controlPlaneStatus(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting control plane pods status")
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tNODE\tPHASE\tREADY\tRESTARTS\tVERSION") //nolint:errcheck
+
+		var skewed []string
+
+		for _, component := range controlPlaneComponents {
+			pods, err := listControlPlanePods(ctx, options, client, component)
+			if err != nil {
+				return nil, err
+			}
+
+			versions := map[string]struct{}{}
+
+			for _, pod := range pods.Items {
+				version := podImageVersion(&pod, component)
+				versions[version] = struct{}{}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%s\n", //nolint:errcheck
+					component,
+					pod.Spec.NodeName,
+					pod.Status.Phase,
+					podReady(&pod),
+					podRestarts(&pod),
+					version,
+				)
+			}
+
+			if len(pods.Items) == 0 {
+				fmt.Fprintf(w, "%s\t-\tMissing\tfalse\t0\t-\n", component) //nolint:errcheck
+			}
+
+			if len(versions) > 1 {
+				skewed = append(skewed, component)
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+
+		if len(skewed) > 0 {
+			fmt.Fprintf(buf, "\nWARNING: version skew detected for %s\n", strings.Join(skewed, ", "))
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func corednsResolution(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("checking CoreDNS resolution")
+
+		endpoints, err := client.CoreV1().Endpoints("kube-system").Get(ctx, "kube-dns", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		domain := "cluster.local"
+
+		if cm, cmErr := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "coredns", v1.GetOptions{}); cmErr == nil {
+			domain = corednsClusterDomain(cm.Data["Corefile"], domain)
+		}
+
+		name := fmt.Sprintf("kubernetes.default.svc.%s.", domain)
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		fmt.Fprintf(buf, "resolving %s\n\n", name)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ENDPOINT\tPOD\tSERVING\tREADY\tLOOKUP") //nolint:errcheck
+
+		// the pod network is probed once, so the unreachable endpoints don't wait for the lookup timeout one by one
+		var probed, reachable bool
+
+		lookup := func(server string) string {
+			if !probed {
+				probed = true
+				reachable = podNetworkReachable(ctx, server)
+			}
+
+			if !reachable {
+				return "not run (pod network is not reachable)"
+			}
+
+			return lookupVia(ctx, server, name)
+		}
+
+		for _, subset := range endpoints.Subsets {
+			for _, addresses := range []struct {
+				list    []corev1.EndpointAddress
+				serving bool
+			}{
+				{list: subset.Addresses, serving: true},
+				{list: subset.NotReadyAddresses, serving: false},
+			} {
+				for _, addr := range addresses.list {
+					pod := "-"
+					ready := "-"
+
+					if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+						pod = addr.TargetRef.Name
+						ready = corednsReady(ctx, client, pod)
+					}
+
+					fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", addr.IP, pod, addresses.serving, ready, lookup(addr.IP)) //nolint:errcheck
+				}
+			}
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+// corednsReady queries the CoreDNS ready plugin through the API server pod proxy.
+func corednsReady(ctx context.Context, client kubernetes.Interface, pod string) string {
+	body, err := client.CoreV1().Pods("kube-system").ProxyGet("http", pod, "8181", "ready", nil).DoRaw(ctx)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// podNetworkProbeTimeout limits the check if the collecting machine can route to the pod network.
+const podNetworkProbeTimeout = time.Second
+
+// podNetworkReachable checks if the DNS server in the pod network accepts the connections from the collecting machine.
+func podNetworkReachable(ctx context.Context, server string) bool {
+	d := net.Dialer{Timeout: podNetworkProbeTimeout}
+
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(server, "53"))
+	if err != nil {
+		return false
+	}
+
+	conn.Close() //nolint:errcheck
+
+	return true
+}
+
+// lookupVia resolves the name using the DNS server directly.
+//
+// This only succeeds when the collecting machine can route to the pod network, so the error is recorded as the result.
+func lookupVia(ctx context.Context, server, name string) string {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+
+			return d.DialContext(ctx, "tcp", net.JoinHostPort(server, "53"))
+		},
+	}
+
+	addrs, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	return strings.Join(addrs, ",")
+}
+
+// corednsClusterDomain extracts the cluster domain from the kubernetes plugin definition in the Corefile.
+func corednsClusterDomain(corefile, fallback string) string {
+	for _, line := range strings.Split(corefile, "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) > 1 && fields[0] == "kubernetes" {
+			return fields[1]
+		}
+	}
+
+	return fallback
+}
+
+func podDisruptionBudgetBlockers(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("checking pod disruption budgets blocking evictions")
+
+		list, err := listAll(ctx, v1.ListOptions{}, client.PolicyV1().PodDisruptionBudgets(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tMIN-AVAILABLE\tMAX-UNAVAILABLE\tCURRENT-HEALTHY\tDESIRED-HEALTHY\tEXPECTED-PODS\tALLOWED-DISRUPTIONS") //nolint:errcheck
+
+		for _, pdb := range list.Items {
+			if pdb.Status.DisruptionsAllowed > 0 {
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n", //nolint:errcheck
+				pdb.Namespace,
+				pdb.Name,
+				intOrStringValue(pdb.Spec.MinAvailable),
+				intOrStringValue(pdb.Spec.MaxUnavailable),
+				pdb.Status.CurrentHealthy,
+				pdb.Status.DesiredHealthy,
+				pdb.Status.ExpectedPods,
+				pdb.Status.DisruptionsAllowed,
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func intOrStringValue(v *intstr.IntOrString) string {
+	if v == nil {
+		return "-"
+	}
+
+	return v.String()
+}
+
+func describePod(client kubernetes.Interface, pod *corev1.Pod) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("describing pod %s/%s", pod.Namespace, pod.Name)
+
+		events, err := podEvents(ctx, options, client, pod)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintf(w, "Name:\t%s\n", pod.Name)           //nolint:errcheck
+		fmt.Fprintf(w, "Namespace:\t%s\n", pod.Namespace) //nolint:errcheck
+		fmt.Fprintf(w, "Node:\t%s\n", pod.Spec.NodeName)  //nolint:errcheck
+
+		if pod.Status.StartTime != nil {
+			fmt.Fprintf(w, "Start Time:\t%s\n", pod.Status.StartTime.UTC().Format(time.RFC3339)) //nolint:errcheck
+		}
+
+		fmt.Fprintf(w, "Labels:\t%s\n", labels.FormatLabels(pod.Labels)) //nolint:errcheck
+		fmt.Fprintf(w, "Status:\t%s\n", pod.Status.Phase)                //nolint:errcheck
+
+		if pod.Status.Reason != "" {
+			fmt.Fprintf(w, "Reason:\t%s\n", pod.Status.Reason) //nolint:errcheck
+		}
+
+		if pod.Status.Message != "" {
+			fmt.Fprintf(w, "Message:\t%s\n", pod.Status.Message) //nolint:errcheck
+		}
+
+		fmt.Fprintf(w, "IP:\t%s\n", pod.Status.PodIP) //nolint:errcheck
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintln(buf, "Conditions:")
+
+		w = tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tMESSAGE") //nolint:errcheck
+
+		for _, cond := range pod.Status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, cond.Message) //nolint:errcheck
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		statuses := map[string]corev1.ContainerStatus{}
+
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			statuses[status.Name] = status
+		}
+
+		fmt.Fprintln(buf, "Containers:")
+
+		for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+			status := statuses[container.Name]
+
+			w = tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+
+			fmt.Fprintf(w, "  %s:\n", container.Name)                                               //nolint:errcheck
+			fmt.Fprintf(w, "    Image:\t%s\n", container.Image)                                     //nolint:errcheck
+			fmt.Fprintf(w, "    State:\t%s\n", containerState(status.State))                        //nolint:errcheck
+			fmt.Fprintf(w, "    Last State:\t%s\n", containerState(status.LastTerminationState))    //nolint:errcheck
+			fmt.Fprintf(w, "    Ready:\t%t\n", status.Ready)                                        //nolint:errcheck
+			fmt.Fprintf(w, "    Restart Count:\t%d\n", status.RestartCount)                         //nolint:errcheck
+			fmt.Fprintf(w, "    Requests:\t%s\n", resourceListString(container.Resources.Requests)) //nolint:errcheck
+			fmt.Fprintf(w, "    Limits:\t%s\n", resourceListString(container.Resources.Limits))     //nolint:errcheck
+
+			if err = w.Flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		fmt.Fprintln(buf, "Events:")
+
+		if err = writeEvents(buf, events); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func unschedulablePods(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("analyzing pending pods")
+
+		pods, err := listPods(ctx, options, client)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, err := listNodes(ctx, options, client)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+
+		fmt.Fprintln(&buf, "Pending pods:")
+
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodPending {
+				continue
+			}
+
+			fmt.Fprintf(&buf, "\n%s/%s (node: %q)\n", pod.Namespace, pod.Name, pod.Spec.NodeName)
+
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type == corev1.PodScheduled && cond.Status != corev1.ConditionTrue {
+					fmt.Fprintf(&buf, "  PodScheduled: %s: %s\n", cond.Reason, cond.Message)
+				}
+			}
+
+			var events []corev1.Event
+
+			events, err = podEvents(ctx, options, client, &pod)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, event := range events {
+				if event.Reason == "FailedScheduling" {
+					fmt.Fprintf(&buf, "  %s %s (x%d): %s\n", eventTime(event).UTC().Format(time.RFC3339), event.Reason, event.Count, strings.TrimSpace(event.Message))
+				}
+			}
+		}
+
+		requested := map[string]corev1.ResourceList{}
+		podCount := map[string]int{}
+
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+
+			podCount[pod.Spec.NodeName]++
+
+			if requested[pod.Spec.NodeName] == nil {
+				requested[pod.Spec.NodeName] = corev1.ResourceList{}
+			}
+
+			for _, container := range pod.Spec.Containers {
+				for name, quantity := range container.Resources.Requests {
+					total := requested[pod.Spec.NodeName][name]
+					total.Add(quantity)
+
+					requested[pod.Spec.NodeName][name] = total
+				}
+			}
+		}
+
+		fmt.Fprintln(&buf, "\nNodes allocatable vs requested:")
+
+		w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NODE\tSCHEDULABLE\tCPU(alloc/req)\tMEMORY(alloc/req)\tPODS(alloc/used)\tTAINTS") //nolint:errcheck
+
+		for _, node := range nodes.Items {
+			req := requested[node.Name]
+
+			taints := make([]string, 0, len(node.Spec.Taints))
+
+			for _, taint := range node.Spec.Taints {
+				taints = append(taints, taint.ToString())
+			}
+
+			fmt.Fprintf(w, "%s\t%t\t%s/%s\t%s/%s\t%s/%d\t%s\n", //nolint:errcheck
+				node.Name,
+				!node.Spec.Unschedulable,
+				node.Status.Allocatable.Cpu(),
+				req.Cpu(),
+				node.Status.Allocatable.Memory(),
+				req.Memory(),
+				node.Status.Allocatable.Pods(),
+				podCount[node.Name],
+				strings.Join(taints, ","),
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+// schedulingLabels are the node labels which are relevant to the scheduling decisions.
+var schedulingLabels = []string{
+	"kubernetes.io/arch",
+	"topology.kubernetes.io/region",
+	"topology.kubernetes.io/zone",
+	"node.kubernetes.io/instance-type",
+}
+
+func nodesSummary(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("summarizing nodes")
+
+		nodes, err := listNodes(ctx, options, client)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tREADY\tCORDONED\tROLES\tKUBELET\tKERNEL\tCONTAINER-RUNTIME\tOS\tLABELS\tTAINTS") //nolint:errcheck
+
+		for _, node := range nodes.Items {
+			ready := "Unknown"
+
+			for _, cond := range node.Status.Conditions {
+				if cond.Type == corev1.NodeReady {
+					ready = string(cond.Status)
+				}
+			}
+
+			var roles, nodeLabels []string
+
+			for key := range node.Labels {
+				if role, ok := strings.CutPrefix(key, "node-role.kubernetes.io/"); ok {
+					roles = append(roles, role)
+				}
+			}
+
+			for _, key := range schedulingLabels {
+				if value, ok := node.Labels[key]; ok {
+					nodeLabels = append(nodeLabels, key+"="+value)
+				}
+			}
+
+			taints := make([]string, 0, len(node.Spec.Taints))
+
+			for _, taint := range node.Spec.Taints {
+				taints = append(taints, taint.ToString())
+			}
+
+			slices.Sort(roles)
+
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+				node.Name,
+				ready,
+				node.Spec.Unschedulable,
+				valueOrDash(strings.Join(roles, ",")),
+				node.Status.NodeInfo.KubeletVersion,
+				node.Status.NodeInfo.KernelVersion,
+				node.Status.NodeInfo.ContainerRuntimeVersion,
+				node.Status.NodeInfo.OSImage,
+				valueOrDash(strings.Join(nodeLabels, ",")),
+				valueOrDash(strings.Join(taints, ",")),
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// helmRelease is the subset of the Helm release fields which are safe to include in the bundle.
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Info      struct {
+		LastDeployed time.Time `json:"last_deployed"`
+		Status       string    `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+	Version int `json:"version"`
+}
+
+func helmReleases(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("getting helm releases")
+
+		secrets, err := listAll(ctx, v1.ListOptions{
+			LabelSelector: "owner=helm",
+			FieldSelector: "type=helm.sh/release.v1",
+		}, client.CoreV1().Secrets(v1.NamespaceAll).List)
+		if err != nil {
+			return nil, err
+		}
+
+		latest := map[string]helmRelease{}
+
+		for _, secret := range secrets.Items {
+			release, decodeErr := decodeHelmRelease(secret.Data["release"])
+			if decodeErr != nil {
+				options.Log("failed to decode helm release %s/%s: %s", secret.Namespace, secret.Name, decodeErr)
+
+				continue
+			}
+
+			key := release.Namespace + "/" + release.Name
+
+			if current, ok := latest[key]; !ok || current.Version < release.Version {
+				latest[key] = release
+			}
+		}
+
+		if len(latest) == 0 {
+			return nil, nil
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tREVISION\tCHART\tCHART-VERSION\tAPP-VERSION\tSTATUS\tLAST-DEPLOYED") //nolint:errcheck
+
+		keys := make([]string, 0, len(latest))
+
+		for key := range latest {
+			keys = append(keys, key)
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			release := latest[key]
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+				release.Namespace,
+				release.Name,
+				release.Version,
+				release.Chart.Metadata.Name,
+				release.Chart.Metadata.Version,
+				release.Chart.Metadata.AppVersion,
+				release.Info.Status,
+				release.Info.LastDeployed.UTC().Format(time.RFC3339),
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+// decodeHelmRelease decodes the release stored by Helm: base64 encoded gzipped JSON.
+func decodeHelmRelease(data []byte) (helmRelease, error) {
+	var release helmRelease
+
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return release, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return release, err
+	}
+
+	defer gz.Close() //nolint:errcheck
+
+	if err = json.NewDecoder(gz).Decode(&release); err != nil {
+		return release, err
+	}
+
+	return release, nil
+}
+
+// kubeProxyRules reports the kube-proxy mode and health, and samples the generated rules on the first nodes.
+//
+// The rules are dumped by running the command in the kube-proxy pods, so the pods/exec permission is required.
+func kubeProxyRules(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("sampling kube-proxy rules")
+
+		ds, err := client.AppsV1().DaemonSets("kube-system").Get(ctx, "kube-proxy", v1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+
+		mode := kubeProxyMode(ctx, client, ds.Spec.Template.Spec.Containers)
+
+		fmt.Fprintf(&buf, "configured mode: %s\n", mode)
+
+		selector, err := v1.LabelSelectorAsSelector(ds.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		pods, err := listAll(ctx, v1.ListOptions{LabelSelector: selector.String()}, client.CoreV1().Pods("kube-system").List)
+		if err != nil {
+			return nil, err
+		}
+
+		slices.SortFunc(pods.Items, func(a, b corev1.Pod) int {
+			return strings.Compare(a.Spec.NodeName, b.Spec.NodeName)
+		})
+
+		if len(pods.Items) > kubeProxyRulesSampleNodes {
+			fmt.Fprintf(&buf, "sampled nodes: %d of %d\n", kubeProxyRulesSampleNodes, len(pods.Items))
+
+			pods.Items = pods.Items[:kubeProxyRulesSampleNodes]
+		}
+
+		for _, pod := range pods.Items {
+			fmt.Fprintf(&buf, "\nnode %s (pod %s):\n", pod.Spec.NodeName, pod.Name)
+
+			for _, endpoint := range []struct {
+				port   string
+				path   string
+				filter string
+			}{
+				{port: "10249", path: "proxyMode"},
+				{port: "10256", path: "healthz"},
+				{port: "10249", path: "metrics", filter: "kubeproxy_sync_proxy_rules"},
+			} {
+				body, err := client.CoreV1().Pods("kube-system").ProxyGet("http", pod.Name, endpoint.port, endpoint.path, nil).DoRaw(ctx)
+				if err != nil {
+					fmt.Fprintf(&buf, "  /%s: error: %s\n", endpoint.path, err)
+
+					continue
+				}
+
+				fmt.Fprintf(&buf, "  /%s:\n", endpoint.path)
+
+				for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+					if endpoint.filter != "" && (!strings.HasPrefix(line, endpoint.filter) || strings.HasPrefix(line, "#")) {
+						continue
+					}
+
+					fmt.Fprintf(&buf, "    %s\n", line)
+				}
+			}
+
+			kubeProxyRulesSample(ctx, &buf, options.KubernetesRESTConfig(), client, &pod, mode)
+		}
+
+		return buf.Bytes(), nil
+	}
+}
+
+// kubeProxyRulesSampleLines is the number of lines of the generated rules sampled on each node.
+const kubeProxyRulesSampleLines = 200
+
+// kubeProxyRulesSampleNodes is the number of nodes the rules are sampled on, the rules are mostly the same on all nodes.
+const kubeProxyRulesSampleNodes = 3
+
+// kubeProxyRulesCommand returns the command dumping the rules generated by kube-proxy in the mode.
+//
+// In the IPVS mode kube-proxy still programs the iptables nat chains for the masquerading and the node ports.
+func kubeProxyRulesCommand(mode string) []string {
+	if mode == "nftables" {
+		return []string{"nft", "list", "table", "ip", "kube-proxy"}
+	}
+
+	return []string{"iptables-save", "-t", "nat"}
+}
+
+// kubeProxyRulesSample runs the rules dump in the kube-proxy pod and writes the first lines of the rules.
+func kubeProxyRulesSample(ctx context.Context, w io.Writer, config *rest.Config, client kubernetes.Interface, pod *corev1.Pod, mode string) {
+	command := kubeProxyRulesCommand(mode)
+
+	if config == nil {
+		fmt.Fprintf(w, "  rules: not sampled, the Kubernetes REST config is not known\n")
+
+		return
+	}
+
+	sample := &lineSample{limit: kubeProxyRulesSampleLines}
+
+	if err := execInPod(ctx, config, client, pod, command, sample); err != nil {
+		fmt.Fprintf(w, "  rules (%s): error: %s\n", strings.Join(command, " "), err)
+
+		return
+	}
+
+	sample.Close() //nolint:errcheck
+
+	fmt.Fprintf(w, "  rules (%s, %d of %d lines):\n", strings.Join(command, " "), len(sample.lines), sample.total)
+
+	for _, line := range sample.lines {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
+
+// maxSampleLineLength is the length the sampled lines are cut at.
+const maxSampleLineLength = 1024
+
+// lineSample keeps the first lines written to it and counts the rest, so the memory usage doesn't depend on the output size.
+type lineSample struct {
+	partial []byte
+	lines   []string
+	total   int
+	limit   int
+	pending bool
+}
+
+// Write implements io.Writer.
+func (s *lineSample) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.appendPartial(p)
+
+			break
+		}
+
+		s.appendPartial(p[:i])
+		s.endLine()
+
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+func (s *lineSample) appendPartial(p []byte) {
+	s.pending = true
+
+	if len(s.lines) < s.limit {
+		s.partial = append(s.partial, p[:min(len(p), maxSampleLineLength-len(s.partial))]...)
+	}
+}
+
+func (s *lineSample) endLine() {
+	if len(s.lines) < s.limit {
+		s.lines = append(s.lines, string(s.partial))
+	}
+
+	s.partial = s.partial[:0]
+	s.pending = false
+	s.total++
+}
+
+// Close ends the last line if it's not terminated by the newline.
+func (s *lineSample) Close() error {
+	if s.pending {
+		s.endLine()
+	}
+
+	return nil
+}
+
+// kubeProxyMode detects kube-proxy mode from the command line flags or from the config map.
+func kubeProxyMode(ctx context.Context, client kubernetes.Interface, containers []corev1.Container) string {
+	for _, container := range containers {
+		for _, arg := range slices.Concat(container.Command, container.Args) {
+			if mode, ok := strings.CutPrefix(arg, "--proxy-mode="); ok {
+				return mode
+			}
+		}
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "kube-proxy", v1.GetOptions{})
+	if err != nil {
+		return "unknown"
+	}
+
+	for _, data := range cm.Data {
+		for _, line := range strings.Split(data, "\n") {
+			if mode, ok := strings.CutPrefix(strings.TrimSpace(line), "mode:"); ok {
+				return valueOrDash(strings.Trim(strings.TrimSpace(mode), `"'`))
+			}
+		}
+	}
+
+	return "default"
+}
+
+func containerRestarts(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("summarizing container restarts")
+
+		pods, err := listPods(ctx, options, client)
+		if err != nil {
+			return nil, err
+		}
+
+		type restartInfo struct {
+			lastFinish time.Time
+			namespace  string
+			pod        string
+			container  string
+			lastReason string
+			restarts   int32
+			oomKilled  bool
+		}
+
+		var restarts []restartInfo
+
+		for _, pod := range pods.Items {
+			for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+				info := restartInfo{
+					namespace: pod.Namespace,
+					pod:       pod.Name,
+					container: status.Name,
+					restarts:  status.RestartCount,
+				}
+
+				for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+					if terminated == nil {
+						continue
+					}
+
+					if terminated.Reason == "OOMKilled" {
+						info.oomKilled = true
+					}
+
+					if terminated.FinishedAt.After(info.lastFinish) {
+						info.lastFinish = terminated.FinishedAt.Time
+						info.lastReason = terminated.Reason
+					}
+				}
+
+				if info.restarts > 0 || info.oomKilled {
+					restarts = append(restarts, info)
+				}
+			}
+		}
+
+		slices.SortStableFunc(restarts, func(a, b restartInfo) int {
+			return int(b.restarts) - int(a.restarts)
+		})
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tPOD\tCONTAINER\tRESTARTS\tOOMKILLED\tLAST-REASON\tLAST-FINISHED") //nolint:errcheck
+
+		for _, info := range restarts {
+			lastFinish := "-"
+
+			if !info.lastFinish.IsZero() {
+				lastFinish = info.lastFinish.UTC().Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%t\t%s\t%s\n", //nolint:errcheck
+				info.namespace,
+				info.pod,
+				info.container,
+				info.restarts,
+				info.oomKilled,
+				valueOrDash(info.lastReason),
+				lastFinish,
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+const (
+	// nodeLeaseStaleThreshold matches the default kube-controller-manager node monitor grace period.
+	nodeLeaseStaleThreshold = 40 * time.Second
+	// nodeStatusStaleThreshold is the default node status report frequency plus the grace period.
+	nodeStatusStaleThreshold = 5*time.Minute + nodeLeaseStaleThreshold
+	// nodeFlappingThreshold flags nodes with recent Ready condition transitions.
+	nodeFlappingThreshold = 10 * time.Minute
+)
+
+func nodeHealth(client kubernetes.Interface) Collect {
+	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+		options.Log("checking nodes health")
+
+		nodes, err := listNodes(ctx, options, client)
+		if err != nil {
+			return nil, err
+		}
+
+		leases, err := listAll(ctx, v1.ListOptions{}, client.CoordinationV1().Leases("kube-node-lease").List)
+		if err != nil {
+			return nil, err
+		}
+
+		renewTimes := map[string]time.Time{}
+
+		for _, lease := range leases.Items {
+			if lease.Spec.RenewTime != nil {
+				renewTimes[lease.Name] = lease.Spec.RenewTime.Time
+			}
+		}
+
+		now := options.Now()
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NODE\tREADY\tLEASE-AGE\tHEARTBEAT-AGE\tREADY-SINCE\tPROBLEMS") //nolint:errcheck
+
+		for _, node := range nodes.Items {
+			var (
+				problems      []string
+				ready         = "Unknown"
+				heartbeatAge  = "-"
+				leaseAge      = "-"
+				readySinceAge = "-"
+			)
+
+			for _, cond := range node.Status.Conditions {
+				switch cond.Type { //nolint:exhaustive
+				case corev1.NodeReady:
+					ready = string(cond.Status)
+					age := now.Sub(cond.LastHeartbeatTime.Time)
+					heartbeatAge = age.Truncate(time.Second).String()
+					readySinceAge = now.Sub(cond.LastTransitionTime.Time).Truncate(time.Second).String()
+
+					if age > nodeStatusStaleThreshold {
+						problems = append(problems, "stale status heartbeat")
+					}
+
+					if now.Sub(cond.LastTransitionTime.Time) < nodeFlappingThreshold {
+						problems = append(problems, "recent Ready transition")
+					}
+
+					if cond.Status != corev1.ConditionTrue {
+						problems = append(problems, fmt.Sprintf("not ready: %s", cond.Reason))
+					}
+				default:
+					if cond.Status == corev1.ConditionTrue {
+						problems = append(problems, string(cond.Type))
+					}
+				}
+			}
+
+			if renewTime, ok := renewTimes[node.Name]; ok {
+				age := now.Sub(renewTime)
+				leaseAge = age.Truncate(time.Second).String()
+
+				if age > nodeLeaseStaleThreshold {
+					problems = append(problems, "stale lease")
+				}
+			} else {
+				problems = append(problems, "missing lease")
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+				node.Name,
+				ready,
+				leaseAge,
+				heartbeatAge,
+				readySinceAge,
+				valueOrDash(strings.Join(problems, ", ")),
+			)
+		}
+
+		if err = w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return bytes.Clone(buf.Bytes()), nil
+	}
+}
+
+func podEvents(ctx context.Context, options *bundle.Options, client kubernetes.Interface, pod *corev1.Pod) ([]corev1.Event, error) {
+	events, err := listAll(ctx, v1.ListOptions{
+		FieldSelector: fields.Set{
+			"involvedObject.name": pod.Name,
+			"involvedObject.uid":  string(pod.UID),
+		}.String(),
+	}, client.CoreV1().Events(pod.Namespace).List)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Items = slices.DeleteFunc(events.Items, func(event corev1.Event) bool {
+		return !options.InTimeRange(eventTime(event))
+	})
+
+	slices.SortFunc(events.Items, func(a, b corev1.Event) int {
+		return eventTime(a).Compare(eventTime(b))
+	})
+
+	return events.Items, nil
+}
+
+func writeEvents(out io.Writer, events []corev1.Event) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  LAST SEEN\tTYPE\tREASON\tFROM\tCOUNT\tMESSAGE") //nolint:errcheck
+
+	for _, event := range events {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%d\t%s\n", //nolint:errcheck
+			eventTime(event).UTC().Format(time.RFC3339),
+			event.Type,
+			event.Reason,
+			event.Source.Component,
+			event.Count,
+			strings.TrimSpace(event.Message),
+		)
+	}
+
+	return w.Flush()
+}
+
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return fmt.Sprintf("Running (started %s)", state.Running.StartedAt.UTC().Format(time.RFC3339))
+	case state.Waiting != nil:
+		return strings.TrimSpace(fmt.Sprintf("Waiting (%s) %s", state.Waiting.Reason, state.Waiting.Message))
+	case state.Terminated != nil:
+		return strings.TrimSpace(fmt.Sprintf("Terminated (%s) exit code %d at %s %s",
+			state.Terminated.Reason,
+			state.Terminated.ExitCode,
+			state.Terminated.FinishedAt.UTC().Format(time.RFC3339),
+			state.Terminated.Message,
+		))
+	default:
+		return "-"
+	}
+}
+
+func resourceListString(list corev1.ResourceList) string {
+	if len(list) == 0 {
+		return "-"
+	}
+
+	res := make([]string, 0, len(list))
+
+	for name, quantity := range list {
+		res = append(res, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+
+	slices.Sort(res)
+
+	return strings.Join(res, ", ")
+}
+
+// isProblemPod returns true for the pods which are not running or not ready.
+func isProblemPod(pod *corev1.Pod) bool {
+	switch pod.Status.Phase { //nolint:exhaustive
+	case corev1.PodSucceeded:
+		return false
+	case corev1.PodRunning:
+		return !podReady(pod)
+	default:
+		return true
+	}
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+
+	return restarts
+}
+
+// podImageVersion returns the image tag of the named container, or of the first container if there's no match.
+func podImageVersion(pod *corev1.Pod, container string) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+
+	image := pod.Spec.Containers[0].Image
+
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			image = c.Image
+
+			break
+		}
+	}
+
+	if idx := strings.LastIndex(image, ":"); idx != -1 && !strings.Contains(image[idx:], "/") {
+		return image[idx+1:]
+	}
+
+	return image
+}
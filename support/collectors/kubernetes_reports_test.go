@@ -0,0 +1,633 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+type testClock time.Time
+
+func (c testClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// testNow is the time of the test cluster snapshot.
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// helmReleaseData encodes the release the way Helm stores it in the secret: base64 encoded gzipped JSON.
+func helmReleaseData(t *testing.T, release string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+
+	_, err := gz.Write([]byte(release))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+func testPod(namespace, name, node, image string, phase corev1.PodPhase, ready bool) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: []corev1.Container{{Name: "main", Image: image}},
+		},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: readyStatus}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: ready}},
+		},
+	}
+}
+
+// testCluster returns the objects of the cluster with a few typical problems:
+// the not ready cordoned worker without the lease, the missing scheduler, the skewed API server,
+// the OOM killed application, the unschedulable pod and the PDB which blocks the evictions.
+func testCluster(t *testing.T) []runtime.Object {
+	t.Helper()
+
+	ago := func(d time.Duration) metav1.Time {
+		return metav1.NewTime(testNow.Add(-d))
+	}
+
+	apiServer := testPod("kube-system", "kube-apiserver-talos-cp-1", "talos-cp-1", "registry.k8s.io/kube-apiserver:v1.30.3", corev1.PodRunning, true)
+	apiServer.Labels = map[string]string{"k8s-app": "kube-apiserver"}
+	apiServer.Spec.Containers[0].Name = "kube-apiserver"
+
+	skewedAPIServer := testPod("kube-system", "kube-apiserver-talos-cp-2", "talos-cp-2", "registry.k8s.io/kube-apiserver:v1.29.6", corev1.PodRunning, true)
+	skewedAPIServer.Labels = map[string]string{"k8s-app": "kube-apiserver"}
+	skewedAPIServer.Spec.Containers[0].Name = "kube-apiserver"
+
+	controllerManager := testPod("kube-system", "kube-controller-manager-talos-cp-1", "talos-cp-1", "registry.k8s.io/kube-controller-manager:v1.30.3", corev1.PodRunning, true)
+	controllerManager.Labels = map[string]string{"k8s-app": "kube-controller-manager"}
+	controllerManager.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("200m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	controllerManager.Status.ContainerStatuses[0].RestartCount = 2
+	controllerManager.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+		Reason:     "Error",
+		ExitCode:   1,
+		FinishedAt: ago(time.Hour),
+	}
+
+	app := testPod("default", "app-0", "talos-cp-1", "ghcr.io/example/app:1.0.0", corev1.PodRunning, false)
+	app.Labels = map[string]string{"app": "web"}
+	app.Spec.Containers[0].Name = "app"
+	app.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	app.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name:         "app",
+			RestartCount: 5,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Reason:     "OOMKilled",
+					ExitCode:   137,
+					FinishedAt: ago(3 * time.Minute),
+				},
+			},
+		},
+	}
+
+	pending := testPod("default", "pending-0", "", "ghcr.io/example/app:1.0.0", corev1.PodPending, false)
+	pending.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}
+	pending.Status.Conditions = append(pending.Status.Conditions, corev1.PodCondition{
+		Type:    corev1.PodScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  "Unschedulable",
+		Message: "0/2 nodes are available: 2 Insufficient cpu.",
+	})
+
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "talos-cp-1",
+				Labels: map[string]string{
+					"node-role.kubernetes.io/control-plane": "",
+					"kubernetes.io/arch":                    "amd64",
+				},
+			},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastHeartbeatTime: ago(10 * time.Second), LastTransitionTime: ago(time.Hour)},
+				},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				NodeInfo: corev1.NodeSystemInfo{
+					KubeletVersion:          "v1.30.3",
+					KernelVersion:           "6.6.33-talos",
+					ContainerRuntimeVersion: "containerd://1.7.18",
+					OSImage:                 "Talos (v1.7.5)",
+				},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-worker-1"},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Reason: "KubeletNotReady", LastHeartbeatTime: ago(10 * time.Minute), LastTransitionTime: ago(2 * time.Minute)},
+					{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+				},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.26.0"},
+			},
+		},
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1", Namespace: "kube-node-lease"},
+			Spec:       coordinationv1.LeaseSpec{RenewTime: &metav1.MicroTime{Time: testNow.Add(-5 * time.Second)}},
+		},
+		&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "kube-controller-manager", Namespace: "kube-system"}},
+		apiServer,
+		skewedAPIServer,
+		controllerManager,
+		app,
+		pending,
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pending-0.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pending-0", Namespace: "default"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedScheduling",
+			Message:        "0/2 nodes are available: 2 Insufficient cpu.",
+			Count:          3,
+			LastTimestamp:  ago(time.Minute),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pending-0.2", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pending-0", Namespace: "default"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedScheduling",
+			Message:        "stale scheduling failure",
+			Count:          1,
+			LastTimestamp:  ago(2 * time.Hour),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "app-0.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "app-0", Namespace: "default"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container app",
+			Source:         corev1.EventSource{Component: "kubelet"},
+			Count:          7,
+			LastTimestamp:  ago(30 * time.Second),
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "other"}},
+		&corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"}}, //nolint:staticcheck
+		&discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns-x7k2p", Namespace: "kube-system"}},
+		&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "default"}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "default"}},
+		&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-data"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "local-path"}},
+		&storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "rbd.csi.ceph.com"}},
+		&storagev1.CSINode{ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1"}},
+		&storagev1.VolumeAttachment{ObjectMeta: metav1.ObjectMeta{Name: "csi-attachment"}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "default"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web-ingress", Namespace: "default"}},
+		&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "system:node"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "app-reader"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "custom-admin"}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-reader-binding"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "app-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "app", Namespace: "kube-system"}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-binding"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "custom-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+		},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "extension-apiserver-authentication-reader", Namespace: "kube-system"}},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "private", Namespace: "default"}},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-system-binding", Namespace: "kube-system"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "extension-apiserver-authentication-reader"},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-role-binding", Namespace: "default"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "private"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &intstr.IntOrString{IntVal: 1}},
+			Status:     policyv1.PodDisruptionBudgetStatus{CurrentHealthy: 1, DesiredHealthy: 1, ExpectedPods: 1},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "relaxed", Namespace: "default"},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.web.v1", Namespace: "default", Labels: map[string]string{"owner": "helm"}},
+			Type:       "helm.sh/release.v1",
+			Data: map[string][]byte{"release": helmReleaseData(t,
+				`{"name":"web","namespace":"default","version":1,"info":{"status":"superseded","last_deployed":"2024-05-01T12:00:00Z"},"chart":{"metadata":{"name":"web","version":"1.2.2","appVersion":"4.5.5"}}}`,
+			)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.web.v2", Namespace: "default", Labels: map[string]string{"owner": "helm"}},
+			Type:       "helm.sh/release.v1",
+			Data: map[string][]byte{"release": helmReleaseData(t,
+				`{"name":"web","namespace":"default","version":2,"info":{"status":"deployed","last_deployed":"2024-06-01T10:00:00Z"},"chart":{"metadata":{"name":"web","version":"1.2.3","appVersion":"4.5.6"}}}`,
+			)},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+			Data:       map[string]string{"Corefile": ".:53 {\n    kubernetes cluster.local in-addr.arpa ip6.arpa\n}\n"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"},
+			Data:       map[string]string{"config.conf": "mode: ipvs\n"},
+		},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"}},
+	}
+}
+
+// testClusterClient returns the fake clientset with the test cluster objects and the discovery information.
+func testClusterClient(t *testing.T) *fake.Clientset {
+	t.Helper()
+
+	client := fake.NewSimpleClientset(testCluster(t)...)
+
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", ShortNames: []string{"po"}, Namespaced: true, Kind: "Pod", Verbs: []string{"get", "list"}},
+				{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: []string{"get"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "daemonsets", ShortNames: []string{"ds"}, Namespaced: true, Kind: "DaemonSet", Verbs: []string{"get", "list"}},
+			},
+		},
+	}
+
+	return client
+}
+
+// restCollectorPaths are the files of the collectors which need the REST client the fake clientset doesn't provide.
+var restCollectorPaths = []string{
+	"kubernetesResources/apiservices.yaml",
+	"kubernetesResources/metrics/nodes.txt",
+	"kubernetesResources/metrics/pods.txt",
+	"kubernetesResources/apiserver-health.txt",
+	"kubernetesResources/coredns/resolution.txt",
+}
+
+func TestKubernetesReports(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	client := testClusterClient(t)
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(client), func(col *collectors.Collector) bool {
+		return slices.Contains(restCollectorPaths, col.Path())
+	})
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithKubernetesClient(client),
+		bundle.WithNamespaces("default"),
+		bundle.WithClock(testClock(testNow)),
+		bundle.WithSince(time.Hour),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(t, runCollectors(ctx, options, cols))
+
+	for _, test := range []struct {
+		path     string
+		contains []string
+		matches  []string
+		missing  []string
+	}{
+		{
+			path:    "kubernetesResources/nodes.txt",
+			matches: []string{`talos-cp-1\s+True\s+false\s+control-plane\s+v1.30.3\s+6.6.33-talos\s+containerd://1.7.18\s+Talos \(v1.7.5\)\s+kubernetes.io/arch=amd64\s+node-role.kubernetes.io/control-plane:NoSchedule`, `talos-worker-1\s+False\s+true\s+-\s+v1.26.0`},
+		},
+		{
+			path: "kubernetesResources/node-health.txt",
+			matches: []string{
+				`talos-cp-1\s+True\s+5s\s+10s\s+1h0m0s\s+-\n`,
+				`talos-worker-1\s+False\s+-\s+10m0s\s+2m0s\s+stale status heartbeat, recent Ready transition, not ready: KubeletNotReady, MemoryPressure, missing lease\n`,
+			},
+		},
+		{
+			path:     "kubernetesResources/controlPlaneStatus.txt",
+			matches:  []string{`kube-apiserver\s+talos-cp-1\s+Running\s+true\s+0\s+v1.30.3`, `kube-apiserver\s+talos-cp-2\s+Running\s+true\s+0\s+v1.29.6`, `kube-controller-manager\s+talos-cp-1\s+Running\s+true\s+2\s+v1.30.3`, `kube-scheduler\s+-\s+Missing`},
+			contains: []string{"WARNING: version skew detected for kube-apiserver\n"},
+		},
+		{
+			path:    "kubernetesResources/pdb-blockers.txt",
+			matches: []string{`default\s+web\s+1\s+-\s+1\s+1\s+1\s+0`},
+			missing: []string{"relaxed"},
+		},
+		{
+			path:     "kubernetesResources/unschedulable-pods.txt",
+			contains: []string{"default/pending-0 (node: \"\")", "PodScheduled: Unschedulable: 0/2 nodes are available: 2 Insufficient cpu.", "2024-06-01T11:59:00Z FailedScheduling (x3)"},
+			matches:  []string{`talos-cp-1\s+true\s+4/700m\s+8Gi/1280Mi\s+110/3\s+node-role.kubernetes.io/control-plane:NoSchedule`, `talos-worker-1\s+false\s+2/0\s+4Gi/0\s+110/0`},
+			missing:  []string{"app-0", "stale scheduling failure"},
+		},
+		{
+			path:    "kubernetesResources/helm-releases.txt",
+			matches: []string{`default\s+web\s+2\s+web\s+1.2.3\s+4.5.6\s+deployed\s+2024-06-01T10:00:00Z`},
+			missing: []string{"superseded"},
+		},
+		{
+			path:    "kubernetesResources/restarts.txt",
+			matches: []string{`default\s+app-0\s+app\s+5\s+true\s+OOMKilled\s+2024-06-01T11:57:00Z\n.*kube-controller-manager-talos-cp-1\s+main\s+2\s+false\s+Error\s+2024-06-01T11:00:00Z`},
+			missing: []string{"kube-apiserver"},
+		},
+		{
+			path:    "kubernetesResources/api-versions.txt",
+			matches: []string{`\nv1\s+true`, `apps/v1\s+true`},
+		},
+		{
+			path:    "kubernetesResources/api-resources.txt",
+			matches: []string{`pods\s+po\s+v1\s+true\s+Pod\s+get,list`, `daemonsets\s+ds\s+apps/v1\s+true\s+DaemonSet`},
+			missing: []string{"pods/log"},
+		},
+		{
+			path:     "kubernetesResources/namespaces.yaml",
+			contains: []string{"name: default", "name: kube-system"},
+		},
+		{
+			path:     "kubernetesResources/services.yaml",
+			contains: []string{"name: kube-dns", "name: web"},
+			missing:  []string{"ignored"},
+		},
+		{
+			path:     "kubernetesResources/endpoints.yaml",
+			contains: []string{"name: kube-dns"},
+		},
+		{
+			path:     "kubernetesResources/endpointSlices.yaml",
+			contains: []string{"name: kube-dns-x7k2p"},
+		},
+		{
+			path:     "kubernetesResources/resourceQuotas.yaml",
+			contains: []string{"name: compute"},
+		},
+		{
+			path:     "kubernetesResources/limitRanges.yaml",
+			contains: []string{"name: limits"},
+		},
+		{
+			path:     "kubernetesResources/events.yaml",
+			contains: []string{"reason: FailedScheduling", "reason: BackOff"},
+		},
+		{
+			path:     "kubernetesResources/storage/persistentVolumes.yaml",
+			contains: []string{"name: pv-data"},
+		},
+		{
+			path:     "kubernetesResources/storage/persistentVolumeClaims.yaml",
+			contains: []string{"name: data"},
+		},
+		{
+			path:     "kubernetesResources/storage/storageClasses.yaml",
+			contains: []string{"name: local-path"},
+		},
+		{
+			path:     "kubernetesResources/storage/csiDrivers.yaml",
+			contains: []string{"name: rbd.csi.ceph.com"},
+		},
+		{
+			path:     "kubernetesResources/storage/csiNodes.yaml",
+			contains: []string{"name: talos-cp-1"},
+		},
+		{
+			path:     "kubernetesResources/storage/volumeAttachments.yaml",
+			contains: []string{"name: csi-attachment"},
+		},
+		{
+			path:     "kubernetesResources/networkPolicies/networkPolicies.yaml",
+			contains: []string{"name: deny-all"},
+		},
+		{
+			path:     "kubernetesResources/ingress/ingresses.yaml",
+			contains: []string{"name: web-ingress"},
+		},
+		{
+			path:     "kubernetesResources/ingress/ingressClasses.yaml",
+			contains: []string{"name: nginx"},
+		},
+		{
+			path:     "kubernetesResources/rbac/clusterRoles.yaml",
+			contains: []string{"name: system:node", "name: app-reader"},
+			missing:  []string{"custom-admin"},
+		},
+		{
+			path:     "kubernetesResources/rbac/clusterRoleBindings.yaml",
+			contains: []string{"name: app-reader-binding"},
+			missing:  []string{"user-binding"},
+		},
+		{
+			path:     "kubernetesResources/rbac/roles.yaml",
+			contains: []string{"name: extension-apiserver-authentication-reader"},
+			missing:  []string{"private"},
+		},
+		{
+			path:     "kubernetesResources/rbac/roleBindings.yaml",
+			contains: []string{"name: kube-system-binding"},
+			missing:  []string{"user-role-binding"},
+		},
+		{
+			path:     "kubernetesResources/leases.yaml",
+			contains: []string{"name: talos-cp-1", "name: kube-controller-manager"},
+		},
+		{
+			path:     "kubernetesResources/podDisruptionBudgets.yaml",
+			contains: []string{"name: web", "name: relaxed"},
+		},
+		{
+			path:     "kubernetesResources/kube-proxy/daemonset.yaml",
+			contains: []string{"name: kube-proxy"},
+		},
+		{
+			path:     "kubernetesResources/kube-proxy/configmap.yaml",
+			contains: []string{"mode: ipvs"},
+		},
+		{
+			path:     "kubernetesResources/coredns/configmap.yaml",
+			contains: []string{"kubernetes cluster.local"},
+		},
+		{
+			path:     "kubernetesResources/coredns/deployment.yaml",
+			contains: []string{"name: coredns"},
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			data, ok := archive.files[test.path]
+			require.True(t, ok)
+
+			for _, s := range test.contains {
+				assert.Contains(t, string(data), s)
+			}
+
+			for _, re := range test.matches {
+				assert.Regexp(t, re, string(data))
+			}
+
+			for _, s := range test.missing {
+				assert.NotContains(t, string(data), s)
+			}
+		})
+	}
+}
+
+func TestProblemPodCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := testClusterClient(t)
+
+	cols, err := collectors.GetProblemPodCollectors(ctx, bundle.NewOptions(), client)
+	require.NoError(err)
+
+	paths := make([]string, 0, len(cols))
+
+	for _, col := range cols {
+		require.Equal("k8s.problem-pods", col.Name())
+
+		paths = append(paths, col.Path())
+	}
+
+	require.ElementsMatch([]string{
+		"kubernetesResources/problem-pods/default/app-0.txt",
+		"kubernetesResources/problem-pods/default/pending-0.txt",
+	}, paths)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(runCollectors(ctx, options, cols))
+
+	data := string(archive.files["kubernetesResources/problem-pods/default/app-0.txt"])
+
+	require.Regexp(`Name:\s+app-0\n`, data)
+	require.Regexp(`Node:\s+talos-cp-1\n`, data)
+	require.Regexp(`Labels:\s+app=web\n`, data)
+	require.Regexp(`Status:\s+Running\n`, data)
+	require.Regexp(`Ready\s+False`, data)
+	require.Regexp(`State:\s+Waiting \(CrashLoopBackOff\)\n`, data)
+	require.Regexp(`Last State:\s+Terminated \(OOMKilled\) exit code 137 at 2024-06-01T11:57:00Z\n`, data)
+	require.Regexp(`Restart Count:\s+5\n`, data)
+	require.Regexp(`Requests:\s+cpu=500m, memory=1Gi\n`, data)
+	require.Regexp(`Limits:\s+-\n`, data)
+	require.Regexp(`2024-06-01T11:59:30Z\s+Warning\s+BackOff\s+kubelet\s+7\s+Back-off restarting failed container app`, data)
+}
+
+func TestPodLogCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := testClusterClient(t)
+
+	previous, err := collectors.GetPreviousPodLogCollectors(ctx, client, "kube-system", "default")
+	require.NoError(err)
+
+	selected, err := collectors.GetPodLogCollectors(ctx, client, "default", "app=web")
+	require.NoError(err)
+
+	cols := slices.Concat(previous, selected)
+
+	names := map[string]string{}
+
+	for _, col := range cols {
+		names[col.Path()] = col.Name()
+	}
+
+	require.Equal(map[string]string{
+		"kubernetesResources/previous-logs/kube-system/kube-controller-manager-talos-cp-1/main.log": "k8s.previous-logs",
+		"kubernetesResources/previous-logs/default/app-0/app.log":                                   "k8s.previous-logs",
+		"kubernetesResources/pod-logs/default/app-0/app.log":                                        "k8s.pod-logs",
+		"kubernetesResources/pod-logs/default/app-0/app-previous.log":                               "k8s.pod-logs.previous",
+	}, names)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(runCollectors(ctx, options, cols))
+
+	for path := range names {
+		require.Equal("fake logs", string(archive.files[path]), path)
+	}
+}
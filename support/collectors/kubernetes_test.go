@@ -0,0 +1,712 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+type testArchive struct {
+	files   map[string][]byte
+	filesMu sync.Mutex
+}
+
+func (a *testArchive) Write(path string, data []byte) error {
+	a.filesMu.Lock()
+	defer a.filesMu.Unlock()
+
+	if a.files == nil {
+		a.files = map[string][]byte{}
+	}
+
+	a.files[path] = data
+
+	return nil
+}
+
+func (a *testArchive) Close() error {
+	return nil
+}
+
+// startAPIServer runs the fake Kubernetes API server and returns the options with the Kubernetes client connected to it.
+func startAPIServer(ctx context.Context, t *testing.T, handler http.Handler, opts ...bundle.Option) (*bundle.Options, *testArchive) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(append([]bundle.Option{
+		bundle.WithArchive(archive),
+		bundle.WithRESTConfig(&rest.Config{Host: srv.URL}),
+		bundle.WithLogOutput(io.Discard),
+	}, opts...)...)
+
+	require.NoError(t, options.InitKubernetesClient(ctx))
+
+	return options, archive
+}
+
+// respondJSON returns the handler which writes the object as JSON.
+func respondJSON(obj any) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		json.NewEncoder(w).Encode(obj) //nolint:errcheck,errchkjson
+	}
+}
+
+// respondExec returns the handler which serves the pod exec requests writing the output to stdout.
+func respondExec(output func(command []string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := httpstream.Handshake(r, w, []string{"v4.channel.k8s.io"}); err != nil {
+			return
+		}
+
+		streams := make(chan httpstream.Stream, 3)
+
+		conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, _ <-chan struct{}) error {
+			streams <- stream
+
+			return nil
+		})
+		if conn == nil {
+			return
+		}
+
+		defer conn.Close() //nolint:errcheck
+
+		// the client opens the error, stdout and stderr streams
+		for range 3 {
+			stream := <-streams
+
+			if stream.Headers().Get(corev1.StreamType) == corev1.StreamTypeStdout {
+				io.WriteString(stream, output(r.URL.Query()["command"])) //nolint:errcheck
+			}
+
+			stream.Close() //nolint:errcheck
+		}
+
+		select {
+		case <-conn.CloseChan():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func TestKubeProxyRules(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /apis/apps/v1/namespaces/kube-system/daemonsets/kube-proxy", respondJSON(&appsv1.DaemonSet{
+		TypeMeta:   metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-proxy"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "kube-proxy", Command: []string{"/usr/local/bin/kube-proxy", "--proxy-mode=iptables"}}},
+				},
+			},
+		},
+	}))
+
+	pods := &corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+
+	for _, node := range []string{"talos-worker-4", "talos-worker-1", "talos-worker-3", "talos-worker-2"} {
+		pods.Items = append(pods.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy-" + node, Namespace: "kube-system"},
+			Spec: corev1.PodSpec{
+				NodeName:   node,
+				Containers: []corev1.Container{{Name: "kube-proxy"}},
+			},
+		})
+	}
+
+	mux.Handle("GET /api/v1/namespaces/kube-system/pods", respondJSON(pods))
+
+	mux.HandleFunc("GET /api/v1/namespaces/kube-system/pods/{pod}/proxy/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("path") {
+		case "proxyMode":
+			fmt.Fprint(w, "iptables")
+		case "healthz":
+			fmt.Fprint(w, `{"lastUpdated": "2024-06-01T12:00:00Z"}`)
+		default:
+			fmt.Fprint(w, "# HELP kubeproxy_sync_proxy_rules_duration_seconds\nkubeproxy_sync_proxy_rules_duration_seconds_count 42\n")
+		}
+	})
+
+	mux.Handle("POST /api/v1/namespaces/kube-system/pods/{pod}/exec", respondExec(func(command []string) string {
+		if strings.Join(command, " ") != "iptables-save -t nat" {
+			return ""
+		}
+
+		return "*nat\n" + strings.Repeat("-A KUBE-SERVICES -d 10.96.0.1/32 -p tcp -j KUBE-SVC-NPX46M4PTMTKRN6Y\n", 500) + "COMMIT"
+	}))
+
+	options, archive := startAPIServer(ctx, t, mux, bundle.WithoutTalos())
+
+	// the rules are sampled only if enabled
+	cols, err := collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+	require.Nil(findCollector(cols, "k8s.kube-proxy.rules"))
+
+	options.KubeProxyRules = true
+
+	cols, err = collectors.GetForOptions(ctx, options)
+	require.NoError(err)
+
+	rules := findCollector(cols, "k8s.kube-proxy.rules")
+	require.NotNil(rules)
+	require.NoError(rules.Run(ctx, options))
+
+	data := string(archive.files["kubernetesResources/kube-proxy/rules.txt"])
+
+	require.Contains(data, "configured mode: iptables")
+	require.Contains(data, "sampled nodes: 3 of 4")
+	require.Contains(data, "node talos-worker-1 (pod kube-proxy-talos-worker-1)")
+	require.Contains(data, "node talos-worker-3 (pod kube-proxy-talos-worker-3)")
+	require.NotContains(data, "talos-worker-4")
+	require.Contains(data, "kubeproxy_sync_proxy_rules_duration_seconds_count 42")
+	require.Contains(data, "rules (iptables-save -t nat, 200 of 502 lines):")
+	require.Equal(3*199, strings.Count(data, "-A KUBE-SERVICES"))
+}
+
+func TestKubernetesCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	archive := &testArchive{}
+
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver-node-1", Namespace: "kube-system"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"}},
+	)
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithKubernetesClient(client),
+		bundle.WithNamespaces("default"),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(client), func(col *collectors.Collector) bool {
+		return !slices.Contains([]string{
+			"kubernetesResources/nodes.yaml",
+			"kubernetesResources/systemPods.yaml",
+			"kubernetesResources/services.yaml",
+		}, col.Path())
+	})
+	require.Len(t, cols, 3)
+
+	require.NoError(t, runCollectors(ctx, options, cols))
+
+	assert.Contains(t, string(archive.files["kubernetesResources/nodes.yaml"]), "node-1")
+	assert.Contains(t, string(archive.files["kubernetesResources/systemPods.yaml"]), "kube-apiserver-node-1")
+	assert.Contains(t, string(archive.files["kubernetesResources/services.yaml"]), "kubernetes")
+}
+
+func TestCachedLists(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver-talos-cp-1", Namespace: "kube-system", Labels: map[string]string{"k8s-app": "kube-apiserver"}},
+			Spec:       corev1.PodSpec{NodeName: "talos-cp-1"},
+		},
+	)
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(client), func(col *collectors.Collector) bool {
+		return !slices.Contains([]string{
+			"kubernetesResources/nodes.yaml",
+			"kubernetesResources/nodes.txt",
+			"kubernetesResources/node-health.txt",
+			"kubernetesResources/controlPlaneStatus.txt",
+		}, col.Path())
+	})
+	require.Len(cols, 4)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(runCollectors(ctx, options, cols))
+
+	for _, col := range cols {
+		require.Contains(string(archive.files[col.Path()]), "talos-cp-1")
+	}
+
+	// the collectors created from the lists share them with the reports
+	_, err := collectors.GetKubeletCollectors(ctx, options, client)
+	require.NoError(err)
+
+	_, err = collectors.GetProblemPodCollectors(ctx, options, client)
+	require.NoError(err)
+
+	lists := map[string]int{}
+
+	for _, action := range client.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			lists[action.GetResource().Resource+"/"+action.GetNamespace()+"/"+list.GetListRestrictions().Labels.String()]++
+		}
+	}
+
+	require.Equal(1, lists["nodes//"])
+	require.Equal(1, lists["pods//"])
+	require.Equal(1, lists["pods/kube-system/k8s-app=kube-apiserver"])
+
+	calls := 0
+
+	for range 2 {
+		_, err = bundle.NewOptions().Cached(ctx, "key", func() (any, error) {
+			calls++
+
+			return nil, errors.New("failed")
+		})
+		require.Error(err)
+	}
+
+	require.Equal(2, calls)
+}
+
+func TestPaginatedLists(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	client := fake.NewSimpleClientset()
+
+	// the fake client doesn't paginate, so the pages are served in the order of the calls
+	namespacePages := [][]string{{"default", "kube-system"}, {"kube-public", "kube-node-lease"}, {"monitoring"}}
+	namespaceCalls := 0
+
+	client.PrependReactor("list", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+		page := namespacePages[namespaceCalls]
+		namespaceCalls++
+
+		list := &corev1.NamespaceList{}
+
+		if namespaceCalls < len(namespacePages) {
+			list.Continue = strconv.Itoa(namespaceCalls)
+		}
+
+		for _, name := range page {
+			list.Items = append(list.Items, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+
+		return true, list, nil
+	})
+
+	// the continue token expires after the first page, so the list is restarted without pagination
+	csiNodeCalls := 0
+
+	client.PrependReactor("list", "csinodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		csiNodeCalls++
+
+		switch csiNodeCalls {
+		case 1:
+			return true, &storagev1.CSINodeList{
+				ListMeta: metav1.ListMeta{Continue: "1"},
+				Items:    []storagev1.CSINode{{ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1"}}},
+			}, nil
+		case 2:
+			return true, nil, apierrors.NewResourceExpired("the continue token has expired")
+		default:
+			return true, &storagev1.CSINodeList{
+				Items: []storagev1.CSINode{
+					{ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "talos-worker-1"}},
+				},
+			}, nil
+		}
+	})
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(client), func(col *collectors.Collector) bool {
+		return !slices.Contains([]string{
+			"kubernetesResources/namespaces.yaml",
+			"kubernetesResources/storage/csiNodes.yaml",
+		}, col.Path())
+	})
+	require.Len(cols, 2)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(runCollectors(ctx, options, cols))
+
+	require.Equal(3, namespaceCalls)
+
+	for _, page := range namespacePages {
+		for _, name := range page {
+			require.Contains(string(archive.files["kubernetesResources/namespaces.yaml"]), "name: "+name)
+		}
+	}
+
+	require.Equal(3, csiNodeCalls)
+
+	csiNodes := string(archive.files["kubernetesResources/storage/csiNodes.yaml"])
+
+	require.Equal(1, strings.Count(csiNodes, "name: talos-cp-1"))
+	require.Contains(csiNodes, "name: talos-worker-1")
+}
+
+func TestKubernetesRESTCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	require := require.New(t)
+
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /apis/apiregistration.k8s.io/v1/apiservices", respondJSON(map[string]any{
+		"apiVersion": "apiregistration.k8s.io/v1",
+		"kind":       "APIServiceList",
+		"items": []any{
+			map[string]any{
+				"apiVersion": "apiregistration.k8s.io/v1",
+				"kind":       "APIService",
+				"metadata":   map[string]any{"name": "v1beta1.metrics.k8s.io"},
+			},
+		},
+	}))
+
+	mux.Handle("GET /apis/metrics.k8s.io/v1beta1/nodes", respondJSON(map[string]any{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "NodeMetricsList",
+		"items": []any{
+			map[string]any{
+				"metadata": map[string]any{"name": "talos-cp-1"},
+				"usage":    map[string]any{"cpu": "250m", "memory": "1Gi"},
+			},
+		},
+	}))
+
+	mux.Handle("GET /apis/metrics.k8s.io/v1beta1/pods", respondJSON(map[string]any{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "PodMetricsList",
+		"items": []any{
+			map[string]any{
+				"metadata": map[string]any{"name": "coredns-abcde", "namespace": "kube-system"},
+				"containers": []any{
+					map[string]any{"name": "coredns", "usage": map[string]any{"cpu": "100m", "memory": "64Mi"}},
+					map[string]any{"name": "sidecar", "usage": map[string]any{"cpu": "50m", "memory": "64Mi"}},
+				},
+			},
+		},
+	}))
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed")
+	})
+
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "[+]ping ok\nlivez check passed")
+	})
+
+	mux.Handle("GET /version", respondJSON(map[string]any{
+		"gitVersion": "v1.30.3",
+		"goVersion":  "go1.22.5",
+		"platform":   "linux/amd64",
+	}))
+
+	mux.Handle("GET /api/v1/nodes", respondJSON(&corev1.NodeList{
+		TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+		Items:    []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "talos-cp-1"}}},
+	}))
+
+	mux.HandleFunc("GET /api/v1/nodes/talos-cp-1/proxy/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"path": %q}`, r.PathValue("path"))
+	})
+
+	mux.Handle("GET /api/v1/namespaces/kube-system/endpoints/kube-dns", respondJSON(&corev1.Endpoints{ //nolint:staticcheck
+		TypeMeta:   metav1.TypeMeta{Kind: "Endpoints", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"},
+		Subsets: []corev1.EndpointSubset{ //nolint:staticcheck
+			{
+				Addresses:         []corev1.EndpointAddress{{IP: "127.0.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "coredns-abcde"}}},
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "127.0.0.2"}},
+			},
+		},
+	}))
+
+	mux.Handle("GET /api/v1/namespaces/kube-system/configmaps/coredns", respondJSON(&corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		Data:       map[string]string{"Corefile": ".:53 {\n    kubernetes talos.local in-addr.arpa ip6.arpa\n}\n"},
+	}))
+
+	mux.HandleFunc("GET /api/v1/namespaces/kube-system/pods/http:coredns-abcde:8181/proxy/ready", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+
+	options, archive := startAPIServer(ctx, t, mux)
+
+	kubeletCollectors, err := collectors.GetKubeletCollectors(ctx, options, options.KubernetesClient)
+	require.NoError(err)
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(options.KubernetesClient), func(col *collectors.Collector) bool {
+		return !slices.Contains([]string{
+			"kubernetesResources/apiservices.yaml",
+			"kubernetesResources/metrics/nodes.txt",
+			"kubernetesResources/metrics/pods.txt",
+			"kubernetesResources/apiserver-health.txt",
+			"kubernetesResources/coredns/resolution.txt",
+		}, col.Path())
+	})
+	require.Len(cols, 5)
+
+	require.NoError(runCollectors(ctx, options, append(cols, kubeletCollectors...)))
+
+	require.Contains(string(archive.files["kubernetesResources/apiservices.yaml"]), "name: v1beta1.metrics.k8s.io")
+	require.Regexp(`talos-cp-1\s+250m\s+1024Mi`, string(archive.files["kubernetesResources/metrics/nodes.txt"]))
+	require.Regexp(`kube-system\s+coredns-abcde\s+150m\s+128Mi`, string(archive.files["kubernetesResources/metrics/pods.txt"]))
+
+	health := string(archive.files["kubernetesResources/apiserver-health.txt"])
+
+	require.Contains(health, "/readyz:\n[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed\n")
+	require.Contains(health, "/livez:\n[+]ping ok\nlivez check passed\n")
+	require.Contains(health, "/version:\nGitVersion: v1.30.3\n")
+	require.Contains(health, "Platform: linux/amd64\n")
+
+	resolution := string(archive.files["kubernetesResources/coredns/resolution.txt"])
+
+	require.Contains(resolution, "resolving kubernetes.default.svc.talos.local.\n")
+	require.Regexp(`127.0.0.1\s+coredns-abcde\s+true\s+OK\s+not run \(pod network is not reachable\)`, resolution)
+	require.Regexp(`127.0.0.2\s+-\s+false\s+-\s+not run \(pod network is not reachable\)`, resolution)
+
+	require.Equal(`{"path": "stats/summary"}`, string(archive.files["kubernetesResources/kubelet/talos-cp-1/stats-summary.json"]))
+	require.Equal(`{"path": "pods"}`, string(archive.files["kubernetesResources/kubelet/talos-cp-1/pods.json"]))
+}
+
+func TestMetricsNotServed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	options, archive := startAPIServer(ctx, t, http.NotFoundHandler())
+
+	cols := slices.DeleteFunc(collectors.GetKubernetesCollectors(options.KubernetesClient), func(col *collectors.Collector) bool {
+		return !strings.HasPrefix(col.Name(), "k8s.metrics.")
+	})
+	require.Len(cols, 2)
+
+	require.NoError(runCollectors(ctx, options, cols))
+	require.Empty(archive.files)
+}
+
+func TestDynamicCollectors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	crdGVR := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	widgetGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+	listKinds := map[schema.GroupVersionResource]string{
+		crdGVR:    "CustomResourceDefinitionList",
+		widgetGVR: "WidgetList",
+		{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"}:            "CiliumNetworkPolicyList",
+		{Group: "cilium.io", Version: "v2", Resource: "ciliumclusterwidenetworkpolicies"}: "CiliumClusterwideNetworkPolicyList",
+		{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gatewayclasses"}:   "GatewayClassList",
+		{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}:         "GatewayList",
+		{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}:       "HTTPRouteList",
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata": map[string]any{
+				"name":              "widgets.example.com",
+				"creationTimestamp": "2024-06-01T12:00:00Z",
+			},
+			"spec": map[string]any{
+				"group": "example.com",
+				"scope": "Namespaced",
+				"names": map[string]any{"kind": "Widget"},
+				"versions": []any{
+					map[string]any{"name": "v1", "served": true},
+					map[string]any{"name": "v1beta1", "served": true, "deprecated": true},
+					map[string]any{"name": "v1alpha1", "served": false},
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata":   map[string]any{"name": "blue", "namespace": "default"},
+		}},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "cilium.io/v2",
+			"kind":       "CiliumNetworkPolicy",
+			"metadata":   map[string]any{"name": "allow-dns", "namespace": "kube-system"},
+		}},
+	)
+
+	cols := collectors.GetDynamicCollectors(client, widgetGVR)
+
+	names := make([]string, 0, len(cols))
+
+	for _, col := range cols {
+		names = append(names, col.Name())
+	}
+
+	require.Equal([]string{
+		"k8s.crds",
+		"k8s.network-policies.ciliumnetworkpolicies",
+		"k8s.network-policies.ciliumclusterwidenetworkpolicies",
+		"k8s.ingress.gatewayclasses",
+		"k8s.ingress.gateways",
+		"k8s.ingress.httproutes",
+		"k8s.custom-resources.widgets.example.com",
+	}, names)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	require.NoError(runCollectors(ctx, options, cols))
+
+	require.Regexp(`widgets.example.com\s+example.com\s+Widget\s+Namespaced\s+v1,v1beta1\(deprecated\)\s+2024-06-01T12:00:00Z`, string(archive.files["kubernetesResources/crds.txt"]))
+	require.Contains(string(archive.files["kubernetesResources/customResources/widgets.example.com.yaml"]), "name: blue")
+	require.Contains(string(archive.files["kubernetesResources/networkPolicies/ciliumnetworkpolicies.yaml"]), "name: allow-dns")
+
+	// the empty lists are not written
+	require.Len(archive.files, 3)
+}
+
+func TestPodLogOptions(t *testing.T) {
+	from := time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	logs := "2024-06-01T11:30:00Z in range\ncontinued\n2024-06-01T12:30:00Z after the range\n"
+
+	for _, test := range []struct {
+		name     string
+		options  []bundle.Option
+		query    map[string]string
+		expected string
+	}{
+		{
+			name:     "defaults",
+			query:    map[string]string{"container": "app"},
+			expected: logs,
+		},
+		{
+			name:     "tail lines for the container",
+			options:  []bundle.Option{bundle.WithLogTailLines(100), bundle.WithLogTailLinesFor("app", 20)},
+			query:    map[string]string{"container": "app", "tailLines": "20"},
+			expected: logs,
+		},
+		{
+			name:     "pod log tail lines",
+			options:  []bundle.Option{bundle.WithLogTailLines(100), bundle.WithPodLogTailLines(5)},
+			query:    map[string]string{"container": "app", "tailLines": "5"},
+			expected: logs,
+		},
+		{
+			name:     "time range",
+			options:  []bundle.Option{bundle.WithTimeRange(from, to)},
+			query:    map[string]string{"container": "app", "sinceTime": "2024-06-01T11:00:00Z", "timestamps": "true"},
+			expected: "2024-06-01T11:30:00Z in range\ncontinued\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			require := require.New(t)
+
+			mux := http.NewServeMux()
+
+			mux.Handle("GET /api/v1/namespaces/default/pods", respondJSON(&corev1.PodList{
+				TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+				Items: []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "app-0", Namespace: "default", Labels: map[string]string{"app": "web"}},
+						Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "app"}}},
+					},
+				},
+			}))
+
+			query := map[string]string{}
+
+			mux.HandleFunc("GET /api/v1/namespaces/default/pods/app-0/log", func(w http.ResponseWriter, r *http.Request) {
+				for key := range r.URL.Query() {
+					query[key] = r.URL.Query().Get(key)
+				}
+
+				fmt.Fprint(w, logs)
+			})
+
+			options, archive := startAPIServer(ctx, t, mux, test.options...)
+
+			cols, err := collectors.GetPodLogCollectors(ctx, options.KubernetesClient, "default", "app=web")
+			require.NoError(err)
+			require.Len(cols, 1)
+
+			require.NoError(cols[0].Run(ctx, options))
+
+			require.Equal(test.query, query)
+			require.Equal(test.expected, string(archive.files["kubernetesResources/pod-logs/default/app-0/app.log"]))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity of the buffers above which they are not put back to the pool,
+// so a single large file doesn't keep the memory for the rest of the collection.
+const maxPooledBufferSize = 1024 * 1024
+
+var (
+	bufferPool = sync.Pool{
+		New: func() any {
+			return new(bytes.Buffer)
+		},
+	}
+
+	bufioWriterPool = sync.Pool{
+		New: func() any {
+			return bufio.NewWriterSize(nil, directStreamBufferSize)
+		},
+	}
+
+	gzipWriterPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(nil)
+		},
+	}
+)
+
+// getBuffer returns the empty buffer from the pool.
+//
+// The buffer data must not be used after the buffer is put back with putBuffer.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer) //nolint:forcetypeassert
+}
+
+// putBuffer puts the buffer back to the pool.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	buf.Reset()
+
+	bufferPool.Put(buf)
+}
+
+// getBufioWriter returns the buffered writer of the direct stream size writing to w.
+func getBufioWriter(w io.Writer) *bufio.Writer {
+	bw := bufioWriterPool.Get().(*bufio.Writer) //nolint:forcetypeassert
+	bw.Reset(w)
+
+	return bw
+}
+
+// putBufioWriter puts the buffered writer back to the pool.
+func putBufioWriter(bw *bufio.Writer) {
+	bw.Reset(nil)
+
+	bufioWriterPool.Put(bw)
+}
+
+// getGzipWriter returns the gzip writer writing to w, the gzip writers are large, so they are reused.
+func getGzipWriter(w io.Writer) *gzip.Writer {
+	zw := gzipWriterPool.Get().(*gzip.Writer) //nolint:forcetypeassert
+	zw.Reset(w)
+
+	return zw
+}
+
+// putGzipWriter puts the gzip writer back to the pool.
+func putGzipWriter(zw *gzip.Writer) {
+	zw.Reset(nil)
+
+	gzipWriterPool.Put(zw)
+}
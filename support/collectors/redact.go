@@ -0,0 +1,211 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/redact"
+)
+
+const (
+	// binaryCheckSize is the size of the data prefix checked for the binary contents.
+	binaryCheckSize = 8000
+
+	// redactChunkSize is the size of the streamed data the redactors are run on at once.
+	redactChunkSize = 64 * 1024
+
+	// maxRedactChunkSize limits the chunk growth while it ends in the middle of a PEM block.
+	maxRedactChunkSize = 1024 * 1024
+)
+
+// redact runs the redactors on the collected data, the binary data is left as is.
+func (c *Collector) redact(options *bundle.Options, data []byte) ([]byte, error) {
+	redactors := c.redactors(options)
+
+	if len(redactors) == 0 || isBinary(bytes.NewReader(data)) {
+		return data, nil
+	}
+
+	return c.runRedactors(options, redactors, data)
+}
+
+func (c *Collector) runRedactors(options *bundle.Options, redactors []bundle.Redactor, data []byte) ([]byte, error) {
+	for _, redactor := range redactors {
+		var (
+			count int
+			err   error
+		)
+
+		data, count, err = redactor.Redact(c.destinationPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("redactor %s failed on %s: %w", redactor.Name(), c.destinationPath, err)
+		}
+
+		options.RecordRedaction(options.ArchivePath(c.destinationPath), redactor.Name(), count)
+	}
+
+	return data, nil
+}
+
+// redactsWholeFile checks if the collected data has to be redacted as a whole.
+//
+// The YAML documents are parsed by the redactors, other files are redacted in chunks of whole lines as they're streamed.
+func (c *Collector) redactsWholeFile(options *bundle.Options) bool {
+	return strings.HasSuffix(c.destinationPath, ".yaml") && len(c.redactors(options)) > 0
+}
+
+// redactors returns the built-in redactors for the collector followed by the custom ones.
+//
+// The Kubernetes secrets are always removed from the YAML files, other built-in redactors are enabled by the options.
+func (c *Collector) redactors(options *bundle.Options) []bundle.Redactor {
+	var builtin []bundle.Redactor
+
+	if strings.HasSuffix(c.destinationPath, ".yaml") {
+		builtin = append(builtin, redact.Kubernetes())
+	}
+
+	if options.RedactSecrets {
+		builtin = append(builtin, redact.Secrets()...)
+	}
+
+	if options.RedactPII {
+		builtin = append(builtin, redact.PII()...)
+	}
+
+	return slices.Concat(builtin, options.Redactors)
+}
+
+// isBinary detects the binary data the same way git does: by looking for the zero byte in the beginning of the data.
+func isBinary(data io.ReaderAt) bool {
+	head := make([]byte, binaryCheckSize)
+
+	n, _ := data.ReadAt(head, 0) //nolint:errcheck
+
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// redactWriter runs the redactors on the streamed data in chunks of whole lines.
+//
+// The binary data is written as is, the PEM blocks are kept in a single chunk.
+type redactWriter struct {
+	w         io.Writer
+	collector *Collector
+	options   *bundle.Options
+	redactors []bundle.Redactor
+	buf       []byte
+	checked   bool
+	binary    bool
+}
+
+func (c *Collector) newRedactWriter(options *bundle.Options, w io.Writer) *redactWriter {
+	return &redactWriter{
+		w:         w,
+		collector: c,
+		options:   options,
+		redactors: c.redactors(options),
+	}
+}
+
+// Write implements io.Writer.
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if rw.binary {
+		return rw.w.Write(p)
+	}
+
+	rw.buf = append(rw.buf, p...)
+
+	if !rw.checked {
+		if len(rw.buf) < binaryCheckSize {
+			return len(p), nil
+		}
+
+		if err := rw.check(); err != nil {
+			return 0, err
+		}
+
+		if rw.binary {
+			return len(p), nil
+		}
+	}
+
+	for len(rw.buf) >= redactChunkSize {
+		n := rw.chunkSize()
+		if n == 0 {
+			break
+		}
+
+		if err := rw.flush(n); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close redacts and writes the rest of the data, the underlying writer is not closed.
+func (rw *redactWriter) Close() error {
+	if len(rw.buf) == 0 {
+		return nil
+	}
+
+	if !rw.checked {
+		if err := rw.check(); err != nil || rw.binary {
+			return err
+		}
+	}
+
+	return rw.flush(len(rw.buf))
+}
+
+// check detects the binary data by its prefix, the binary data buffered so far is written as is.
+func (rw *redactWriter) check() error {
+	rw.checked = true
+	rw.binary = isBinary(bytes.NewReader(rw.buf))
+
+	if !rw.binary {
+		return nil
+	}
+
+	_, err := rw.w.Write(rw.buf)
+	rw.buf = nil
+
+	return err
+}
+
+// chunkSize returns the size of the buffered data ready to be redacted, or zero if more data is needed.
+func (rw *redactWriter) chunkSize() int {
+	n := bytes.LastIndexByte(rw.buf, '\n') + 1
+
+	// the unfinished PEM block is moved to the next chunk
+	if begin := bytes.LastIndex(rw.buf[:n], []byte("-----BEGIN ")); begin >= 0 && !bytes.Contains(rw.buf[begin:n], []byte("-----END ")) {
+		n = bytes.LastIndexByte(rw.buf[:begin], '\n') + 1
+	}
+
+	// the data without the line breaks is redacted as is once the chunk gets too large
+	if n == 0 && len(rw.buf) >= maxRedactChunkSize {
+		n = len(rw.buf)
+	}
+
+	return n
+}
+
+func (rw *redactWriter) flush(n int) error {
+	data, err := rw.collector.runRedactors(rw.options, rw.redactors, bytes.Clone(rw.buf[:n]))
+	if err != nil {
+		return err
+	}
+
+	rw.buf = append(rw.buf[:0], rw.buf[n:]...)
+
+	_, err = rw.w.Write(data)
+
+	return err
+}
@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// spillThreshold is the size of the data kept in memory before it's spilled to disk.
+const spillThreshold = 4 * 1024 * 1024
+
+// directStreamBufferSize is the size of the buffers between the collector and the archive
+// when the data is streamed directly.
+const directStreamBufferSize = 64 * 1024
+
+// spillBuffer keeps the written data in memory until it grows over the threshold,
+// then moves it to a temporary file in the directory.
+//
+// The data is always kept in memory if the directory is not set.
+type spillBuffer struct {
+	file *os.File
+	// buf is taken from the pool on the first write and put back when the data is spilled or the buffer is closed
+	buf       *bytes.Buffer
+	dir       string
+	size      int64
+	threshold int
+}
+
+// Write implements io.Writer.
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.buf == nil {
+		b.buf = getBuffer()
+	}
+
+	if b.file == nil && b.dir != "" && b.buf.Len()+len(p) > b.threshold {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		n   int
+		err error
+	)
+
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.buf.Write(p)
+	}
+
+	b.size += int64(n)
+
+	return n, err
+}
+
+func (b *spillBuffer) spill() error {
+	file, err := os.CreateTemp(b.dir, "talos-support-*")
+	if err != nil {
+		return err
+	}
+
+	b.file = file
+
+	if _, err = b.buf.WriteTo(file); err != nil {
+		return err
+	}
+
+	putBuffer(b.buf)
+	b.buf = nil
+
+	return nil
+}
+
+// Size returns the number of bytes written.
+func (b *spillBuffer) Size() int64 {
+	return b.size
+}
+
+// ReaderAt returns the reader for the written data.
+func (b *spillBuffer) ReaderAt() io.ReaderAt {
+	if b.file != nil {
+		return b.file
+	}
+
+	if b.buf == nil {
+		return bytes.NewReader(nil)
+	}
+
+	return bytes.NewReader(b.buf.Bytes())
+}
+
+// Close removes the temporary file and puts the memory buffer back to the pool.
+func (b *spillBuffer) Close() error {
+	if b.buf != nil {
+		putBuffer(b.buf)
+		b.buf = nil
+	}
+
+	if b.file == nil {
+		return nil
+	}
+
+	return errors.Join(b.file.Close(), os.Remove(b.file.Name()))
+}
@@ -6,6 +6,7 @@ package collectors
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -14,39 +15,94 @@ import (
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/resource/meta"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
 	"github.com/dustin/go-humanize"
 	"github.com/siderolabs/talos/pkg/machinery/api/common"
 	"github.com/siderolabs/talos/pkg/machinery/api/machine"
 	"github.com/siderolabs/talos/pkg/machinery/client"
 	"github.com/siderolabs/talos/pkg/machinery/constants"
 	"github.com/siderolabs/talos/pkg/machinery/formatters"
+	"github.com/siderolabs/talos/pkg/machinery/resources/config"
 	"github.com/siderolabs/talos/pkg/machinery/version"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"gopkg.in/yaml.v3"
 
 	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/redact"
 )
 
-func dmesg(ctx context.Context, options *bundle.Options) ([]byte, error) {
+func dmesg(ctx context.Context, options *bundle.Options, w io.Writer) error {
 	stream, err := options.TalosClient.Dmesg(ctx, false, false)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	data := []byte{}
+	return copyStream(w, stream)
+}
+
+func logs(id, name string, kubernetes bool) StreamCollect {
+	return func(ctx context.Context, options *bundle.Options, w io.Writer) error {
+		var (
+			namespace string
+			driver    common.ContainerDriver
+			err       error
+		)
 
+		if kubernetes {
+			namespace = constants.K8sContainerdNamespace
+			driver = common.ContainerDriver_CRI
+		} else {
+			namespace = constants.SystemContainerdNamespace
+			driver = common.ContainerDriver_CONTAINERD
+		}
+
+		options.Log("getting %s/%s service logs", namespace, name)
+
+		tailLines := options.TailLines(name)
+		if tailLines <= 0 {
+			tailLines = -1
+		}
+
+		stream, err := options.TalosClient.Logs(ctx, namespace, driver, id, false, tailLines)
+		if err != nil {
+			return err
+		}
+
+		if since, until := options.TimeRange(); since.IsZero() && until.IsZero() {
+			return copyStream(w, stream)
+		}
+
+		filter := newLogLineFilter(w, options)
+
+		if err = copyStream(filter, stream); err != nil {
+			return err
+		}
+
+		return filter.Flush()
+	}
+}
+
+// dataStream is the Talos API stream of the data chunks.
+type dataStream interface {
+	Recv() (*common.Data, error)
+}
+
+// copyStream writes the data chunks to the writer as they are received.
+func copyStream(w io.Writer, stream dataStream) error {
 	for {
 		resp, err := stream.Recv()
 		if err != nil {
 			if errors.Is(err, io.EOF) || client.StatusCode(err) == codes.Canceled {
-				break
+				return nil
 			}
 
-			return nil, fmt.Errorf("error reading from stream: %w", err)
+			return fmt.Errorf("error reading from stream: %w", err)
 		}
 
 		if resp.Metadata != nil {
@@ -55,58 +111,122 @@ func dmesg(ctx context.Context, options *bundle.Options) ([]byte, error) {
 			}
 		}
 
-		data = append(data, resp.GetBytes()...)
+		if _, err = w.Write(resp.GetBytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// filterLogLines drops the log lines outside of the time range set in the options.
+//
+// The lines without a recognized timestamp are kept or dropped together with the previous line,
+// so multi-line entries are not split.
+func filterLogLines(options *bundle.Options, data []byte) []byte {
+	if since, until := options.TimeRange(); since.IsZero() && until.IsZero() {
+		return data
 	}
 
-	return data, nil
+	var buf bytes.Buffer
+
+	filter := newLogLineFilter(&buf, options)
+
+	filter.Write(data) //nolint:errcheck
+	filter.Flush()     //nolint:errcheck
+
+	return buf.Bytes()
 }
 
-func logs(service string, kubernetes bool) Collect {
-	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
-		var (
-			namespace string
-			driver    common.ContainerDriver
-			err       error
-		)
+// logLineFilter is filterLogLines for the logs written in chunks, the line split between the chunks is kept
+// until it's complete.
+type logLineFilter struct {
+	w       io.Writer
+	options *bundle.Options
+	partial []byte
+	keep    bool
+}
 
-		if kubernetes {
-			namespace = constants.K8sContainerdNamespace
-			driver = common.ContainerDriver_CRI
-		} else {
-			namespace = constants.SystemContainerdNamespace
-			driver = common.ContainerDriver_CONTAINERD
+func newLogLineFilter(w io.Writer, options *bundle.Options) *logLineFilter {
+	return &logLineFilter{
+		w:       w,
+		options: options,
+		keep:    true,
+	}
+}
+
+// Write implements io.Writer.
+func (f *logLineFilter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			f.partial = append(f.partial, p...)
+
+			break
 		}
 
-		options.Log("getting %s/%s service logs", namespace, service)
+		line := p[:i+1]
 
-		stream, err := options.TalosClient.Logs(ctx, namespace, driver, service, false, -1)
-		if err != nil {
-			return nil, err
+		if len(f.partial) > 0 {
+			line = append(f.partial, line...)
 		}
 
-		data := []byte{}
+		if err := f.writeLine(line); err != nil {
+			return 0, err
+		}
 
-		for {
-			resp, err := stream.Recv()
-			if err != nil {
-				if errors.Is(err, io.EOF) || client.StatusCode(err) == codes.Canceled {
-					break
-				}
+		f.partial = f.partial[:0]
+		p = p[i+1:]
+	}
 
-				return nil, fmt.Errorf("error reading from stream: %w", err)
-			}
+	return n, nil
+}
 
-			if resp.Metadata != nil {
-				if resp.Metadata.Error != "" {
-					fmt.Fprintf(os.Stderr, "%s\n", resp.Metadata.Error)
-				}
-			}
+// Flush writes the last line if it doesn't end with the newline.
+func (f *logLineFilter) Flush() error {
+	if len(f.partial) == 0 {
+		return nil
+	}
+
+	err := f.writeLine(f.partial)
+
+	f.partial = nil
+
+	return err
+}
+
+func (f *logLineFilter) writeLine(line []byte) error {
+	if timestamp, ok := logLineTimestamp(line); ok {
+		f.keep = f.options.InTimeRange(timestamp)
+	}
+
+	if !f.keep {
+		return nil
+	}
+
+	_, err := f.w.Write(line)
+
+	return err
+}
+
+// goLogTimestampLayout is the timestamp layout of the Go log package.
+const goLogTimestampLayout = "2006/01/02 15:04:05"
 
-			data = append(data, resp.GetBytes()...)
+// logLineTimestamp parses the timestamp the log line starts with: RFC3339 (as in CRI logs) or Go log package format.
+func logLineTimestamp(line []byte) (time.Time, bool) {
+	if field, _, ok := bytes.Cut(line, []byte(" ")); ok {
+		if t, err := time.Parse(time.RFC3339Nano, string(field)); err == nil {
+			return t, true
 		}
+	}
 
-		return data, nil
+	if len(line) >= len(goLogTimestampLayout) {
+		if t, err := time.Parse(goLogTimestampLayout, string(line[:len(goLogTimestampLayout)])); err == nil {
+			return t, true
+		}
 	}
+
+	return time.Time{}, false
 }
 
 func dependencies(ctx context.Context, options *bundle.Options) ([]byte, error) {
@@ -128,6 +248,26 @@ func dependencies(ctx context.Context, options *bundle.Options) ([]byte, error)
 	return buf.Bytes(), nil
 }
 
+// machineConfig collects the machine config with the secrets removed regardless of the bundle redaction options.
+func machineConfig(ctx context.Context, options *bundle.Options) ([]byte, error) {
+	options.Log("getting machine config")
+
+	machineConfig, err := safe.StateGet[*config.MachineConfig](ctx, options.TalosClient.COSI,
+		resource.NewMetadata(config.NamespaceName, config.MachineConfigType, config.V1Alpha1ID, resource.VersionUndefined))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := machineConfig.Container().Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err = redact.MachineConfig().Redact("machine-config.yaml", data)
+
+	return data, err
+}
+
 func mounts(ctx context.Context, options *bundle.Options) ([]byte, error) {
 	options.Log("getting mounts")
 
@@ -160,6 +300,137 @@ func devices(ctx context.Context, options *bundle.Options) ([]byte, error) {
 	return io.ReadAll(r)
 }
 
+func nodeFile(path string) StreamCollect {
+	return func(ctx context.Context, options *bundle.Options, w io.Writer) error {
+		options.Log("reading %s", path)
+
+		r, err := options.TalosClient.Read(ctx, path)
+		if err != nil {
+			return err
+		}
+
+		defer r.Close() //nolint:errcheck
+
+		_, err = io.Copy(w, r)
+
+		return err
+	}
+}
+
+func etcdStatus(ctx context.Context, options *bundle.Options) ([]byte, error) {
+	options.Log("getting etcd status")
+
+	members, err := options.TalosClient.EtcdMemberList(ctx, &machine.EtcdMemberListRequest{QueryLocal: true})
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := options.TalosClient.EtcdStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	alarms, err := options.TalosClient.EtcdAlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "MEMBER\tHOSTNAME\tPEER URLS\tCLIENT URLS\tLEARNER") //nolint:errcheck
+
+	for _, msg := range members.GetMessages() {
+		for _, member := range msg.GetMembers() {
+			fmt.Fprintf(w, "%x\t%s\t%s\t%s\t%t\n", //nolint:errcheck
+				member.GetId(),
+				member.GetHostname(),
+				strings.Join(member.GetPeerUrls(), ","),
+				strings.Join(member.GetClientUrls(), ","),
+				member.GetIsLearner(),
+			)
+		}
+	}
+
+	fmt.Fprintln(w)                                                                                       //nolint:errcheck
+	fmt.Fprintln(w, "MEMBER\tDB SIZE\tIN USE\tLEADER\tRAFT INDEX\tRAFT TERM\tRAFT APPLIED INDEX\tERRORS") //nolint:errcheck
+
+	for _, msg := range status.GetMessages() {
+		memberStatus := msg.GetMemberStatus()
+
+		fmt.Fprintf(w, "%x\t%s\t%s\t%x\t%d\t%d\t%d\t%s\n", //nolint:errcheck
+			memberStatus.GetMemberId(),
+			humanize.Bytes(uint64(memberStatus.GetDbSize())),
+			humanize.Bytes(uint64(memberStatus.GetDbSizeInUse())),
+			memberStatus.GetLeader(),
+			memberStatus.GetRaftIndex(),
+			memberStatus.GetRaftTerm(),
+			memberStatus.GetRaftAppliedIndex(),
+			strings.Join(memberStatus.GetErrors(), ", "),
+		)
+	}
+
+	fmt.Fprintln(w)                  //nolint:errcheck
+	fmt.Fprintln(w, "MEMBER\tALARM") //nolint:errcheck
+
+	for _, msg := range alarms.GetMessages() {
+		for _, alarm := range msg.GetMemberAlarms() {
+			fmt.Fprintf(w, "%x\t%s\n", alarm.GetMemberId(), alarm.GetAlarm()) //nolint:errcheck
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+func etcdSnapshot(ctx context.Context, options *bundle.Options, w io.Writer) error {
+	options.Log("getting etcd snapshot")
+
+	r, err := options.TalosClient.EtcdSnapshot(ctx, &machine.EtcdSnapshotRequest{})
+	if err != nil {
+		return err
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	_, err = io.Copy(w, r)
+
+	return err
+}
+
+// packetCaptureSnapLen is the maximum number of bytes captured from each packet.
+const packetCaptureSnapLen = 65536
+
+func packetCapture(iface string, duration time.Duration) StreamCollect {
+	return func(ctx context.Context, options *bundle.Options, w io.Writer) error {
+		options.Log("capturing packets on %s for %s", iface, duration)
+
+		captureCtx, cancel := context.WithTimeout(ctx, duration)
+		defer cancel()
+
+		r, err := options.TalosClient.PacketCapture(captureCtx, &machine.PacketCaptureRequest{
+			Interface: iface,
+			SnapLen:   packetCaptureSnapLen,
+		})
+		if err != nil {
+			return err
+		}
+
+		defer r.Close() //nolint:errcheck
+
+		if _, err = io.Copy(w, r); err != nil {
+			return err
+		}
+
+		// the capture is stopped by the timeout, so only the parent context cancellation is an error
+		return ctx.Err()
+	}
+}
+
 func ioPressure(ctx context.Context, options *bundle.Options) ([]byte, error) {
 	options.Log("getting disk stats")
 
@@ -173,9 +444,10 @@ func ioPressure(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
+	buf := getBuffer()
+	defer putBuffer(buf)
 
-	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "NAME\tIO_TIME\tIO_TIME_WEIGHTED\tDISK_WRITE_SECTORS\tDISK_READ_SECTORS") //nolint:errcheck
 
 	for _, msg := range resp.Messages {
@@ -194,7 +466,7 @@ func ioPressure(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		return nil, err
 	}
 
-	return buf.Bytes(), nil
+	return bytes.Clone(buf.Bytes()), nil
 }
 
 func processes(ctx context.Context, options *bundle.Options) ([]byte, error) {
@@ -205,9 +477,10 @@ func processes(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
+	buf := getBuffer()
+	defer putBuffer(buf)
 
-	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	w := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "PID\tSTATE\tTHREADS\tCPU-TIME\tVIRTMEM\tRESMEM\tCOMMAND") //nolint:errcheck
 
 	for _, msg := range resp.Messages {
@@ -234,7 +507,7 @@ func processes(ctx context.Context, options *bundle.Options) ([]byte, error) {
 		return nil, err
 	}
 
-	return buf.Bytes(), nil
+	return bytes.Clone(buf.Bytes()), nil
 }
 
 func summary(ctx context.Context, options *bundle.Options) ([]byte, error) {
@@ -257,47 +530,74 @@ func summary(ctx context.Context, options *bundle.Options) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func talosResource(rd *meta.ResourceDefinition) Collect {
-	return func(ctx context.Context, options *bundle.Options) ([]byte, error) {
+// talosResource streams the resources of the type to the file as they are received.
+//
+// The resources are read with the bootstrapped watch instead of the list call, so the large resource lists
+// are not buffered by apid and the client as a whole.
+func talosResource(rd *meta.ResourceDefinition) StreamCollect {
+	return func(ctx context.Context, options *bundle.Options, w io.Writer) error {
 		options.Log("getting talos resource %s/%s", rd.TypedSpec().DefaultNamespace, rd.TypedSpec().Type)
 
-		resources, err := options.TalosClient.COSI.List(ctx, resource.NewMetadata(rd.TypedSpec().DefaultNamespace, rd.TypedSpec().Type, "", resource.VersionUndefined))
-		if err != nil {
-			return nil, err
+		timeout := cmp.Or(options.ResourceTimeout, bundle.DefaultResourceTimeout)
+
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("the resource list was not received in %s", timeout))
+		defer cancel()
+
+		events := make(chan state.Event)
+
+		if err := options.TalosClient.COSI.WatchKind(
+			ctx,
+			resource.NewMetadata(rd.TypedSpec().DefaultNamespace, rd.TypedSpec().Type, "", resource.VersionUndefined),
+			events,
+			state.WithBootstrapContents(true),
+		); err != nil {
+			return err
 		}
 
 		var (
-			buf      bytes.Buffer
-			hasItems bool
+			encoder = yaml.NewEncoder(w)
+			empty   = true
 		)
 
-		encoder := yaml.NewEncoder(&buf)
+		for {
+			var event state.Event
 
-		for _, r := range resources.Items {
-			data := struct {
-				Metadata *resource.Metadata `yaml:"metadata"`
-				Spec     interface{}        `yaml:"spec"`
-			}{
-				Metadata: r.Metadata(),
-				Spec:     "<REDACTED>",
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			case event = <-events:
 			}
 
-			if rd.TypedSpec().Sensitivity != meta.Sensitive {
-				data.Spec = r.Spec()
-			}
+			switch event.Type { //nolint:exhaustive
+			case state.Created:
+				data := struct {
+					Metadata *resource.Metadata `yaml:"metadata"`
+					Spec     interface{}        `yaml:"spec"`
+				}{
+					Metadata: event.Resource.Metadata(),
+					Spec:     "<REDACTED>",
+				}
 
-			if err = encoder.Encode(&data); err != nil {
-				return nil, err
-			}
+				if rd.TypedSpec().Sensitivity != meta.Sensitive {
+					data.Spec = event.Resource.Spec()
+				}
 
-			hasItems = true
-		}
+				if err := encoder.Encode(&data); err != nil {
+					return err
+				}
 
-		if !hasItems {
-			return nil, nil
-		}
+				empty = false
+			case state.Bootstrapped:
+				// the encoder fails to close the stream without documents, nothing is written for the empty list
+				if empty {
+					return nil
+				}
 
-		return buf.Bytes(), encoder.Close()
+				return encoder.Close()
+			case state.Errored:
+				return event.Error
+			}
+		}
 	}
 }
 
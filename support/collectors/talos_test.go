@@ -0,0 +1,109 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package collectors_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/cosi-project/runtime/pkg/resource/meta"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/cosi-project/runtime/pkg/state/impl/inmem"
+	"github.com/cosi-project/runtime/pkg/state/impl/namespaced"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+func TestTalosResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	st := state.WrapCore(namespaced.NewState(inmem.Build))
+
+	for i := range 100 {
+		require.NoError(st.Create(ctx, meta.NewNamespace(fmt.Sprintf("ns-%03d", i), meta.NamespaceSpec{})))
+	}
+
+	namespaces, err := meta.NewResourceDefinition(meta.ResourceDefinitionSpec{
+		Type:             meta.NamespaceType,
+		DefaultNamespace: meta.NamespaceName,
+	})
+	require.NoError(err)
+
+	empty, err := meta.NewResourceDefinition(meta.ResourceDefinitionSpec{
+		Type:             "Empties.test.talos.dev",
+		DefaultNamespace: meta.NamespaceName,
+	})
+	require.NoError(err)
+
+	archive := &testArchive{}
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(archive),
+		bundle.WithTalosClient(&client.Client{COSI: st}),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	cols := collectors.WithFolder([]*collectors.Collector{
+		collectors.NewTalosResourceCollector(namespaces),
+		collectors.NewTalosResourceCollector(empty),
+	}, "resources")
+
+	require.True(cols[0].TalosResource())
+	require.NoError(runCollectors(ctx, options, cols))
+
+	data := string(archive.files["resources/namespaces.meta.cosi.dev.yaml"])
+	require.Contains(data, "id: ns-000")
+	require.Contains(data, "id: ns-099")
+	require.Equal(100, strings.Count(data, "metadata:"))
+	require.NotContains(archive.files, "resources/empties.test.talos.dev.yaml")
+
+	require.Error(bundle.NewOptions(bundle.WithResourceWorkers(-1)).Validate())
+}
+
+// unbootstrappedState never reports that the initial list of the resources is sent.
+type unbootstrappedState struct {
+	state.CoreState
+}
+
+func (s *unbootstrappedState) WatchKind(context.Context, resource.Kind, chan<- state.Event, ...state.WatchKindOption) error {
+	return nil
+}
+
+func TestTalosResourceTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	namespaces, err := meta.NewResourceDefinition(meta.ResourceDefinitionSpec{
+		Type:             meta.NamespaceType,
+		DefaultNamespace: meta.NamespaceName,
+	})
+	require.NoError(err)
+
+	options := bundle.NewOptions(
+		bundle.WithArchive(&testArchive{}),
+		bundle.WithTalosClient(&client.Client{COSI: state.WrapCore(&unbootstrappedState{CoreState: namespaced.NewState(inmem.Build)})}),
+		bundle.WithResourceTimeout(100*time.Millisecond),
+		bundle.WithLogOutput(io.Discard),
+	)
+
+	err = collectors.NewTalosResourceCollector(namespaces).Run(ctx, options)
+	require.ErrorContains(err, "the resource list was not received in 100ms")
+	require.NoError(ctx.Err())
+
+	require.ErrorContains(bundle.NewOptions(bundle.WithResourceTimeout(-time.Second)).Validate(), "resource timeout must not be negative")
+}
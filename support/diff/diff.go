@@ -0,0 +1,406 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package diff compares two support bundles of the same cluster.
+package diff
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// ChangeType is the kind of the change.
+type ChangeType string
+
+// ChangeType values.
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// Result is the difference between the bundles.
+//
+// The nodes are matched by the node directory names, so both bundles should be collected using the same node addresses.
+type Result struct {
+	NodesAdded   []string         `yaml:"nodesAdded,omitempty" json:"nodesAdded,omitempty"`
+	NodesRemoved []string         `yaml:"nodesRemoved,omitempty" json:"nodesRemoved,omitempty"`
+	Versions     []VersionChange  `yaml:"versions,omitempty" json:"versions,omitempty"`
+	Services     []ServiceChange  `yaml:"services,omitempty" json:"services,omitempty"`
+	Resources    []ResourceChange `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// VersionChange is the change of the component version on the node.
+type VersionChange struct {
+	Node      string `yaml:"node" json:"node"`
+	Component string `yaml:"component" json:"component"`
+	Old       string `yaml:"old,omitempty" json:"old,omitempty"`
+	New       string `yaml:"new,omitempty" json:"new,omitempty"`
+}
+
+// ServiceChange is the change of the Talos service state.
+type ServiceChange struct {
+	Node     string `yaml:"node" json:"node"`
+	Service  string `yaml:"service" json:"service"`
+	OldState string `yaml:"oldState,omitempty" json:"oldState,omitempty"`
+	NewState string `yaml:"newState,omitempty" json:"newState,omitempty"`
+	// NewlyFailing is set if the service is failed or unhealthy in the new bundle only.
+	NewlyFailing bool `yaml:"newlyFailing" json:"newlyFailing"`
+}
+
+// ResourceChange is the change of the Talos resource on the node.
+//
+// The resource is changed if its spec is different, the metadata changes (e.g. the version) are ignored.
+type ResourceChange struct {
+	Node      string     `yaml:"node" json:"node"`
+	Type      string     `yaml:"type" json:"type"`
+	Namespace string     `yaml:"namespace" json:"namespace"`
+	ID        string     `yaml:"id" json:"id"`
+	Change    ChangeType `yaml:"change" json:"change"`
+}
+
+// Empty checks if the bundles are the same.
+func (r *Result) Empty() bool {
+	return len(r.NodesAdded) == 0 && len(r.NodesRemoved) == 0 && len(r.Versions) == 0 && len(r.Services) == 0 && len(r.Resources) == 0
+}
+
+// NewlyFailing returns the services which are failing in the new bundle only.
+func (r *Result) NewlyFailing() []ServiceChange {
+	var res []ServiceChange
+
+	for _, change := range r.Services {
+		if change.NewlyFailing {
+			res = append(res, change)
+		}
+	}
+
+	return res
+}
+
+// Compare compares the bundle collected before and after the change.
+func Compare(before, after *bundlereader.Bundle) (*Result, error) {
+	res := &Result{}
+
+	oldNodes := nodeNames(before)
+	newNodes := nodeNames(after)
+
+	for _, name := range newNodes {
+		if !slices.Contains(oldNodes, name) {
+			res.NodesAdded = append(res.NodesAdded, name)
+		}
+	}
+
+	for _, name := range oldNodes {
+		if !slices.Contains(newNodes, name) {
+			res.NodesRemoved = append(res.NodesRemoved, name)
+		}
+	}
+
+	for _, name := range newNodes {
+		oldNode, ok := before.Node(name)
+		if !ok {
+			continue
+		}
+
+		newNode, _ := after.Node(name)
+
+		if err := res.compareNodes(oldNode, newNode); err != nil {
+			return nil, fmt.Errorf("failed to compare node %s: %w", name, err)
+		}
+	}
+
+	if err := res.compareKubernetesNodes(before, after); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(res.Versions, func(a, b VersionChange) int {
+		return cmp.Or(strings.Compare(a.Node, b.Node), strings.Compare(a.Component, b.Component))
+	})
+
+	return res, nil
+}
+
+func nodeNames(b *bundlereader.Bundle) []string {
+	nodes := b.Nodes()
+	names := make([]string, 0, len(nodes))
+
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	return names
+}
+
+func (r *Result) compareNodes(before, after *bundlereader.Node) error {
+	if slices.Contains(before.Files(), "summary") && slices.Contains(after.Files(), "summary") {
+		oldVersion, err := before.TalosVersion()
+		if err != nil {
+			return err
+		}
+
+		newVersion, err := after.TalosVersion()
+		if err != nil {
+			return err
+		}
+
+		if oldVersion != newVersion {
+			r.Versions = append(r.Versions, VersionChange{Node: after.Name, Component: "talos", Old: oldVersion, New: newVersion})
+		}
+	}
+
+	if err := r.compareServices(before, after); err != nil {
+		return err
+	}
+
+	return r.compareResources(before, after)
+}
+
+func (r *Result) compareServices(before, after *bundlereader.Node) error {
+	oldServices := before.Services()
+
+	for _, id := range after.Services() {
+		newStatus, err := after.ServiceStatus(id)
+		if err != nil {
+			return err
+		}
+
+		oldStatus := &bundlereader.ServiceStatus{}
+
+		if slices.Contains(oldServices, id) {
+			if oldStatus, err = before.ServiceStatus(id); err != nil {
+				return err
+			}
+		}
+
+		if oldStatus.State == newStatus.State && oldStatus.Health == newStatus.Health {
+			continue
+		}
+
+		r.Services = append(r.Services, ServiceChange{
+			Node:         after.Name,
+			Service:      id,
+			OldState:     serviceState(oldStatus),
+			NewState:     serviceState(newStatus),
+			NewlyFailing: newStatus.Failing() && !oldStatus.Failing(),
+		})
+	}
+
+	return nil
+}
+
+func serviceState(status *bundlereader.ServiceStatus) string {
+	if status.State == "" || status.Health == "" || status.Health == "?" {
+		return status.State
+	}
+
+	return fmt.Sprintf("%s (health %s)", status.State, status.Health)
+}
+
+func (r *Result) compareResources(before, after *bundlereader.Node) error {
+	types := slices.Concat(before.ResourceTypes(), after.ResourceTypes())
+
+	slices.Sort(types)
+
+	for _, resourceType := range slices.Compact(types) {
+		oldResources, err := resourceSpecs(before, resourceType)
+		if err != nil {
+			return err
+		}
+
+		newResources, err := resourceSpecs(after, resourceType)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]resourceKey, 0, len(oldResources)+len(newResources))
+
+		for key := range oldResources {
+			keys = append(keys, key)
+		}
+
+		for key := range newResources {
+			if _, ok := oldResources[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		slices.SortFunc(keys, func(a, b resourceKey) int {
+			return cmp.Or(strings.Compare(a.namespace, b.namespace), strings.Compare(a.id, b.id))
+		})
+
+		for _, key := range keys {
+			oldSpec, inOld := oldResources[key]
+			newSpec, inNew := newResources[key]
+
+			var change ChangeType
+
+			switch {
+			case !inOld:
+				change = Added
+			case !inNew:
+				change = Removed
+			case !bytes.Equal(oldSpec, newSpec):
+				change = Changed
+			default:
+				continue
+			}
+
+			r.Resources = append(r.Resources, ResourceChange{
+				Node:      after.Name,
+				Type:      resourceType,
+				Namespace: key.namespace,
+				ID:        key.id,
+				Change:    change,
+			})
+		}
+	}
+
+	return nil
+}
+
+type resourceKey struct {
+	namespace string
+	id        string
+}
+
+// resourceSpecs returns the encoded resource specs of the type, the missing resource file is treated as empty.
+func resourceSpecs(node *bundlereader.Node, resourceType string) (map[resourceKey][]byte, error) {
+	if !slices.Contains(node.Files(), path.Join("resources", resourceType+".yaml")) {
+		return nil, nil
+	}
+
+	resources, err := node.Resources(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[resourceKey][]byte, len(resources))
+
+	for _, r := range resources {
+		spec, err := yaml.Marshal(r.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		res[resourceKey{namespace: r.Metadata.Namespace, id: r.Metadata.ID}] = spec
+	}
+
+	return res, nil
+}
+
+// compareKubernetesNodes compares the kubelet versions of the Kubernetes nodes.
+func (r *Result) compareKubernetesNodes(before, after *bundlereader.Bundle) error {
+	const nodesFile = bundlereader.KubernetesResourcesDir + "/nodes.yaml"
+
+	if !before.Has(nodesFile) || !after.Has(nodesFile) {
+		return nil
+	}
+
+	oldNodes, err := before.KubernetesNodes()
+	if err != nil {
+		return err
+	}
+
+	newNodes, err := after.KubernetesNodes()
+	if err != nil {
+		return err
+	}
+
+	oldVersions := map[string]string{}
+
+	for _, node := range oldNodes.Items {
+		oldVersions[node.Name] = node.Status.NodeInfo.KubeletVersion
+	}
+
+	for _, node := range newNodes.Items {
+		oldVersion, ok := oldVersions[node.Name]
+		if ok && oldVersion != node.Status.NodeInfo.KubeletVersion {
+			r.Versions = append(r.Versions, VersionChange{
+				Node:      node.Name,
+				Component: "kubelet",
+				Old:       oldVersion,
+				New:       node.Status.NodeInfo.KubeletVersion,
+			})
+		}
+	}
+
+	return nil
+}
+
+// Write renders the difference as the human readable text.
+func (r *Result) Write(w io.Writer) error {
+	if r.Empty() {
+		_, err := fmt.Fprintln(w, "no changes found")
+
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	section := func(title, header string) {
+		fmt.Fprintf(tw, "%s:\n%s\n", title, header) //nolint:errcheck
+	}
+
+	if len(r.NodesAdded) > 0 || len(r.NodesRemoved) > 0 {
+		section("Nodes", "NODE\tCHANGE")
+
+		for _, node := range r.NodesAdded {
+			fmt.Fprintf(tw, "%s\t%s\n", node, Added) //nolint:errcheck
+		}
+
+		for _, node := range r.NodesRemoved {
+			fmt.Fprintf(tw, "%s\t%s\n", node, Removed) //nolint:errcheck
+		}
+
+		fmt.Fprintln(tw) //nolint:errcheck
+	}
+
+	if len(r.Versions) > 0 {
+		section("Versions", "NODE\tCOMPONENT\tOLD\tNEW")
+
+		for _, change := range r.Versions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", change.Node, change.Component, change.Old, change.New) //nolint:errcheck
+		}
+
+		fmt.Fprintln(tw) //nolint:errcheck
+	}
+
+	if len(r.Services) > 0 {
+		section("Services", "NODE\tSERVICE\tOLD\tNEW\tNEWLY FAILING")
+
+		for _, change := range r.Services {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", change.Node, change.Service, or(change.OldState), or(change.NewState), change.NewlyFailing) //nolint:errcheck
+		}
+
+		fmt.Fprintln(tw) //nolint:errcheck
+	}
+
+	if len(r.Resources) > 0 {
+		section("Resources", "NODE\tTYPE\tNAMESPACE\tID\tCHANGE")
+
+		for _, change := range r.Resources {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", change.Node, change.Type, change.Namespace, change.ID, change.Change) //nolint:errcheck
+		}
+	}
+
+	return tw.Flush()
+}
+
+func or(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package diff_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/diff"
+)
+
+func TestDiff(t *testing.T) {
+	require := require.New(t)
+
+	summary := func(version string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("Client:\n\tTag:         v1.8.0\nServer:\n\tTag:         " + version + "\n")}
+	}
+
+	addresses := func(addresses ...string) *fstest.MapFile {
+		var buf bytes.Buffer
+
+		for _, address := range addresses {
+			fmt.Fprintf(&buf, "---\nmetadata:\n    namespace: network\n    id: %s\nspec:\n    address: %s\n", address, address)
+		}
+
+		return &fstest.MapFile{Data: buf.Bytes()}
+	}
+
+	before, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                    {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"10.5.0.2/summary":                 summary("v1.7.6"),
+		"10.5.0.2/service-logs/etcd.state": {Data: []byte("ID       etcd\nSTATE    Running\nHEALTH   OK\n")},
+		"10.5.0.2/resources/addressstatuses.net.talos.dev.yaml": addresses("10.5.0.2/24", "172.20.0.2/24"),
+		"10.5.0.3/summary": summary("v1.7.6"),
+	})
+	require.NoError(err)
+
+	after, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                    {Data: []byte("created: 2024-01-02T00:00:00Z\n")},
+		"10.5.0.2/summary":                 summary("v1.8.0"),
+		"10.5.0.2/service-logs/etcd.state": {Data: []byte("ID       etcd\nSTATE    Failed\nHEALTH   Fail\n")},
+		"10.5.0.2/resources/addressstatuses.net.talos.dev.yaml": addresses("10.5.0.2/24", "fd00::2/64"),
+		"10.5.0.4/summary": summary("v1.8.0"),
+	})
+	require.NoError(err)
+
+	res, err := diff.Compare(before, after)
+	require.NoError(err)
+
+	require.Equal([]string{"10.5.0.4"}, res.NodesAdded)
+	require.Equal([]string{"10.5.0.3"}, res.NodesRemoved)
+	require.Equal([]diff.VersionChange{{Node: "10.5.0.2", Component: "talos", Old: "v1.7.6", New: "v1.8.0"}}, res.Versions)
+	require.Len(res.NewlyFailing(), 1)
+	require.Equal("etcd", res.NewlyFailing()[0].Service)
+	require.Equal([]diff.ResourceChange{
+		{Node: "10.5.0.2", Type: "addressstatuses.net.talos.dev", Namespace: "network", ID: "172.20.0.2/24", Change: diff.Removed},
+		{Node: "10.5.0.2", Type: "addressstatuses.net.talos.dev", Namespace: "network", ID: "fd00::2/64", Change: diff.Added},
+	}, res.Resources)
+
+	var buf bytes.Buffer
+
+	require.NoError(res.Write(&buf))
+	assert.Contains(t, buf.String(), "Failed (health Fail)")
+}
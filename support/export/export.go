@@ -0,0 +1,105 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package export converts the support bundles into the layouts expected by the third party tools.
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// TarArchive writes the files to the gzipped tar archive.
+//
+// Most of the log ingestion tools accept the gzipped tarballs, e.g. the sosreport archives.
+type TarArchive struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	modTime time.Time
+	mu      sync.Mutex
+}
+
+// NewTarArchive creates the gzipped tar archive writing to w, the files get the modification time.
+func NewTarArchive(w io.Writer, modTime time.Time) *TarArchive {
+	gz := gzip.NewWriter(w)
+
+	return &TarArchive{
+		gz:      gz,
+		tw:      tar.NewWriter(gz),
+		modTime: modTime,
+	}
+}
+
+// Write implements bundle.Archive.
+func (a *TarArchive) Write(path string, contents []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.tw.WriteHeader(a.header(path, int64(len(contents)))); err != nil {
+		return err
+	}
+
+	_, err := a.tw.Write(contents)
+
+	return err
+}
+
+// WriteFrom implements bundle.StreamArchive.
+//
+// The tar header needs the file size upfront, so the contents are read into memory.
+func (a *TarArchive) WriteFrom(path string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return a.Write(path, data)
+}
+
+// Close implements bundle.Archive.
+func (a *TarArchive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return errors.Join(a.tw.Close(), a.gz.Close())
+}
+
+func (a *TarArchive) header(path string, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  a.modTime,
+		Format:   tar.FormatPAX,
+	}
+}
+
+// copyFile copies the bundle file to the archive streaming it if the archive supports streaming.
+func copyFile(b *bundlereader.Bundle, archive bundle.Archive, from, to string) error {
+	if streamArchive, ok := archive.(bundle.StreamArchive); ok {
+		r, err := b.Open(from)
+		if err != nil {
+			return err
+		}
+
+		defer r.Close() //nolint:errcheck
+
+		return streamArchive.WriteFrom(to, r)
+	}
+
+	data, err := b.ReadFile(from)
+	if err != nil {
+		return err
+	}
+
+	return archive.Write(to, data)
+}
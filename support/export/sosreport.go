@@ -0,0 +1,207 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// SOSReportCluster is the name used in place of the host name for the report with the cluster wide files.
+const SOSReportCluster = "cluster"
+
+// SOSReportOptions configures the sosreport export.
+type SOSReportOptions struct {
+	// CaseID is the support case ID included in the report names, the same way `sos report --case-id` does.
+	CaseID string
+	// Nodes limits the export to the nodes, the cluster wide report is skipped if set.
+	Nodes []string
+}
+
+// SOSReport converts the bundle into the sosreport-like layout: each node is written to its own
+// `sosreport-<node>-<date>` directory with the logs under `var/log` and the command outputs under `sos_commands`,
+// the cluster wide files are written to the `sosreport-cluster-<date>` directory.
+//
+// Each report has the `sos_reports/manifest.json` file which maps the report files to the bundle files.
+// The archive is not closed.
+func SOSReport(b *bundlereader.Bundle, archive bundle.Archive, opts SOSReportOptions) error {
+	created := time.Now()
+
+	if md := b.Metadata(); md != nil && !md.Created.IsZero() {
+		created = md.Created
+	}
+
+	for _, node := range b.Nodes() {
+		if len(opts.Nodes) > 0 && !slices.Contains(opts.Nodes, node.Name) {
+			continue
+		}
+
+		files := make(map[string]string, len(node.Files()))
+
+		for _, file := range node.Files() {
+			files[node.Path(file)] = sosNodePath(file)
+		}
+
+		if err := writeSOSReport(b, archive, sosReportName(node.Name, opts.CaseID, created), node.Name, created, files); err != nil {
+			return fmt.Errorf("failed to export node %s: %w", node.Name, err)
+		}
+	}
+
+	if len(opts.Nodes) > 0 {
+		return nil
+	}
+
+	files := map[string]string{}
+
+	for _, file := range b.Files() {
+		dir, rest, ok := strings.Cut(file, "/")
+
+		switch {
+		case ok && dir == bundlereader.KubernetesResourcesDir:
+			files[file] = path.Join("sos_commands/kubernetes", rest)
+		case ok:
+			if _, isNode := b.Node(dir); isNode {
+				continue
+			}
+
+			fallthrough
+		default:
+			files[file] = path.Join("sos_commands/talos_support", file)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	if err := writeSOSReport(b, archive, sosReportName(SOSReportCluster, opts.CaseID, created), SOSReportCluster, created, files); err != nil {
+		return fmt.Errorf("failed to export cluster resources: %w", err)
+	}
+
+	return nil
+}
+
+// sosManifest is the sosreport manifest, only the fields used by the ingestion tools are filled.
+type sosManifest struct {
+	Created time.Time `json:"start_time"`
+	// Files maps the report files to the support bundle files they were converted from.
+	Files   map[string]string `json:"files"`
+	Version string            `json:"version"`
+	Node    string            `json:"node"`
+	Policy  string            `json:"policy"`
+}
+
+func writeSOSReport(b *bundlereader.Bundle, archive bundle.Archive, root, host string, created time.Time, files map[string]string) error {
+	manifest := sosManifest{
+		Version: "talos-support-1",
+		Policy:  "Talos Linux",
+		Node:    host,
+		Created: created.UTC(),
+		Files:   make(map[string]string, len(files)),
+	}
+
+	sources := make([]string, 0, len(files))
+
+	for source := range files {
+		sources = append(sources, source)
+	}
+
+	slices.Sort(sources)
+
+	for _, source := range sources {
+		target := files[source]
+
+		if err := copyFile(b, archive, source, path.Join(root, target)); err != nil {
+			return err
+		}
+
+		manifest.Files[target] = source
+	}
+
+	if err := archive.Write(path.Join(root, "hostname"), []byte(host+"\n")); err != nil {
+		return err
+	}
+
+	if err := archive.Write(path.Join(root, "date"), []byte(created.UTC().Format(time.UnixDate)+"\n")); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return archive.Write(path.Join(root, "sos_reports", "manifest.json"), append(data, '\n'))
+}
+
+func sosReportName(host, caseID string, created time.Time) string {
+	parts := []string{"sosreport", sanitizeName(host)}
+
+	if caseID != "" {
+		parts = append(parts, sanitizeName(caseID))
+	}
+
+	return strings.Join(append(parts, created.UTC().Format("2006-01-02-150405")), "-")
+}
+
+// sanitizeName replaces the characters which are not allowed in the sosreport names.
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// sosNodeFiles maps the node files to the sosreport paths of the similar data collected by sos plugins.
+var sosNodeFiles = map[string]string{
+	"summary":                "sos_commands/talos/talosctl_version",
+	"dmesg.log":              "sos_commands/kernel/dmesg",
+	"controller-runtime.log": "var/log/talos/controller-runtime.log",
+	"dns-resolve-cache.log":  "var/log/talos/dns-resolve-cache.log",
+	"machine-config.yaml":    "etc/talos/config.yaml",
+	"mounts":                 "sos_commands/filesys/df_-al",
+	"devices":                "proc/bus/pci/devices",
+	"io":                     "sos_commands/block/diskstats",
+	"processes":              "sos_commands/process/ps_auxwww",
+	"etcd-status":            "sos_commands/etcd/etcdctl_endpoint_status",
+	"dependencies.dot":       "sos_commands/talos/controller_dependencies.dot",
+}
+
+// sosNodePath returns the sosreport path of the node file.
+func sosNodePath(file string) string {
+	if p, ok := sosNodeFiles[file]; ok {
+		return p
+	}
+
+	dir, rest, _ := strings.Cut(file, "/")
+
+	switch dir {
+	case "service-logs":
+		if id, ok := strings.CutSuffix(rest, ".state"); ok {
+			return path.Join("sos_commands/talos/services", "talosctl_service_"+id)
+		}
+
+		return path.Join("var/log/talos", rest)
+	case "kubernetes-logs":
+		return path.Join("var/log/containers", rest)
+	case "resources":
+		return path.Join("sos_commands/talos/resources", rest)
+	case "pcap":
+		return path.Join("sos_commands/networking", rest)
+	default:
+		return path.Join("sos_commands/talos", file)
+	}
+}
@@ -0,0 +1,108 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package export_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/export"
+)
+
+type testArchive struct {
+	files   map[string][]byte
+	filesMu sync.Mutex
+}
+
+func (a *testArchive) Write(path string, data []byte) error {
+	a.filesMu.Lock()
+	defer a.filesMu.Unlock()
+
+	if a.files == nil {
+		a.files = map[string][]byte{}
+	}
+
+	a.files[path] = data
+
+	return nil
+}
+
+func (a *testArchive) Close() error {
+	return nil
+}
+
+func TestSOSReport(t *testing.T) {
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                                           {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"kubernetesResources/nodes.yaml":                          {Data: []byte("items: []\n")},
+		"10.5.0.2/dmesg.log":                                      {Data: []byte("kern: info: [2024-01-01T00:00:00Z]: booting\n")},
+		"10.5.0.2/service-logs/etcd.log":                          {Data: []byte("etcd log\n")},
+		"10.5.0.2/service-logs/etcd.state":                        {Data: []byte("STATE Running\n")},
+		"10.5.0.2/kubernetes-logs/kube-system/kube-apiserver.log": {Data: []byte("apiserver log\n")},
+	})
+	require.NoError(err)
+
+	archive := &testArchive{}
+
+	require.NoError(export.SOSReport(b, archive, export.SOSReportOptions{CaseID: "1234"}))
+
+	node := "sosreport-10.5.0.2-1234-2024-01-01-000000/"
+	cluster := "sosreport-cluster-1234-2024-01-01-000000/"
+
+	require.Equal("kern: info: [2024-01-01T00:00:00Z]: booting\n", string(archive.files[node+"sos_commands/kernel/dmesg"]))
+	require.Equal("etcd log\n", string(archive.files[node+"var/log/talos/etcd.log"]))
+	require.Equal("STATE Running\n", string(archive.files[node+"sos_commands/talos/services/talosctl_service_etcd"]))
+	require.Equal("apiserver log\n", string(archive.files[node+"var/log/containers/kube-system/kube-apiserver.log"]))
+	require.Equal("10.5.0.2\n", string(archive.files[node+"hostname"]))
+	require.Equal("items: []\n", string(archive.files[cluster+"sos_commands/kubernetes/nodes.yaml"]))
+	require.Contains(archive.files, cluster+"sos_commands/talos_support/metadata.yaml")
+
+	var manifest struct {
+		Files map[string]string `json:"files"`
+	}
+
+	require.NoError(json.Unmarshal(archive.files[node+"sos_reports/manifest.json"], &manifest))
+	require.Equal("10.5.0.2/dmesg.log", manifest.Files["sos_commands/kernel/dmesg"])
+
+	var buf bytes.Buffer
+
+	tarArchive := export.NewTarArchive(&buf, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(export.SOSReport(b, tarArchive, export.SOSReportOptions{Nodes: []string{"10.5.0.2"}}))
+	require.NoError(tarArchive.Close())
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(err)
+
+	var names []string
+
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, nextErr := tr.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+
+		require.NoError(nextErr)
+
+		names = append(names, hdr.Name)
+	}
+
+	require.Contains(names, "sosreport-10.5.0.2-2024-01-01-000000/var/log/talos/etcd.log")
+	require.NotContains(names, "sosreport-cluster-2024-01-01-000000/hostname")
+}
@@ -0,0 +1,225 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// ClusterErrorFile is the name of the file with the error of the cluster which failed to be collected.
+//
+// The file is put under the cluster directory, the other clusters are still collected.
+const ClusterErrorFile = "error.txt"
+
+// ClusterOptions configures the collection from one cluster of the multi-cluster bundle.
+type ClusterOptions struct {
+	// Options are the bundle options of the cluster: the Talos and Kubernetes clients, the nodes, the collection settings.
+	//
+	// The archive and the path prefix are set by CreateMultiClusterBundle.
+	Options *bundle.Options
+	// Name is the name of the cluster, the cluster files are put under the directory with the name.
+	Name string
+	// Collectors are the collectors of the cluster, collectors.GetForOptions is used if nil.
+	Collectors []*collectors.Collector
+}
+
+// CreateMultiClusterBundle generates the support bundle of several clusters in one archive.
+//
+// The files of each cluster are put under the directory named after the cluster, so each of them can be read
+// with bundlereader using the cluster name as the prefix. The collectors of all clusters share the workers:
+// the pool size is the largest number of workers set in the cluster options. The progress of the clusters
+// is reported to the cluster options with the sources prefixed by the cluster name, the progress callbacks
+// are not called concurrently.
+//
+// If the collectors of a cluster can't be created or prepared, the error is written to ClusterErrorFile of the cluster
+// and the bundle fails only if no cluster is collected.
+//
+// The upload and the SFTP output are not supported per cluster. The archive is closed when the bundle is done.
+func CreateMultiClusterBundle(ctx context.Context, clusters []ClusterOptions, archive bundle.Archive) error {
+	if err := validateClusters(clusters, archive); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+
+	for _, cluster := range clusters {
+		defer cluster.Options.Close() //nolint:errcheck
+	}
+
+	started := make([]time.Time, len(clusters))
+
+	for i, cluster := range clusters {
+		started[i] = cluster.Options.Now()
+	}
+
+	failures, err := createMultiClusterBundle(ctx, clusters, &sharedArchive{Archive: archive})
+
+	for i, cluster := range clusters {
+		clusterErr := err
+		if clusterErr == nil {
+			clusterErr = failures[i]
+		}
+
+		cluster.Options.NotifyCompletion(ctx, started[i], clusterErr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// createMultiClusterBundle returns the failures of the clusters which were not collected in the order of the clusters.
+func createMultiClusterBundle(ctx context.Context, clusters []ClusterOptions, archive *sharedArchive) ([]error, error) {
+	var progressMu, errorHandlerMu sync.Mutex
+
+	runs := make([]*bundleRun, 0, len(clusters))
+	pools := workerPools{workers: 1}
+	failures := make([]error, len(clusters))
+
+	for i, cluster := range clusters {
+		options := cluster.Options
+
+		options.Archive = archive
+		options.PathPrefix = path.Join(cluster.Name, options.PathPrefix)
+
+		run, err := prepareCluster(ctx, cluster)
+		if err != nil {
+			failures[i] = fmt.Errorf("cluster %s: %w", cluster.Name, err)
+
+			options.Log("failed to collect cluster %s: %s", cluster.Name, err)
+
+			if err = archive.Write(options.ArchivePath(ClusterErrorFile), []byte(err.Error()+"\n")); err != nil {
+				return nil, fmt.Errorf("cluster %s: %w", cluster.Name, err)
+			}
+
+			continue
+		}
+
+		run.cluster = cluster.Name
+		run.progressMu = &progressMu
+		run.errorHandlerMu = &errorHandlerMu
+
+		runs = append(runs, run)
+
+		pools = pools.merge(poolsFor(options))
+	}
+
+	if len(runs) == 0 {
+		return failures, errors.Join(failures...)
+	}
+
+	start := time.Now()
+
+	err := runCollectors(ctx, pools, runs...)
+
+	for _, run := range runs {
+		run.options.RecordPhase(bundle.PhaseCollect, time.Since(start))
+	}
+
+	if err != nil {
+		return failures, err
+	}
+
+	for _, run := range runs {
+		if err := run.finish(ctx); err != nil {
+			return failures, fmt.Errorf("cluster %s: %w", run.cluster, err)
+		}
+	}
+
+	return failures, nil
+}
+
+// prepareCluster creates the collectors of the cluster if they're not set and prepares the cluster bundle.
+func prepareCluster(ctx context.Context, cluster ClusterOptions) (*bundleRun, error) {
+	options := cluster.Options
+
+	cols := cluster.Collectors
+	if cols == nil {
+		var err error
+
+		cols, err = collectors.GetForOptions(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+
+	run, err := prepareBundle(ctx, options, cols)
+
+	options.RecordPhase(bundle.PhasePrepare, time.Since(start))
+
+	return run, err
+}
+
+// validateClusters checks the cluster options which are not checked by bundle.Options.Validate.
+func validateClusters(clusters []ClusterOptions, archive bundle.Archive) error {
+	var errs []error
+
+	if archive == nil {
+		errs = append(errs, errors.New("archive is not set"))
+	}
+
+	if len(clusters) == 0 {
+		errs = append(errs, errors.New("no clusters are set"))
+	}
+
+	names := map[string]struct{}{}
+
+	for i, cluster := range clusters {
+		switch {
+		case cluster.Name == "":
+			errs = append(errs, fmt.Errorf("cluster %d has no name", i))
+		case strings.Contains(cluster.Name, "/"), strings.Contains(cluster.Name, ".."), cluster.Name == ".":
+			errs = append(errs, fmt.Errorf("cluster name %q is not a valid directory name", cluster.Name))
+		case cluster.Options == nil:
+			errs = append(errs, fmt.Errorf("cluster %s has no options", cluster.Name))
+		case cluster.Options.Archive != nil, cluster.Options.Upload != nil, cluster.Options.SFTPOutput != nil:
+			errs = append(errs, fmt.Errorf("cluster %s sets its own output", cluster.Name))
+		}
+
+		if _, ok := names[cluster.Name]; ok {
+			errs = append(errs, fmt.Errorf("duplicate cluster name %q", cluster.Name))
+		}
+
+		names[cluster.Name] = struct{}{}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sharedArchive is the archive shared by the clusters, it's closed once all clusters are done.
+type sharedArchive struct {
+	bundle.Archive
+}
+
+// WriteFrom implements bundle.StreamArchive.
+func (a *sharedArchive) WriteFrom(name string, r io.Reader) error {
+	if archive, ok := a.Archive.(bundle.StreamArchive); ok {
+		return archive.WriteFrom(name, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return a.Archive.Write(name, data)
+}
+
+// Close implements bundle.Archive.
+func (a *sharedArchive) Close() error {
+	return nil
+}
@@ -0,0 +1,263 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AnonymizerConfig configures the values replaced by the anonymizer.
+//
+// The IP and MAC addresses are always replaced, the hostnames can't be detected reliably,
+// so they are replaced only if they are listed or belong to one of the domains.
+type AnonymizerConfig struct {
+	Hostnames []string
+	Domains   []string
+}
+
+// Anonymizer replaces the IP addresses, MAC addresses and hostnames with the stable pseudonyms.
+//
+// The same value gets the same pseudonym in all files of the bundle including the file paths, so the relations
+// between the nodes can still be analyzed. The IPv4 addresses keep the last octet, the addresses from the same /24
+// network stay in the same pseudonym network.
+//
+// The mapping from the real values to the pseudonyms can be saved using WriteMapping, it should be kept by the bundle owner.
+type Anonymizer struct {
+	hostnameRe *regexp.Regexp
+	hostnames  map[string]struct{}
+	domains    []string
+	mapping    map[string]string
+	networks   map[string]string
+	counters   map[string]int
+	mu         sync.Mutex
+}
+
+var (
+	macRe  = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?::[0-9a-f]{2}){5}\b`)
+	ipv6Re = regexp.MustCompile(`(?i)(?:[0-9a-f]{1,4}:|:){2,7}(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9a-f]{1,4}|:)`)
+	ipv4Re = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	hostRe = regexp.MustCompile(`[a-zA-Z0-9][a-zA-Z0-9.-]*`)
+)
+
+// NewAnonymizer creates new anonymizer.
+func NewAnonymizer(config AnonymizerConfig) *Anonymizer {
+	a := &Anonymizer{
+		hostnames: map[string]struct{}{},
+		mapping:   map[string]string{},
+		networks:  map[string]string{},
+		counters:  map[string]int{},
+	}
+
+	names := make([]string, 0, len(config.Hostnames)+len(config.Domains))
+
+	for _, hostname := range config.Hostnames {
+		hostname = strings.ToLower(hostname)
+
+		a.hostnames[hostname] = struct{}{}
+		names = append(names, regexp.QuoteMeta(hostname))
+	}
+
+	for _, domain := range config.Domains {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+
+		a.domains = append(a.domains, domain)
+		names = append(names, regexp.QuoteMeta(domain))
+	}
+
+	if len(names) > 0 {
+		a.hostnameRe = regexp.MustCompile(`(?i)` + strings.Join(names, "|"))
+	}
+
+	return a
+}
+
+// Name implements bundle.Redactor.
+func (a *Anonymizer) Name() string {
+	return "anonymizer"
+}
+
+// Redact implements bundle.Redactor.
+func (a *Anonymizer) Redact(_ string, data []byte) ([]byte, int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var count, n int
+
+	data, n = replaceGroup(macRe, data, 0, a.mac)
+	count += n
+
+	data, n = replaceGroup(ipv6Re, data, 0, a.ip)
+	count += n
+
+	data, n = replaceGroup(ipv4Re, data, 0, a.ip)
+	count += n
+
+	// the hostnames are looked up in all words which look like a hostname, so check the data first
+	if a.hostnameRe != nil && a.hostnameRe.Match(data) {
+		data, n = replaceGroup(hostRe, data, 0, a.hostname)
+		count += n
+	}
+
+	return data, count, nil
+}
+
+// RedactPath implements bundle.PathRedactor.
+func (a *Anonymizer) RedactPath(path string) string {
+	data, _, _ := a.Redact(path, []byte(path)) //nolint:errcheck
+
+	return string(data)
+}
+
+// Mapping returns the copy of the mapping from the real values to the pseudonyms.
+func (a *Anonymizer) Mapping() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := make(map[string]string, len(a.mapping))
+
+	for k, v := range a.mapping {
+		res[k] = v
+	}
+
+	return res
+}
+
+// next returns the next value of the counter starting from 1.
+func (a *Anonymizer) next(kind string) int {
+	a.counters[kind]++
+
+	return a.counters[kind]
+}
+
+// pseudonym returns the pseudonym for the value creating it if it's not known yet.
+func (a *Anonymizer) pseudonym(value string, create func() string) string {
+	if res, ok := a.mapping[value]; ok {
+		return res
+	}
+
+	res := create()
+	a.mapping[value] = res
+
+	return res
+}
+
+func (a *Anonymizer) mac(value string) string {
+	value = strings.ToLower(value)
+
+	if value == "00:00:00:00:00:00" || value == "ff:ff:ff:ff:ff:ff" {
+		return value
+	}
+
+	return a.pseudonym(value, func() string {
+		n := a.next("mac")
+
+		return fmt.Sprintf("02:00:00:%02x:%02x:%02x", byte(n>>16), byte(n>>8), byte(n))
+	})
+}
+
+func (a *Anonymizer) ip(value string) string {
+	addr, err := netip.ParseAddr(value)
+	if err != nil || addr.Zone() != "" {
+		return value
+	}
+
+	if addr.IsLoopback() || addr.IsUnspecified() || addr.IsMulticast() {
+		return value
+	}
+
+	// the embedded IPv4 address is replaced separately
+	if addr.Is4In6() {
+		return value
+	}
+
+	if addr.Is6() {
+		return a.pseudonym(addr.String(), func() string {
+			n := a.next("ipv6")
+
+			return netip.AddrFrom16([16]byte{0xfd, 12: byte(n >> 24), 13: byte(n >> 16), 14: byte(n >> 8), 15: byte(n)}).String()
+		})
+	}
+
+	octets := addr.As4()
+
+	// netmasks and broadcast addresses
+	if octets[0] == 255 {
+		return value
+	}
+
+	return a.pseudonym(addr.String(), func() string {
+		network := netip.AddrFrom4([4]byte{octets[0], octets[1], octets[2]}).String()
+
+		prefix, ok := a.networks[network]
+		if !ok {
+			n := a.next("ipv4")
+			prefix = fmt.Sprintf("10.%d.%d.", byte(n>>8), byte(n))
+			a.networks[network] = prefix
+		}
+
+		return fmt.Sprintf("%s%d", prefix, octets[3])
+	})
+}
+
+func (a *Anonymizer) hostname(value string) string {
+	name := strings.ToLower(strings.TrimRight(value, ".-"))
+
+	if _, ok := a.hostnames[name]; !ok && !slices.ContainsFunc(a.domains, func(domain string) bool {
+		return name == domain || strings.HasSuffix(name, "."+domain)
+	}) {
+		return value
+	}
+
+	return a.pseudonym(name, func() string {
+		for _, domain := range a.domains {
+			if name == domain {
+				return fmt.Sprintf("domain-%d", a.next("domain"))
+			}
+
+			if strings.HasSuffix(name, "."+domain) {
+				return fmt.Sprintf("host-%d.%s", a.next("host"), a.hostname(domain))
+			}
+		}
+
+		return fmt.Sprintf("host-%d", a.next("host"))
+	}) + value[len(name):]
+}
+
+// replaceGroup replaces the submatch of all regular expression matches using the function.
+//
+// It returns the number of the changed matches.
+func replaceGroup(re *regexp.Regexp, data []byte, group int, f func(string) string) ([]byte, int) {
+	matches := re.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return data, 0
+	}
+
+	res := make([]byte, 0, len(data))
+	last, count := 0, 0
+
+	for _, match := range matches {
+		start, end := match[group*2], match[group*2+1]
+
+		value := string(data[start:end])
+		replacement := f(value)
+
+		if replacement != value {
+			count++
+		}
+
+		res = append(res, data[last:start]...)
+		res = append(res, replacement...)
+		last = end
+	}
+
+	res = append(res, data[last:]...)
+
+	return res, count
+}
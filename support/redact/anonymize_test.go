@@ -0,0 +1,47 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/redact"
+)
+
+func TestAnonymizer(t *testing.T) {
+	require := require.New(t)
+
+	anonymizer := redact.NewAnonymizer(redact.AnonymizerConfig{
+		Hostnames: []string{"talos-cp-1"},
+		Domains:   []string{"corp.example.com"},
+	})
+
+	require.Equal("10.0.1.2/addresses", anonymizer.RedactPath("172.20.0.2/addresses"))
+
+	redacted, count, err := anonymizer.Redact("10.0.1.2/addresses",
+		[]byte("talos-cp-1 172.20.0.2/24 172.20.0.3 2001:db8::1 aa:bb:cc:dd:ee:ff api.corp.example.com 127.0.0.1\n"),
+	)
+	require.NoError(err)
+	require.Equal(6, count)
+	require.Equal("host-1 10.0.1.2/24 10.0.1.3 fd00::1 02:00:00:00:00:01 host-2.domain-1 127.0.0.1\n", string(redacted))
+
+	var buf bytes.Buffer
+
+	require.NoError(anonymizer.WriteMapping(&buf, []byte("secret")))
+	require.NotContains(buf.String(), "172.20.0.2")
+
+	_, err = redact.ReadMapping(bytes.NewReader(buf.Bytes()), []byte("wrong"))
+	require.Error(err)
+
+	mapping, err := redact.ReadMapping(&buf, []byte("secret"))
+	require.NoError(err)
+
+	require.Equal(anonymizer.Mapping(), mapping)
+	require.Equal("10.0.1.2", mapping["172.20.0.2"])
+	require.Equal("host-1", mapping["talos-cp-1"])
+}
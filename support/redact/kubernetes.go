@@ -0,0 +1,115 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// kubeconfigSecrets are the paths of the credentials in the kubeconfig.
+var kubeconfigSecrets = [][]string{
+	{"users", "*", "user", "token"},
+	{"users", "*", "user", "password"},
+	{"users", "*", "user", "client-key-data"},
+	{"users", "*", "user", "auth-provider", "config", "id-token"},
+	{"users", "*", "user", "auth-provider", "config", "refresh-token"},
+	{"users", "*", "user", "auth-provider", "config", "access-token"},
+	{"users", "*", "user", "auth-provider", "config", "client-secret"},
+}
+
+// lastAppliedAnnotation contains the whole object including the Secret data.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+var jwtRe = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
+
+// Kubernetes creates a redactor which removes the Secret data, the service account tokens and the kubeconfig credentials
+// from the Kubernetes resources in the YAML files.
+//
+// The values are replaced with the placeholder containing the value length and hash, so the values can still be compared.
+func Kubernetes() bundle.Redactor {
+	return NewFunc("kubernetes", redactKubernetes)
+}
+
+func redactKubernetes(_ string, data []byte) ([]byte, int, error) {
+	if !bytes.Contains(data, []byte("Secret")) && !bytes.Contains(data, []byte("Config")) && !bytes.Contains(data, []byte("eyJ")) {
+		return data, 0, nil
+	}
+
+	return redactYAML(data, redactKubernetesNode)
+}
+
+func redactKubernetesNode(node *yaml.Node) int {
+	count := 0
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.ScalarNode:
+		switch {
+		case jwtRe.MatchString(node.Value):
+			count += redactHashed(node)
+		case strings.Contains(node.Value, "kind: Config"):
+			// kubeconfig embedded in the ConfigMap data, annotations, etc.
+			redacted, n, err := redactYAML([]byte(node.Value), redactKubernetesNode)
+			if err == nil && n > 0 {
+				node.Value = string(redacted)
+				count += n
+			}
+		}
+	case yaml.MappingNode:
+		switch mappingValue(node, "kind") {
+		case "Secret":
+			for _, field := range []string{"data", "stringData"} {
+				count += redactPath(node, []string{field, "*"}, redactHashed)
+			}
+
+			count += redactPath(node, []string{"metadata", "annotations", lastAppliedAnnotation}, redactHashed)
+		case "Config":
+			for _, path := range kubeconfigSecrets {
+				count += redactPath(node, path, redactHashed)
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		count += redactKubernetesNode(child)
+	}
+
+	return count
+}
+
+// mappingValue returns the scalar value of the key in the mapping node.
+func mappingValue(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+
+	return ""
+}
+
+// redactHashed replaces the scalar value with the placeholder containing the value length and the hash.
+func redactHashed(node *yaml.Node) int {
+	if node.Kind != yaml.ScalarNode || node.Value == "" || node.Tag == "!!null" || strings.HasPrefix(node.Value, "<REDACTED") {
+		return 0
+	}
+
+	hash := sha256.Sum256([]byte(node.Value))
+
+	*node = yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!str",
+		Value: fmt.Sprintf("<REDACTED length=%d sha256=%x>", len(node.Value), hash[:6]),
+	}
+
+	return 1
+}
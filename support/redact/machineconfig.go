@@ -0,0 +1,270 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// machineConfigSecrets are the paths of the secret fields in the Talos v1alpha1 machine config document.
+//
+// The `*` matches any map key or list item.
+var machineConfigSecrets = [][]string{
+	{"machine", "token"},
+	{"machine", "env", "*"},
+	{"machine", "files", "*", "content"},
+	{"machine", "ca", "crt"},
+	{"machine", "ca", "key"},
+	{"machine", "acceptedCAs", "*", "crt"},
+	{"machine", "network", "interfaces", "*", "wireguard", "privateKey"},
+	{"machine", "network", "interfaces", "*", "vlans", "*", "wireguard", "privateKey"},
+	{"machine", "registries", "config", "*", "auth", "password"},
+	{"machine", "registries", "config", "*", "auth", "auth"},
+	{"machine", "registries", "config", "*", "auth", "identityToken"},
+	{"machine", "registries", "config", "*", "tls", "clientIdentity", "key"},
+	{"machine", "systemDiskEncryption", "*", "keys", "*", "static", "passphrase"},
+	{"cluster", "secret"},
+	{"cluster", "token"},
+	{"cluster", "aescbcEncryptionSecret"},
+	{"cluster", "secretboxEncryptionSecret"},
+	{"cluster", "ca", "crt"},
+	{"cluster", "ca", "key"},
+	{"cluster", "acceptedCAs", "*", "crt"},
+	{"cluster", "aggregatorCA", "crt"},
+	{"cluster", "aggregatorCA", "key"},
+	{"cluster", "serviceAccount", "key"},
+	{"cluster", "etcd", "ca", "crt"},
+	{"cluster", "etcd", "ca", "key"},
+	{"cluster", "inlineManifests", "*", "contents"},
+	{"cluster", "extraManifestHeaders", "*"},
+}
+
+// safeConfigDocuments are the kinds of the Talos config documents other than v1alpha1 which don't contain secrets.
+//
+// The values of all other documents are redacted as they might contain the secrets, e.g. the SideroLink join token,
+// the environment and the files of the extension services.
+var safeConfigDocuments = []string{
+	"NetworkDefaultActionConfig",
+	"NetworkRuleConfig",
+	"VolumeConfig",
+	"WatchdogTimerConfig",
+}
+
+// configDocumentKeys are the fields of the config documents kept when the document values are redacted.
+var configDocumentKeys = []string{"apiVersion", "kind", "name"}
+
+// MachineConfig creates a redactor which removes the secrets, certificates and tokens from the Talos machine config.
+//
+// The machine config documents are looked up in all YAML files including the nested ones, e.g. in the resource specs.
+// The secret fields of the v1alpha1 document are redacted, all other fields are kept as is. All values of the other
+// config documents are redacted unless the document kind is known not to contain secrets.
+// The files which are not YAML are left untouched.
+func MachineConfig() bundle.Redactor {
+	return NewFunc("machine-config", redactMachineConfig)
+}
+
+func redactMachineConfig(_ string, data []byte) ([]byte, int, error) {
+	if !bytes.Contains(data, []byte("v1alpha1")) {
+		return data, 0, nil
+	}
+
+	return redactYAML(data, redactMachineConfigs)
+}
+
+// redactYAML runs the redaction function on all YAML documents and encodes them back if anything was redacted.
+//
+// The data which is not YAML is returned as is.
+func redactYAML(data []byte, redact func(doc *yaml.Node) int) ([]byte, int, error) {
+	var (
+		docs  []*yaml.Node
+		count int
+	)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var doc yaml.Node
+
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return data, 0, nil
+		}
+
+		count += redact(&doc)
+
+		docs = append(docs, &doc)
+	}
+
+	if count == 0 {
+		return data, 0, nil
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	return buf.Bytes(), count, nil
+}
+
+// redactMachineConfigs finds the machine config documents in the node tree and redacts them.
+func redactMachineConfigs(node *yaml.Node) int {
+	if isMachineConfig(node) {
+		count := 0
+
+		for _, path := range machineConfigSecrets {
+			count += redactPath(node, path, redactValue)
+		}
+
+		return count
+	}
+
+	if kind, ok := configDocumentKind(node); ok {
+		if slices.Contains(safeConfigDocuments, kind) {
+			return 0
+		}
+
+		count := 0
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !slices.Contains(configDocumentKeys, node.Content[i].Value) {
+				count += redactValues(node.Content[i+1])
+			}
+		}
+
+		return count
+	}
+
+	count := 0
+
+	for _, child := range node.Content {
+		count += redactMachineConfigs(child)
+	}
+
+	return count
+}
+
+func isMachineConfig(node *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	var version, config bool
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "version":
+			version = node.Content[i+1].Value == "v1alpha1"
+		case "machine", "cluster":
+			config = true
+		}
+	}
+
+	return version && config
+}
+
+// configDocumentKind returns the kind of the Talos config document other than v1alpha1.
+func configDocumentKind(node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.MappingNode {
+		return "", false
+	}
+
+	var apiVersion, kind string
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "apiVersion":
+			apiVersion = node.Content[i+1].Value
+		case "kind":
+			kind = node.Content[i+1].Value
+		}
+	}
+
+	return kind, apiVersion == "v1alpha1" && kind != ""
+}
+
+// redactValues replaces all scalar values in the tree keeping the keys.
+func redactValues(node *yaml.Node) int {
+	switch node.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		count := 0
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			count += redactValues(node.Content[i+1])
+		}
+
+		return count
+	case yaml.SequenceNode:
+		count := 0
+
+		for _, item := range node.Content {
+			count += redactValues(item)
+		}
+
+		return count
+	default:
+		return redactValue(node)
+	}
+}
+
+// redactPath replaces the value at the path using the replace function.
+func redactPath(node *yaml.Node, path []string, replace func(node *yaml.Node) int) int {
+	if len(path) == 0 {
+		return replace(node)
+	}
+
+	count := 0
+
+	switch node.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if path[0] == "*" || node.Content[i].Value == path[0] {
+				count += redactPath(node.Content[i+1], path[1:], replace)
+			}
+		}
+	case yaml.SequenceNode:
+		if path[0] == "*" {
+			for _, item := range node.Content {
+				count += redactPath(item, path[1:], replace)
+			}
+		}
+	}
+
+	return count
+}
+
+// redactValue replaces the value with the placeholder.
+func redactValue(node *yaml.Node) int {
+	if node.Kind == yaml.ScalarNode && (node.Value == "" || node.Value == Redacted || node.Tag == "!!null") {
+		return 0
+	}
+
+	*node = yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!str",
+		Value: Redacted,
+	}
+
+	return 1
+}
@@ -0,0 +1,109 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// mappingHeader identifies the encrypted mapping file format.
+const mappingHeader = "talos-support-mapping-v1\n"
+
+const saltSize = 16
+
+// WriteMapping writes the mapping from the real values to the pseudonyms encrypted with the passphrase.
+//
+// The key is derived from the passphrase using scrypt, the mapping is encrypted using AES-256-GCM.
+func (a *Anonymizer) WriteMapping(w io.Writer, passphrase []byte) error {
+	data, err := yaml.Marshal(a.Mapping())
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := mappingCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	out := slices.Concat([]byte(mappingHeader), salt, nonce)
+	out = aead.Seal(out, nonce, data, []byte(mappingHeader))
+
+	_, err = w.Write(out)
+
+	return err
+}
+
+// ReadMapping reads the mapping written by Anonymizer.WriteMapping.
+func ReadMapping(r io.Reader, passphrase []byte) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := bytes.CutPrefix(data, []byte(mappingHeader))
+	if !ok || len(data) < saltSize {
+		return nil, errors.New("unknown mapping file format")
+	}
+
+	aead, err := mappingCipher(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[saltSize:]
+
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("mapping file is truncated")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(mappingHeader))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the mapping: %w", err)
+	}
+
+	var mapping map[string]string
+
+	if err = yaml.Unmarshal(plaintext, &mapping); err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+func mappingCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,32 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"regexp"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+var pii = []bundle.Redactor{
+	// the comment of the SSH public key is usually user@host
+	Regexp(
+		"ssh-key-comment",
+		regexp.MustCompile(`\b((?:ssh-(?:rsa|dss|ed25519)|ecdsa-sha2-nistp\d+|sk-[a-z0-9-]+@openssh\.com) AAAA[A-Za-z0-9+/]+={0,3}) [^\s"']+`),
+		"${1} "+Redacted,
+	),
+	Regexp(
+		"url-query-credentials",
+		regexp.MustCompile(`(?i)([?&](?:password|passwd|pwd|token|access_token|id_token|api_key|apikey|key|secret|client_secret|sig|signature|x-amz-signature|x-amz-credential|code)=)[^&\s"'#]+`),
+		"${1}"+Redacted,
+	),
+	Regexp("email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`), Redacted),
+}
+
+// PII returns the redactors for the personal information commonly found in the logs: email addresses,
+// credentials in the URL query strings and the comments of the SSH public keys.
+func PII() []bundle.Redactor {
+	return pii
+}
@@ -0,0 +1,25 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siderolabs/go-talos-support/support/redact"
+)
+
+func TestPII(t *testing.T) {
+	data := `login by john.doe@example.com
+GET https://example.com/callback?state=1&code=c2VjcmV0&lang=en
+ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample jdoe@laptop
+`
+
+	assert.Equal(t, `login by <REDACTED>
+GET https://example.com/callback?state=1&code=<REDACTED>&lang=en
+ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample <REDACTED>
+`, redactAll(t, redact.PII(), "app.log", data))
+}
@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package redact provides the redactors which remove the sensitive information from the support bundle.
+package redact
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// Func is the function implementing the redactor.
+type Func func(path string, data []byte) ([]byte, int, error)
+
+// NewFunc creates a redactor from the function.
+func NewFunc(name string, f Func) bundle.Redactor {
+	return &funcRedactor{
+		name: name,
+		f:    f,
+	}
+}
+
+type funcRedactor struct {
+	f    Func
+	name string
+}
+
+// Name implements bundle.Redactor.
+func (r *funcRedactor) Name() string {
+	return r.name
+}
+
+// Redact implements bundle.Redactor.
+func (r *funcRedactor) Redact(path string, data []byte) ([]byte, int, error) {
+	return r.f(path, data)
+}
+
+// Regexp creates a redactor which replaces all matches of the regular expression.
+//
+// The replacement can reference the submatches, e.g. `${1}<REDACTED>`, see regexp.Regexp.Expand.
+func Regexp(name string, re *regexp.Regexp, replacement string) bundle.Redactor {
+	return NewFunc(name, func(_ string, data []byte) ([]byte, int, error) {
+		res, count := replace(re, data, []byte(replacement))
+
+		return res, count, nil
+	})
+}
+
+// replace replaces all matches of the regular expression expanding the template and returns the number of replacements.
+func replace(re *regexp.Regexp, data, template []byte) ([]byte, int) {
+	matches := re.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return data, 0
+	}
+
+	res := make([]byte, 0, len(data))
+	last := 0
+
+	for _, match := range matches {
+		res = append(res, data[last:match[0]]...)
+		res = re.Expand(res, template, data, match)
+		last = match[1]
+	}
+
+	res = append(res, data[last:]...)
+
+	return res, len(matches)
+}
+
+// ForPaths limits the redactor to the files matching any of the path patterns, see path.Match.
+func ForPaths(redactor bundle.Redactor, patterns ...string) bundle.Redactor {
+	return NewFunc(redactor.Name(), func(p string, data []byte) ([]byte, int, error) {
+		if !matchesAny(p, patterns) {
+			return data, 0, nil
+		}
+
+		return redactor.Redact(p, data)
+	})
+}
+
+func matchesAny(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok { //nolint:errcheck
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// Rules is the file with the user defined redaction rules.
+//
+// Example:
+//
+//	rules:
+//	  - name: customer-id
+//	    pattern: 'CUST-[0-9]{6}'
+//	    replacement: '<CUSTOMER-ID>'
+//	    paths:
+//	      - '*/service-logs/*.log'
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule replaces the matches of the regular expression in the files matching the paths.
+//
+// The default replacement is Redacted, the rule applies to all files if no paths are set.
+type Rule struct {
+	Name        string   `yaml:"name"`
+	Pattern     string   `yaml:"pattern"`
+	Replacement string   `yaml:"replacement,omitempty"`
+	Paths       []string `yaml:"paths,omitempty"`
+}
+
+// LoadRules creates the redactors from the rules in YAML format.
+func LoadRules(r io.Reader) ([]bundle.Redactor, error) {
+	var rules Rules
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse redaction rules: %w", err)
+	}
+
+	redactors := make([]bundle.Redactor, 0, len(rules.Rules))
+
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule %d has no name", i)
+		}
+
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of redaction rule %s: %w", rule.Name, err)
+		}
+
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = Redacted
+		}
+
+		redactor := Regexp(rule.Name, re, replacement)
+
+		if len(rule.Paths) > 0 {
+			redactor = ForPaths(redactor, rule.Paths...)
+		}
+
+		redactors = append(redactors, redactor)
+	}
+
+	return redactors, nil
+}
+
+// LoadRulesFile creates the redactors from the rules file.
+func LoadRulesFile(path string) ([]bundle.Redactor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	return LoadRules(f)
+}
@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/redact"
+)
+
+func TestLoadRulesFile(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`rules:
+  - name: customer-id
+    pattern: 'CUST-[0-9]{6}'
+    replacement: '<CUSTOMER-ID>'
+    paths:
+      - '*.log'
+  - name: order-id
+    pattern: 'ORD-[0-9]+'
+`), 0o600))
+
+	redactors, err := redact.LoadRulesFile(rulesFile)
+	require.NoError(t, err)
+	require.Len(t, redactors, 2)
+	assert.Equal(t, "customer-id", redactors[0].Name())
+
+	assert.Equal(t, "request from <CUSTOMER-ID> for <REDACTED>\n", redactAll(t, redactors, "app.log", "request from CUST-123456 for ORD-42\n"))
+	assert.Equal(t, "request from CUST-123456 for <REDACTED>\n", redactAll(t, redactors, "app.txt", "request from CUST-123456 for ORD-42\n"))
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	for _, rules := range []string{
+		"rules:\n  - pattern: 'CUST-[0-9]{6}'\n",
+		"rules:\n  - name: broken\n    pattern: '('\n",
+		"rules:\n  - name: typo\n    patern: 'CUST'\n",
+	} {
+		_, err := redact.LoadRules(strings.NewReader(rules))
+		assert.Error(t, err, rules)
+	}
+}
@@ -0,0 +1,143 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// maxFindings limits the number of the suspected secrets reported for a single file.
+const maxFindings = 100
+
+// minEntropy is the minimum Shannon entropy in bits per character of the random looking strings.
+//
+// The hex strings (e.g. image digests) have at most 4 bits per character, so they are not reported.
+const minEntropy = 4.5
+
+type scanRule struct {
+	re   *regexp.Regexp
+	name string
+}
+
+var scanRules = []scanRule{
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{name: "pem-block-base64", re: pemBase64Re},
+	{name: "bootstrap-token", re: bootstrapTokenRe},
+	{name: "jwt", re: jwtTokenRe},
+	{name: "url-basic-auth", re: urlBasicAuthRe},
+	{name: "aws-access-key-id", re: awsAccessKeyIDRe},
+	{name: "aws-secret-access-key", re: awsSecretAccessKeyRe},
+	{name: "gcp-api-key", re: gcpAPIKeyRe},
+	{
+		name: "credential-assignment",
+		re:   regexp.MustCompile(`(?i)\b(?:password|passwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?[^\s"'<${*][^\s"']{7,}`),
+	},
+}
+
+var randomStringRe = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+
+// Scan looks for the suspected secrets in the text: the known token formats and the random looking strings.
+//
+// The data is not changed, the matches in the findings are masked.
+func Scan(r io.Reader) ([]bundle.SecretFinding, error) {
+	var findings []bundle.SecretFinding
+
+	reader := bufio.NewReader(r)
+
+	for line := 1; len(findings) < maxFindings; line++ {
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		findings = append(findings, scanLine(text, line)...)
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if len(findings) > maxFindings {
+		findings = findings[:maxFindings]
+	}
+
+	return findings, nil
+}
+
+func scanLine(text string, line int) []bundle.SecretFinding {
+	var findings []bundle.SecretFinding
+
+	for _, rule := range scanRules {
+		if match := rule.re.FindString(text); match != "" {
+			findings = append(findings, bundle.SecretFinding{
+				Line:  line,
+				Rule:  rule.name,
+				Match: mask(match),
+			})
+		}
+	}
+
+	if len(findings) > 0 {
+		return findings
+	}
+
+	for _, match := range randomStringRe.FindAllString(text, -1) {
+		if isRandom(match) {
+			return []bundle.SecretFinding{
+				{
+					Line:  line,
+					Rule:  "high-entropy-string",
+					Match: mask(match),
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+// isRandom checks if the string looks like a random token: it has letters and digits and high entropy.
+func isRandom(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false
+	}
+
+	var counts [256]int
+
+	for i := range len(s) {
+		counts[s[i]]++
+	}
+
+	entropy := 0.0
+
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+
+		p := float64(count) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy >= minEntropy
+}
+
+// mask hides the suspected secret keeping only the beginning of it.
+func mask(s string) string {
+	const visible = 6
+
+	if len(s) <= visible {
+		return fmt.Sprintf("%s... (%d chars)", s[:min(len(s), 2)], len(s))
+	}
+
+	return fmt.Sprintf("%s... (%d chars)", s[:visible], len(s))
+}
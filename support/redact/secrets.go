@@ -0,0 +1,46 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package redact
+
+import (
+	"regexp"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+)
+
+// Redacted is the placeholder for the removed data.
+const Redacted = "<REDACTED>"
+
+var (
+	pemBlockRe           = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]+)-----[A-Za-z0-9+/=\s:,.\\-]*?-----END [A-Z0-9 ]+-----`)
+	pemBase64Re          = regexp.MustCompile(`LS0tLS1CRUdJT[A-Za-z0-9+/]+={0,2}`)
+	bootstrapTokenRe     = regexp.MustCompile(`\b[a-z0-9]{6}\.[a-z0-9]{16}\b`)
+	jwtTokenRe           = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	bearerTokenRe        = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	urlBasicAuthRe       = regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s:@]+:[^/\s@]+@`)
+	awsAccessKeyIDRe     = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	awsSecretAccessKeyRe = regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[=:]\s*["']?)[A-Za-z0-9/+=]{40}`)
+	gcpAPIKeyRe          = regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)
+)
+
+var secrets = []bundle.Redactor{
+	MachineConfig(),
+	Regexp("pem-block", pemBlockRe, "-----BEGIN ${1}-----"+Redacted+"-----END ${1}-----"),
+	// base64 encoded PEM blocks, as in the Talos machine config and kubeconfig
+	Regexp("pem-block-base64", pemBase64Re, Redacted),
+	Regexp("bootstrap-token", bootstrapTokenRe, Redacted),
+	Regexp("jwt", jwtTokenRe, Redacted),
+	Regexp("bearer-token", bearerTokenRe, "${1}"+Redacted),
+	Regexp("url-basic-auth", urlBasicAuthRe, "${1}"+Redacted+"@"),
+	Regexp("aws-access-key-id", awsAccessKeyIDRe, Redacted),
+	Regexp("aws-secret-access-key", awsSecretAccessKeyRe, "${1}"+Redacted),
+	Regexp("gcp-api-key", gcpAPIKeyRe, Redacted),
+}
+
+// Secrets returns the redactors for the Talos machine config secrets and the common secret formats: PEM keys
+// and certificates, bootstrap tokens, bearer tokens and JWTs, basic auth credentials in URLs, AWS and GCP keys.
+func Secrets() []bundle.Redactor {
+	return secrets
+}
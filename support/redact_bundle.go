@@ -0,0 +1,51 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"context"
+	"time"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// RedactBundle writes the sanitized copy of the existing bundle to the archive set in the options.
+//
+// The files of the bundle go through the redaction pipeline configured by the options (bundle.WithSecretRedaction,
+// bundle.WithPIIRedaction, bundle.WithRedactors, etc.) the same way as during the collection, and the redaction
+// report is written to the copy. The Talos and Kubernetes clients are not used.
+//
+// The custom metadata of the original bundle is kept unless the options set their own, and the copy keeps
+// the creation time of the original bundle unless the clock is set by bundle.WithClock.
+func RedactBundle(ctx context.Context, b *bundlereader.Bundle, options *bundle.Options) error {
+	copyMetadata(b, options)
+
+	return CreateSupportBundle(ctx, options, GetBundleCollectors(b)...)
+}
+
+// copyMetadata sets the metadata and the creation time of the copy of the bundle from the original one.
+func copyMetadata(b *bundlereader.Bundle, options *bundle.Options) {
+	md := b.Metadata()
+	if md == nil {
+		return
+	}
+
+	if options.Metadata == nil {
+		options.Metadata = md.Custom
+	}
+
+	if options.Clock == nil && !md.Created.IsZero() {
+		options.Clock = fixedClock(md.Created)
+	}
+}
+
+// fixedClock always returns the same time.
+type fixedClock time.Time
+
+// Now implements bundle.Clock.
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/report"
+)
+
+func TestHTMLReport(t *testing.T) {
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml":                                              {Data: []byte("created: 2024-01-01T00:00:00Z\ncustom:\n    ticket: \"1234\"\n")},
+		"10.5.0.2/summary":                                           {Data: []byte("Server:\n\tTag:         v1.8.0\n")},
+		"10.5.0.2/service-logs/kubelet.state":                        {Data: []byte("ID       kubelet\nSTATE    Failed\nHEALTH   ?\n")},
+		"10.5.0.2/service-logs/kubelet.log":                          {Data: []byte("line 1\nline 2 <script>\nline 3\n")},
+		"10.5.0.2/resources/hostnamestatuses.network.talos.dev.yaml": {Data: []byte("metadata:\n    id: hostname\nspec:\n    hostname: talos-cp-1\n")},
+	})
+	require.NoError(err)
+
+	r, err := report.New(b, report.Options{LogLines: 2})
+	require.NoError(err)
+
+	require.Len(r.Nodes, 1)
+	require.Equal("v1.8.0", r.Nodes[0].TalosVersion)
+	require.Equal([]string{"kubelet"}, r.Nodes[0].FailingServices)
+	require.Equal(1, r.Critical)
+
+	var buf bytes.Buffer
+
+	require.NoError(r.WriteHTML(&buf))
+
+	html := buf.String()
+
+	assert.Contains(t, html, "ticket")
+	assert.Contains(t, html, "service kubelet failed")
+	assert.Contains(t, html, "line 2 &lt;script&gt;")
+	assert.NotContains(t, html, "line 1")
+	assert.Contains(t, html, "hostname: talos-cp-1")
+}
@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Markdown summary limits, the summary should fit into the issue description.
+const (
+	markdownFindings  = 10
+	markdownLogErrors = 10
+)
+
+// WriteMarkdown renders the short triage summary as Markdown: the cluster overview, the top findings
+// and the most frequent errors from the logs.
+//
+// The summary is meant to be pasted into the GitHub issue or the support ticket.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "## Talos support bundle summary")
+	fmt.Fprintln(&buf)
+
+	if r.Metadata != nil {
+		fmt.Fprintf(&buf, "- Created: %s\n", r.Metadata.Created.Format(time.RFC3339))
+
+		keys := make([]string, 0, len(r.Metadata.Custom))
+
+		for key := range r.Metadata.Custom {
+			keys = append(keys, key)
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "- %s: %s\n", key, r.Metadata.Custom[key])
+		}
+	}
+
+	fmt.Fprintf(&buf, "- Nodes: %d, files: %d\n", len(r.Nodes), r.Files)
+	fmt.Fprintf(&buf, "- Problems: %d critical, %d warnings\n", r.Critical, r.Warnings)
+	fmt.Fprintln(&buf)
+
+	if len(r.Nodes) > 0 {
+		fmt.Fprintln(&buf, "### Cluster overview")
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, "| Node | Kubernetes node | Ready | Talos | Kubelet | Failing services |")
+		fmt.Fprintln(&buf, "| --- | --- | --- | --- | --- | --- |")
+
+		for _, node := range r.Nodes {
+			fmt.Fprintf(&buf, "| %s | %s | %s | %s | %s | %s |\n",
+				cell(node.Name),
+				cell(node.KubernetesName),
+				cell(node.Ready),
+				cell(node.TalosVersion),
+				cell(node.KubeletVersion),
+				cell(strings.Join(node.FailingServices, ", ")),
+			)
+		}
+
+		fmt.Fprintln(&buf)
+	}
+
+	fmt.Fprintln(&buf, "### Top findings")
+	fmt.Fprintln(&buf)
+
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(&buf, "No problems found.")
+	}
+
+	for _, finding := range r.Findings[:min(len(r.Findings), markdownFindings)] {
+		node := ""
+		if finding.Node != "" {
+			node = fmt.Sprintf(" `%s`", finding.Node)
+		}
+
+		fmt.Fprintf(&buf, "- **%s**%s %s\n", finding.Severity, node, finding.Message)
+
+		if finding.Remediation != "" {
+			fmt.Fprintf(&buf, "  - %s\n", finding.Remediation)
+		}
+	}
+
+	if len(r.Findings) > markdownFindings {
+		fmt.Fprintf(&buf, "- ... and %d more\n", len(r.Findings)-markdownFindings)
+	}
+
+	if r.AnalyzeError != "" {
+		fmt.Fprintln(&buf)
+		fmt.Fprintf(&buf, "> Analysis was incomplete: %s\n", r.AnalyzeError)
+	}
+
+	fmt.Fprintln(&buf)
+
+	if len(r.LogErrors) > 0 {
+		fmt.Fprintln(&buf, "### Notable errors in logs")
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, "| Count | File | Message |")
+		fmt.Fprintln(&buf, "| --- | --- | --- |")
+
+		for _, logError := range r.LogErrors[:min(len(r.LogErrors), markdownLogErrors)] {
+			fmt.Fprintf(&buf, "| %d | %s | `%s` |\n", logError.Count, cell(logError.Path), strings.ReplaceAll(cell(logError.Line), "`", "'"))
+		}
+
+		fmt.Fprintln(&buf)
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// cell escapes the value for the Markdown table cell.
+func cell(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s)
+}
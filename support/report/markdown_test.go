@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/report"
+)
+
+func TestMarkdownReport(t *testing.T) {
+	require := require.New(t)
+
+	b, err := bundlereader.FromFS(fstest.MapFS{
+		"metadata.yaml": {Data: []byte("created: 2024-01-01T00:00:00Z\n")},
+		"10.5.0.2/service-logs/etcd.log": {Data: []byte(
+			"2024/01/01 00:00:01 error: dial tcp 10.5.0.3:2380: connection refused\n" +
+				"2024/01/01 00:00:02 error: dial tcp 10.5.0.3:2380: connection refused\n" +
+				"2024/01/01 00:00:03 ready to serve | client requests\n" +
+				"2024/01/01 00:00:04 failed to publish local member\n",
+		)},
+	})
+	require.NoError(err)
+
+	r, err := report.New(b, report.Options{})
+	require.NoError(err)
+
+	require.Len(r.LogErrors, 2)
+	require.Equal(2, r.LogErrors[0].Count)
+
+	var buf bytes.Buffer
+
+	require.NoError(r.WriteMarkdown(&buf))
+
+	md := buf.String()
+
+	assert.Contains(t, md, "### Cluster overview")
+	assert.Contains(t, md, "| 2 | 10.5.0.2/service-logs/etcd.log | `2024/01/01 00:00:01 error: dial tcp 10.5.0.3:2380: connection refused` |")
+	assert.Contains(t, md, "No problems found.")
+}
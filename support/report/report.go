@@ -0,0 +1,317 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package report renders the human readable reports from the support bundle.
+package report
+
+import (
+	"cmp"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/siderolabs/go-talos-support/support/analyze"
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+)
+
+// Default report limits.
+const (
+	DefaultLogLines    = 200
+	DefaultMaxFileSize = 64 * 1024
+)
+
+// Options configures the report.
+type Options struct {
+	// Rules are the analyzer rules, the default rules are used if empty.
+	Rules []analyze.Rule
+	// LogLines is the number of the last lines of each log included in the report.
+	LogLines int
+	// MaxFileSize is the size limit of each resource file included in the report, the larger files are truncated.
+	MaxFileSize int
+}
+
+// Report is the data rendered by the report generators.
+type Report struct {
+	Metadata     *bundle.BundleMetadata
+	AnalyzeError string
+	Nodes        []NodeSummary
+	Findings     []analyze.Finding
+	Logs         []File
+	LogErrors    []LogError
+	Resources    []File
+	Files        int
+	Critical     int
+	Warnings     int
+}
+
+// NodeSummary is the node overview: Talos node and the matching Kubernetes node.
+type NodeSummary struct {
+	Name             string
+	TalosVersion     string
+	KubernetesName   string
+	KubeletVersion   string
+	OSImage          string
+	ContainerRuntime string
+	Ready            string
+	FailingServices  []string
+	Services         int
+}
+
+// File is the bundle file excerpt.
+type File struct {
+	Path      string
+	Node      string
+	Content   string
+	Truncated bool
+}
+
+// LogError is the error message found in the logs.
+//
+// The messages which differ only in the numbers (timestamps, addresses, counters) are counted together,
+// the first occurrence is kept as the example.
+type LogError struct {
+	Path    string
+	Line    string
+	Count   int
+	pattern string
+}
+
+// New collects the report data from the bundle.
+func New(b *bundlereader.Bundle, opts Options) (*Report, error) {
+	if opts.LogLines <= 0 {
+		opts.LogLines = DefaultLogLines
+	}
+
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+
+	r := &Report{
+		Metadata: b.Metadata(),
+		Files:    len(b.Files()),
+	}
+
+	findings, err := analyze.Analyze(b, opts.Rules...)
+	if err != nil {
+		r.AnalyzeError = err.Error()
+	}
+
+	r.Findings = findings
+
+	for _, finding := range findings {
+		switch finding.Severity { //nolint:exhaustive
+		case analyze.SeverityCritical:
+			r.Critical++
+		case analyze.SeverityWarning:
+			r.Warnings++
+		}
+	}
+
+	if err = r.collectNodes(b); err != nil {
+		return nil, err
+	}
+
+	if err = r.collectFiles(b, opts); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Report) collectNodes(b *bundlereader.Bundle) error {
+	for _, node := range b.Nodes() {
+		summary := NodeSummary{Name: node.Name}
+
+		if slices.Contains(node.Files(), "summary") {
+			version, err := node.TalosVersion()
+			if err != nil {
+				return err
+			}
+
+			summary.TalosVersion = version
+		}
+
+		for _, id := range node.Services() {
+			status, err := node.ServiceStatus(id)
+			if err != nil {
+				return err
+			}
+
+			summary.Services++
+
+			if status.Failing() {
+				summary.FailingServices = append(summary.FailingServices, id)
+			}
+		}
+
+		r.Nodes = append(r.Nodes, summary)
+	}
+
+	if !b.Has(bundlereader.KubernetesResourcesDir + "/nodes.yaml") {
+		return nil
+	}
+
+	nodes, err := b.KubernetesNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes.Items {
+		// the Talos nodes are named by the addresses used to collect the bundle, so match them by the node addresses
+		idx := slices.IndexFunc(r.Nodes, func(summary NodeSummary) bool {
+			return summary.Name == node.Name || slices.ContainsFunc(node.Status.Addresses, func(address corev1.NodeAddress) bool {
+				return address.Address == summary.Name
+			})
+		})
+
+		if idx < 0 {
+			r.Nodes = append(r.Nodes, NodeSummary{})
+			idx = len(r.Nodes) - 1
+		}
+
+		summary := &r.Nodes[idx]
+
+		summary.KubernetesName = node.Name
+		summary.KubeletVersion = node.Status.NodeInfo.KubeletVersion
+		summary.OSImage = node.Status.NodeInfo.OSImage
+		summary.ContainerRuntime = node.Status.NodeInfo.ContainerRuntimeVersion
+		summary.Ready = string(corev1.ConditionUnknown)
+
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				summary.Ready = string(condition.Status)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Report) collectFiles(b *bundlereader.Bundle, opts Options) error {
+	logErrors := map[string]*LogError{}
+
+	for _, file := range b.Logs() {
+		lines := make([]string, 0, opts.LogLines)
+		truncated := false
+
+		if err := b.Lines(file, func(line string) bool {
+			if len(lines) == opts.LogLines {
+				lines = slices.Delete(lines, 0, 1)
+				truncated = true
+			}
+
+			lines = append(lines, line)
+
+			if errorLineRe.MatchString(line) {
+				recordLogError(logErrors, file, line)
+			}
+
+			return true
+		}); err != nil {
+			return err
+		}
+
+		r.Logs = append(r.Logs, File{
+			Path:      file,
+			Node:      fileNode(b, file),
+			Content:   strings.Join(lines, "\n"),
+			Truncated: truncated,
+		})
+	}
+
+	for _, logError := range logErrors {
+		r.LogErrors = append(r.LogErrors, *logError)
+	}
+
+	slices.SortFunc(r.LogErrors, func(a, b LogError) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Path, b.Path), strings.Compare(a.pattern, b.pattern))
+	})
+
+	for _, file := range b.Files() {
+		dir, rest, _ := strings.Cut(file, "/")
+
+		isResource := dir == bundlereader.KubernetesResourcesDir || strings.HasPrefix(rest, "resources/")
+		if !isResource || path.Ext(file) != ".yaml" {
+			continue
+		}
+
+		data, err := b.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		f := File{
+			Path: file,
+			Node: fileNode(b, file),
+		}
+
+		if len(data) > opts.MaxFileSize {
+			data = data[:opts.MaxFileSize]
+			f.Truncated = true
+		}
+
+		f.Content = string(data)
+
+		r.Resources = append(r.Resources, f)
+	}
+
+	return nil
+}
+
+var (
+	errorLineRe = regexp.MustCompile(`(?i)\b(error|fatal|panic|failed|failure)\b|\blevel=(error|fatal)\b|^[EF]\d{4} `)
+	numberRe    = regexp.MustCompile(`[0-9a-fA-F]*[0-9][0-9a-fA-F]*`)
+)
+
+const (
+	// maxLogErrorLength is the length of the log error example.
+	maxLogErrorLength = 300
+	// maxLogErrorPatterns limits the memory used by the log errors, the new patterns are dropped above the limit.
+	maxLogErrorPatterns = 10000
+)
+
+// recordLogError records the error line grouping it with the similar lines from the same file.
+func recordLogError(logErrors map[string]*LogError, file, line string) {
+	pattern := numberRe.ReplaceAllString(line, "N")
+	key := file + "\x00" + pattern
+
+	if logError, ok := logErrors[key]; ok {
+		logError.Count++
+
+		return
+	}
+
+	if len(logErrors) >= maxLogErrorPatterns {
+		return
+	}
+
+	if len(line) > maxLogErrorLength {
+		line = line[:maxLogErrorLength] + "..."
+	}
+
+	logErrors[key] = &LogError{
+		Path:    file,
+		Line:    line,
+		Count:   1,
+		pattern: pattern,
+	}
+}
+
+// fileNode returns the node the file belongs to or the empty string for the cluster wide files.
+func fileNode(b *bundlereader.Bundle, file string) string {
+	dir, _, ok := strings.Cut(file, "/")
+	if !ok {
+		return ""
+	}
+
+	if _, isNode := b.Node(dir); !isNode {
+		return ""
+	}
+
+	return dir
+}
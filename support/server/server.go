@@ -0,0 +1,241 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package server exposes the support bundle generation as a gRPC service.
+package server
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/siderolabs/go-talos-support/support"
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+	"github.com/siderolabs/go-talos-support/support/server/serverpb"
+)
+
+// ChunkSize is the maximum size of the archive chunks streamed to the client.
+const ChunkSize = 1024 * 1024
+
+// Server implements the support bundle gRPC service.
+type Server struct {
+	serverpb.UnimplementedSupportServiceServer
+
+	options []bundle.Option
+}
+
+// NewServer creates the support bundle service.
+//
+// The options (Talos and Kubernetes clients, redactors, extra collectors, etc.) are applied to every bundle
+// before the options from the request. The collector logs are discarded unless the options set the log output.
+func NewServer(options ...bundle.Option) *Server {
+	return &Server{
+		options: slices.Concat([]bundle.Option{bundle.WithLogOutput(io.Discard)}, options),
+	}
+}
+
+// Register registers the service on the gRPC server.
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	serverpb.RegisterSupportServiceServer(registrar, s)
+}
+
+// CreateBundle implements serverpb.SupportServiceServer.
+func (s *Server) CreateBundle(req *serverpb.CreateBundleRequest, srv grpc.ServerStreamingServer[serverpb.CreateBundleResponse]) error {
+	ctx := srv.Context()
+
+	requestOptions, err := RequestOptions(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stream := &responseStream{srv: srv}
+
+	options := bundle.NewOptions(slices.Concat(s.options, requestOptions, []bundle.Option{
+		bundle.WithArchiveOutput(stream),
+		bundle.WithProgressFunc(stream.progress),
+	})...)
+
+	if err = options.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	cols, err := collectors.GetForOptions(ctx, options)
+	if err != nil {
+		options.Close() //nolint:errcheck
+
+		return status.Errorf(codes.FailedPrecondition, "failed to set up collectors: %s", err)
+	}
+
+	if err = support.CreateSupportBundle(ctx, options, cols...); err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+
+		return status.Errorf(codes.Internal, "failed to create support bundle: %s", err)
+	}
+
+	return stream.flush()
+}
+
+// RequestOptions converts the request into the bundle options.
+func RequestOptions(req *serverpb.CreateBundleRequest) ([]bundle.Option, error) {
+	var options []bundle.Option
+
+	switch req.GetProfile() {
+	case serverpb.Profile_PROFILE_STANDARD:
+		options = append(options, bundle.WithProfile(bundle.ProfileStandard))
+	case serverpb.Profile_PROFILE_MINIMAL:
+		options = append(options, bundle.WithProfile(bundle.ProfileMinimal))
+	case serverpb.Profile_PROFILE_FULL:
+		options = append(options, bundle.WithProfile(bundle.ProfileFull))
+	default:
+		return nil, errors.New("unknown profile " + req.GetProfile().String())
+	}
+
+	switch req.GetNodeRole() {
+	case serverpb.NodeRole_NODE_ROLE_ANY:
+	case serverpb.NodeRole_NODE_ROLE_CONTROL_PLANE:
+		options = append(options, bundle.WithControlPlaneOnly())
+	case serverpb.NodeRole_NODE_ROLE_WORKER:
+		options = append(options, bundle.WithWorkersOnly())
+	default:
+		return nil, errors.New("unknown node role " + req.GetNodeRole().String())
+	}
+
+	if len(req.GetNodes()) > 0 {
+		options = append(options, bundle.WithNodes(req.GetNodes()...))
+	}
+
+	if req.GetNodeLabels() != "" {
+		options = append(options, bundle.WithNodeLabels(req.GetNodeLabels()))
+	}
+
+	if req.GetNumWorkers() > 0 {
+		options = append(options, bundle.WithNumWorkers(int(req.GetNumWorkers())))
+	}
+
+	if len(req.GetNamespaces()) > 0 {
+		options = append(options, bundle.WithNamespaces(req.GetNamespaces()...))
+	}
+
+	if req.GetSince() != nil {
+		if err := req.GetSince().CheckValid(); err != nil {
+			return nil, err
+		}
+
+		options = append(options, bundle.WithSince(req.GetSince().AsDuration()))
+	}
+
+	if req.GetMaxFileSize() > 0 {
+		options = append(options, bundle.WithMaxFileSize(req.GetMaxFileSize()))
+	}
+
+	if req.GetRedactSecrets() {
+		options = append(options, bundle.WithSecretRedaction())
+	}
+
+	if req.GetRedactPii() {
+		options = append(options, bundle.WithPIIRedaction())
+	}
+
+	if req.GetSkipKubernetes() {
+		options = append(options, bundle.WithoutKubernetes())
+	}
+
+	if req.GetSkipTalos() {
+		options = append(options, bundle.WithoutTalos())
+	}
+
+	if len(req.GetMetadata()) > 0 {
+		options = append(options, bundle.WithMetadata(req.GetMetadata()))
+	}
+
+	return options, nil
+}
+
+// responseStream sends the archive chunks and the progress to the client.
+//
+// The archive is written and the progress is reported from the different goroutines, the stream is not safe
+// for the concurrent sends.
+type responseStream struct {
+	srv grpc.ServerStreamingServer[serverpb.CreateBundleResponse]
+	buf []byte
+	mu  sync.Mutex
+}
+
+// Write implements io.Writer, the data is buffered up to the chunk size.
+func (s *responseStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(p)
+
+	for len(p) > 0 {
+		size := min(len(p), ChunkSize-len(s.buf))
+
+		s.buf = append(s.buf, p[:size]...)
+		p = p[size:]
+
+		if len(s.buf) == ChunkSize {
+			if err := s.sendChunk(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (s *responseStream) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buf) == 0 {
+		return nil
+	}
+
+	return s.sendChunk()
+}
+
+func (s *responseStream) sendChunk() error {
+	err := s.srv.Send(&serverpb.CreateBundleResponse{
+		Event: &serverpb.CreateBundleResponse_Chunk{
+			Chunk: s.buf,
+		},
+	})
+
+	// the message is serialized by Send, so the buffer can be reused
+	s.buf = s.buf[:0]
+
+	return err
+}
+
+func (s *responseStream) progress(p bundle.Progress) {
+	msg := &serverpb.Progress{
+		Source:   p.Source,
+		State:    p.State,
+		Warnings: p.Warnings,
+		Total:    int32(p.Total), //nolint:gosec
+	}
+
+	if p.Error != nil {
+		msg.Error = p.Error.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the send errors are reported by the archive writes, the progress is best effort
+	s.srv.Send(&serverpb.CreateBundleResponse{ //nolint:errcheck
+		Event: &serverpb.CreateBundleResponse_Progress{
+			Progress: msg,
+		},
+	})
+}
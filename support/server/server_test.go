@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package server_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+	"github.com/siderolabs/go-talos-support/support/server"
+	"github.com/siderolabs/go-talos-support/support/server/serverpb"
+)
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require := require.New(t)
+
+	// random data doesn't compress, so the archive is split into several chunks
+	hello := make([]byte, server.ChunkSize*2)
+
+	_, err := rand.Read(hello)
+	require.NoError(err)
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := grpc.NewServer()
+
+	server.NewServer(
+		bundle.WithoutTalos(),
+		bundle.WithoutKubernetes(),
+		collectors.WithExtraCollectors(collectors.NewCollector("hello.txt", func(context.Context, *bundle.Options) ([]byte, error) {
+			return hello, nil
+		})),
+	).Register(grpcServer)
+
+	go grpcServer.Serve(listener) //nolint:errcheck
+
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(err)
+
+	defer conn.Close() //nolint:errcheck
+
+	stream, err := serverpb.NewSupportServiceClient(conn).CreateBundle(ctx, &serverpb.CreateBundleRequest{
+		Metadata: map[string]string{"ticket": "1234"},
+	})
+	require.NoError(err)
+
+	var (
+		archive  bytes.Buffer
+		progress []*serverpb.Progress
+		chunks   int
+	)
+
+	for {
+		resp, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+
+		require.NoError(recvErr)
+
+		switch event := resp.GetEvent().(type) {
+		case *serverpb.CreateBundleResponse_Chunk:
+			require.LessOrEqual(len(event.Chunk), server.ChunkSize)
+
+			archive.Write(event.Chunk)
+
+			chunks++
+		case *serverpb.CreateBundleResponse_Progress:
+			progress = append(progress, event.Progress)
+		}
+	}
+
+	require.Greater(chunks, 1)
+	require.Len(progress, 1)
+	require.Equal("collect hello.txt", progress[0].GetState())
+
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	require.NoError(err)
+
+	b, err := bundlereader.FromFS(zr)
+	require.NoError(err)
+
+	data, err := b.ReadFile("hello.txt")
+	require.NoError(err)
+	require.Equal(hello, data)
+	require.Equal("1234", b.Metadata().Custom["ticket"])
+
+	stream, err = serverpb.NewSupportServiceClient(conn).CreateBundle(ctx, &serverpb.CreateBundleRequest{Profile: serverpb.Profile(42)})
+	require.NoError(err)
+
+	_, err = stream.Recv()
+	require.Equal(codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// StreamSupportBundle generates the support bundle in the background and returns the reader of the zip archive.
+//
+// The archive bytes become available as the collectors complete, so the bundle can be sent to the client
+// (e.g. as the HTTP response) without buffering it first. The archive set in the options is replaced.
+// The collection error is returned by the reader after the data written so far.
+//
+// Closing the reader before reaching the end cancels the collection and waits for it to stop.
+func StreamSupportBundle(ctx context.Context, options *bundle.Options, cols ...*collectors.Collector) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+
+	bundle.WithArchiveOutput(pw)(options)
+
+	if err := options.Validate(); err != nil {
+		options.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	r := &streamReader{
+		PipeReader: pr,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		defer cancel()
+
+		pw.CloseWithError(CreateSupportBundle(ctx, options, cols...)) //nolint:errcheck
+	}()
+
+	return r, nil
+}
+
+// streamReader stops the bundle collection when closed.
+type streamReader struct {
+	*io.PipeReader
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close implements io.Closer.
+func (r *streamReader) Close() error {
+	r.cancel()
+
+	// unblock the archive writes
+	r.PipeReader.Close() //nolint:errcheck
+
+	<-r.done
+
+	return nil
+}
@@ -7,73 +7,459 @@ package support
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/siderolabs/gen/channel"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/siderolabs/go-talos-support/support/bundle"
 	"github.com/siderolabs/go-talos-support/support/collectors"
+	"github.com/siderolabs/go-talos-support/support/redact"
 )
 
 // CreateSupportBundle generates support bundle using provided collectors.
+//
+// The clients created by the bundle creator are closed when the bundle is done.
+// If the upload is configured by bundle.WithUpload, the bundle is uploaded before the archive is closed,
+// the upload result is available from options.UploadResult, UploadSupportBundle returns it.
+// If the SFTP output is configured by bundle.WithSFTPOutput, the archive is written to the remote host.
+// The completion summary is sent when the bundle is done, see bundle.WithCompletionWebhook.
+// The performance statistics are available from options.Stats after the bundle is created.
 func CreateSupportBundle(ctx context.Context, options *bundle.Options, cols ...*collectors.Collector) error {
-	tasks := make(chan *collectors.Collector)
+	defer options.Close() //nolint:errcheck
 
-	totals := calculateTotals(cols...)
+	started := options.Now()
 
-	eg, ctx := errgroup.WithContext(ctx)
+	err := createSupportBundle(ctx, options, cols...)
 
-	collectProgress := options.Progress != nil
+	options.NotifyCompletion(ctx, started, err)
 
-	if options.NumWorkers == 0 {
-		options.NumWorkers = 1
+	return err
+}
+
+// UploadSupportBundle generates the support bundle the same way as CreateSupportBundle and returns the upload result.
+//
+// The upload has to be configured by bundle.WithUpload, the local archive is optional.
+func UploadSupportBundle(ctx context.Context, options *bundle.Options, cols ...*collectors.Collector) (*bundle.UploadResult, error) {
+	if options.Upload == nil {
+		options.Close() //nolint:errcheck
+
+		return nil, errors.New("upload is not configured")
 	}
 
-	for range options.NumWorkers {
-		eg.Go(func() error {
-			for {
-				select {
-				case collector := <-tasks:
-					if collector == nil {
-						return ctx.Err()
-					}
-
-					err := collector.Run(ctx, options)
-
-					if !collectProgress {
-						continue
-					}
-
-					progress := bundle.Progress{
-						Error:  err,
-						Total:  totals[collector.Source()],
-						Source: collector.Source(),
-						State:  collector.String(),
-					}
-
-					if !channel.SendWithContext(ctx, options.Progress, progress) {
-						return nil
-					}
-				case <-ctx.Done():
-					return ctx.Err()
+	if err := CreateSupportBundle(ctx, options, cols...); err != nil {
+		return nil, err
+	}
+
+	return options.UploadResult(), nil
+}
+
+func createSupportBundle(ctx context.Context, options *bundle.Options, cols ...*collectors.Collector) error {
+	start := time.Now()
+
+	run, err := prepareBundle(ctx, options, cols)
+
+	options.RecordPhase(bundle.PhasePrepare, time.Since(start))
+
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+
+	err = runCollectors(ctx, poolsFor(options), run)
+
+	options.RecordPhase(bundle.PhaseCollect, time.Since(start))
+
+	if err != nil {
+		return err
+	}
+
+	return run.finish(ctx)
+}
+
+// bundleRun is the state of the bundle being created.
+type bundleRun struct {
+	options *bundle.Options
+	totals  map[string]int
+	// progressMu and errorHandlerMu are shared by the bundles created together
+	progressMu     *sync.Mutex
+	errorHandlerMu *sync.Mutex
+	// cluster prefixes the progress sources of the multi-cluster bundle
+	cluster       string
+	cols          []*collectors.Collector
+	attestation   bundle.Attestation
+	attestationMu sync.Mutex
+}
+
+// prepareBundle validates the options, opens the archive and writes the metadata.
+func prepareBundle(ctx context.Context, options *bundle.Options, cols []*collectors.Collector) (*bundleRun, error) {
+	if err := options.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	if options.RedactionRulesFile != "" {
+		redactors, err := redact.LoadRulesFile(options.RedactionRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redaction rules: %w", err)
+		}
+
+		options.Redactors = append(options.Redactors, redactors...)
+		options.RedactionRulesFile = ""
+	}
+
+	if err := options.OpenOutput(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := options.PrepareUpload(); err != nil {
+		return nil, err
+	}
+
+	options.IndexFiles()
+	// the queue is the outermost wrapper, so it gets the sizes of the collected files
+	options.QueueWrites()
+
+	if err := options.WriteMetadata(); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	run := &bundleRun{
+		options:        options,
+		progressMu:     &sync.Mutex{},
+		errorHandlerMu: &sync.Mutex{},
+	}
+
+	run.cols = slices.DeleteFunc(slices.Clone(cols), func(col *collectors.Collector) bool {
+		if options.Allowed(col.Path()) {
+			return false
+		}
+
+		run.attestation.Refused = append(run.attestation.Refused, options.ArchivePath(col.Path()))
+
+		return true
+	})
+
+	run.totals = calculateTotals(run.cols...)
+
+	return run, nil
+}
+
+// task is the collector of the bundle run.
+type task struct {
+	run       *bundleRun
+	collector *collectors.Collector
+	// pool is the channel of the worker pool running the collector
+	pool chan task
+}
+
+// source is the key of the collector source, unique across the clusters.
+func (t task) source() string {
+	return path.Join(t.run.cluster, t.collector.Source())
+}
+
+// workerPools are the worker pools running the collectors.
+type workerPools struct {
+	// adaptiveLatency enables the adaptive scheduling, see bundle.WithAdaptiveWorkers
+	adaptiveLatency   time.Duration
+	workers           int
+	kubernetesWorkers int
+	resourceWorkers   int
+}
+
+func poolsFor(options *bundle.Options) workerPools {
+	return workerPools{
+		adaptiveLatency:   options.AdaptiveLatency,
+		workers:           max(options.NumWorkers, 1),
+		kubernetesWorkers: options.KubernetesWorkers,
+		resourceWorkers:   options.ResourceWorkers,
+	}
+}
+
+// merge returns the pools large enough for both bundles.
+func (pools workerPools) merge(other workerPools) workerPools {
+	return workerPools{
+		adaptiveLatency:   max(pools.adaptiveLatency, other.adaptiveLatency),
+		workers:           max(pools.workers, other.workers),
+		kubernetesWorkers: max(pools.kubernetesWorkers, other.kubernetesWorkers),
+		resourceWorkers:   max(pools.resourceWorkers, other.resourceWorkers),
+	}
+}
+
+// runCollectors runs the collectors of the bundles using the shared workers.
+//
+// The cluster and the Talos resource collectors get their own pools if the number of the Kubernetes
+// or the resource workers is set. The number of the collectors run at once against each source is adapted
+// to its responsiveness if the adaptive scheduling is enabled.
+func runCollectors(ctx context.Context, pools workerPools, runs ...*bundleRun) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	var limiter *sourceLimiter
+
+	if pools.adaptiveLatency > 0 {
+		limiter = newSourceLimiter(max(pools.workers, pools.kubernetesWorkers, pools.resourceWorkers), pools.adaptiveLatency)
+	}
+
+	worker := func(tasks <-chan task) error {
+		for {
+			select {
+			case t, ok := <-tasks:
+				if !ok {
+					return egCtx.Err()
+				}
+
+				// don't collect more data while the archive writer is behind
+				if err := t.run.options.WaitWriteQueue(egCtx); err != nil {
+					return err
+				}
+
+				start := time.Now()
+
+				err := t.run.collect(egCtx, t.collector)
+
+				if limiter != nil {
+					duration, timeout := time.Since(start), t.run.options.CollectorTimeout
+
+					limiter.release(t.source(), duration, timeout > 0 && duration >= timeout)
 				}
+
+				if err != nil {
+					return err
+				}
+			case <-egCtx.Done():
+				return egCtx.Err()
 			}
-		})
+		}
 	}
 
-	for _, col := range cols {
-		channel.SendWithContext(ctx, tasks, col)
+	channels := make([]chan task, 0, 3)
+
+	pool := func(size int) chan task {
+		tasks := make(chan task)
+
+		for range size {
+			eg.Go(func() error { return worker(tasks) })
+		}
+
+		channels = append(channels, tasks)
+
+		return tasks
+	}
+
+	tasks := pool(pools.workers)
+	kubernetesTasks, resourceTasks := tasks, tasks
+
+	// the cluster collectors hit the Kubernetes API server, so they get their own pool if it's configured
+	if pools.kubernetesWorkers > 0 {
+		kubernetesTasks = pool(pools.kubernetesWorkers)
+	}
+
+	// the resource listings are heavy on apid, so they get their own pool if it's configured
+	if pools.resourceWorkers > 0 {
+		resourceTasks = pool(pools.resourceWorkers)
+	}
+
+	queues := map[chan task][]task{}
+
+	for _, run := range runs {
+		for _, col := range run.cols {
+			t := task{run: run, collector: col, pool: tasks}
+
+			switch {
+			case col.TalosResource():
+				t.pool = resourceTasks
+			case col.Source() == collectors.Cluster:
+				t.pool = kubernetesTasks
+			}
+
+			queues[t.pool] = append(queues[t.pool], t)
+		}
 	}
 
-	close(tasks)
+	// each pool gets its own dispatcher, so the busy pool doesn't hold back the tasks of the others
+	for _, tasks := range channels {
+		eg.Go(func() error {
+			defer close(tasks)
+
+			dispatch(egCtx, limiter, queues[tasks])
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// collect runs the collector and reports the progress.
+func (run *bundleRun) collect(ctx context.Context, collector *collectors.Collector) error {
+	options := run.options
+
+	start, started := time.Now(), options.Now()
 
-	if err := eg.Wait(); err != nil {
+	err := runCollector(ctx, options, collector, run.errorHandlerMu)
+	if errors.Is(err, errAborted) {
 		return err
 	}
 
+	options.RecordCollector(collector.Source(), collector.Path(), time.Since(start), err)
+
+	audit := bundle.AuditEntry{
+		Time:      started,
+		Collector: collector.Name(),
+		Path:      options.ArchivePath(collector.Path()),
+		Outcome:   bundle.AuditOutcomeOK,
+		Duration:  time.Since(start),
+	}
+
+	if collector.Source() != collectors.Cluster {
+		audit.Node = collector.Source()
+	}
+
+	if err != nil {
+		audit.Outcome = bundle.AuditOutcomeFailed
+		audit.Error = err.Error()
+	}
+
+	options.RecordAudit(audit)
+
+	if options.AllowListMode {
+		run.attestationMu.Lock()
+
+		if err != nil {
+			run.attestation.Failed = append(run.attestation.Failed, options.ArchivePath(collector.Path()))
+		} else {
+			run.attestation.Collected = append(run.attestation.Collected, options.ArchivePath(collector.Path()))
+		}
+
+		run.attestationMu.Unlock()
+	}
+
+	if options.Progress == nil && options.ProgressFunc == nil && options.ProgressWriter == nil {
+		return nil
+	}
+
+	progress := bundle.Progress{
+		Error:  err,
+		Total:  run.totals[collector.Source()],
+		Source: collector.Source(),
+		Name:   collector.Name(),
+		State:  collector.String(),
+	}
+
+	progress.QueueDepth, progress.QueueSize = options.WriteQueue()
+
+	if run.cluster != "" {
+		progress.Source = path.Join(run.cluster, progress.Source)
+	}
+
+	for _, finding := range options.SecretFindings(options.ArchivePath(collector.Path())) {
+		progress.Warnings = append(progress.Warnings, finding.String())
+	}
+
+	if options.ProgressFunc != nil {
+		run.progressMu.Lock()
+		options.ProgressFunc(progress)
+		run.progressMu.Unlock()
+	}
+
+	options.WriteProgress(progress)
+
+	if options.Progress != nil && !channel.SendWithContext(ctx, options.Progress, progress) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// finish writes the reports, uploads the bundle and closes the archive.
+func (run *bundleRun) finish(ctx context.Context) error {
+	options := run.options
+
+	defer func(start time.Time) {
+		options.RecordPhase(bundle.PhaseFinish, time.Since(start))
+	}(time.Now())
+
+	if err := options.WriteAttestation(run.attestation); err != nil {
+		return fmt.Errorf("failed to write attestation: %w", err)
+	}
+
+	if err := options.WriteSecretScan(); err != nil {
+		return fmt.Errorf("failed to write secret scan report: %w", err)
+	}
+
+	if err := options.WriteRedactionReport(); err != nil {
+		return fmt.Errorf("failed to write redaction report: %w", err)
+	}
+
+	if err := options.WriteAuditLog(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	if err := options.WriteManifest(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if options.Upload != nil {
+		if _, err := options.UploadBundle(ctx); err != nil {
+			return fmt.Errorf("failed to upload bundle: %w", err)
+		}
+	}
+
 	return options.Archive.Close()
 }
 
+var errAborted = errors.New("collection aborted")
+
+// runCollector runs the collector and asks the error handler what to do on failure.
+func runCollector(ctx context.Context, options *bundle.Options, collector *collectors.Collector, errorHandlerMu *sync.Mutex) error {
+	attempts := options.CollectorAttempts
+	if attempts <= 0 {
+		attempts = bundle.DefaultCollectorAttempts
+	}
+
+	backoff := options.CollectorBackoff
+	if backoff <= 0 {
+		backoff = bundle.DefaultCollectorBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := collector.Run(ctx, options)
+		if err == nil || options.ErrorHandler == nil || ctx.Err() != nil {
+			return err
+		}
+
+		errorHandlerMu.Lock()
+		decision := options.ErrorHandler(collector.Source(), collector.Path(), err)
+		errorHandlerMu.Unlock()
+
+		switch decision { //nolint:exhaustive
+		case bundle.DecisionRetry:
+			if attempt >= attempts {
+				return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+
+			options.Log("retrying %s in %s", collector.Path(), backoff)
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		case bundle.DecisionAbort:
+			return fmt.Errorf("%w: %s %s: %w", errAborted, collector.Source(), collector.Path(), err)
+		default:
+			return err
+		}
+	}
+}
+
 func calculateTotals(cols ...*collectors.Collector) map[string]int {
 	res := map[string]int{}
 
@@ -0,0 +1,196 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package support
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/go-talos-support/support/bundle"
+	"github.com/siderolabs/go-talos-support/support/bundlereader"
+	"github.com/siderolabs/go-talos-support/support/collectors"
+)
+
+// DefaultTrimMinLogSize is the size the logs are never truncated below.
+const DefaultTrimMinLogSize = 16 * 1024
+
+// TrimOptions configures the bundle trimming.
+type TrimOptions struct {
+	// TargetUncompressedSize is the limit of the total uncompressed size of the bundle files.
+	//
+	// The compressed size of the files depends on their contents, so the archive size is not limited directly:
+	// it is usually much smaller than the target.
+	TargetUncompressedSize int64
+	// MinLogSize is the size the logs are never truncated below, DefaultTrimMinLogSize is used if zero.
+	MinLogSize int64
+}
+
+// TrimBundle writes the copy of the existing bundle which fits into the uncompressed size target to the archive
+// set in the options.
+//
+// The files are trimmed starting from the least valuable ones: the packet captures and the etcd snapshots are removed
+// first, the largest ones first, then the logs are truncated to the same size limit keeping their beginning and end.
+// The other files (configuration, resources, reports) are never trimmed, so the target might be not reached.
+//
+// The removed and truncated files are listed in the trim report which is written to the copy and returned.
+// The copy goes through the redaction pipeline configured by the options and keeps the metadata of the original
+// bundle the same way as with RedactBundle.
+func TrimBundle(ctx context.Context, b *bundlereader.Bundle, options *bundle.Options, opts TrimOptions) (*bundle.TrimReport, error) {
+	if opts.MinLogSize <= 0 {
+		opts.MinLogSize = DefaultTrimMinLogSize
+	}
+
+	cols := GetBundleCollectors(b)
+	sizes := make(map[string]int64, len(cols))
+
+	report := &bundle.TrimReport{
+		TargetUncompressedSize: opts.TargetUncompressedSize,
+	}
+
+	for _, col := range cols {
+		size, err := b.Size(col.Path())
+		if err != nil {
+			return nil, err
+		}
+
+		sizes[col.Path()] = size
+		report.OriginalSize += size
+	}
+
+	report.Size = report.OriginalSize
+
+	removable := slices.DeleteFunc(slices.Clone(cols), func(col *collectors.Collector) bool {
+		return !trimRemovable(col.Path())
+	})
+
+	slices.SortStableFunc(removable, func(a, b *collectors.Collector) int {
+		return cmp.Compare(sizes[b.Path()], sizes[a.Path()])
+	})
+
+	removed := map[string]struct{}{}
+
+	for _, col := range removable {
+		if report.Size <= opts.TargetUncompressedSize {
+			break
+		}
+
+		removed[col.Path()] = struct{}{}
+		report.Size -= sizes[col.Path()]
+
+		report.Files = append(report.Files, bundle.TrimmedFile{
+			Path:         col.Path(),
+			Action:       bundle.TrimRemoved,
+			OriginalSize: sizes[col.Path()],
+		})
+	}
+
+	var logs []string
+
+	for _, col := range cols {
+		if strings.HasSuffix(col.Path(), ".log") {
+			logs = append(logs, col.Path())
+		}
+	}
+
+	limit := trimLogLimit(logs, sizes, report.Size-opts.TargetUncompressedSize, opts.MinLogSize)
+
+	trimmed := make([]*collectors.Collector, 0, len(cols)+1)
+
+	for _, col := range cols {
+		if _, ok := removed[col.Path()]; ok {
+			continue
+		}
+
+		if limit > 0 && strings.HasSuffix(col.Path(), ".log") && sizes[col.Path()] > limit {
+			report.Size -= sizes[col.Path()] - limit
+
+			report.Files = append(report.Files, bundle.TrimmedFile{
+				Path:         col.Path(),
+				Action:       bundle.TrimTruncated,
+				OriginalSize: sizes[col.Path()],
+				Size:         limit,
+			})
+
+			col = NewBundleFileCollector(b, col.Path(), limit)
+		}
+
+		trimmed = append(trimmed, col)
+	}
+
+	slices.SortFunc(report.Files, func(a, b bundle.TrimmedFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	data, err := yaml.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed = append(trimmed, collectors.NewCollector(bundle.TrimReportFile, func(context.Context, *bundle.Options) ([]byte, error) {
+		return data, nil
+	}))
+
+	copyMetadata(b, options)
+
+	return report, CreateSupportBundle(ctx, options, trimmed...)
+}
+
+// trimRemovable checks if the file can be removed by the trimming: the packet captures and the etcd snapshots.
+func trimRemovable(p string) bool {
+	return path.Base(p) == "etcd.snapshot" || path.Ext(p) == ".pcap"
+}
+
+// trimLogLimit finds the largest size limit for the logs which reduces their total size by the excess.
+//
+// Zero is returned if the logs don't need to be truncated, the limit is never below the minimum size.
+func trimLogLimit(logs []string, sizes map[string]int64, excess, minSize int64) int64 {
+	if excess <= 0 || len(logs) == 0 {
+		return 0
+	}
+
+	saved := func(limit int64) int64 {
+		var total int64
+
+		for _, log := range logs {
+			total += max(sizes[log]-limit, 0)
+		}
+
+		return total
+	}
+
+	var largest int64
+
+	for _, log := range logs {
+		largest = max(largest, sizes[log])
+	}
+
+	if largest <= minSize {
+		return 0
+	}
+
+	low, high := minSize, largest
+
+	if saved(low) < excess {
+		return low
+	}
+
+	// the savings decrease with the limit, find the largest limit which still saves enough
+	for low < high {
+		mid := low + (high-low+1)/2
+
+		if saved(mid) >= excess {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return low
+}